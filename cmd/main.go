@@ -3,14 +3,19 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"maqhaa/library/logging"
 	"maqhaa/library/middleware"
 	exRepo "maqhaa/product_service/external/repository"
+	"maqhaa/product_service/internal/app/model"
 	"maqhaa/product_service/internal/app/repository"
 	"maqhaa/product_service/internal/app/service"
+	"maqhaa/product_service/internal/broker"
+	"maqhaa/product_service/internal/catalogue"
 	"maqhaa/product_service/internal/config"
 	"maqhaa/product_service/internal/database"
 	grpcHandler "maqhaa/product_service/internal/interface/grpc/handler"
@@ -19,6 +24,7 @@ import (
 	"maqhaa/product_service/internal/interface/http/router"
 	"net"
 	"os"
+	"path/filepath"
 	"time"
 
 	"google.golang.org/grpc"
@@ -39,6 +45,11 @@ func main() {
 		logging.Log.Fatalf("Error loading configuration: %v", err)
 	}
 	logging.Log.Infof("Load configuration from %v", *configFilePath)
+
+	if flag.Arg(0) == "migrate-images" {
+		migrateImages(cfg)
+		return
+	}
 	// Access configuration values
 	dbConfig := cfg.Database
 
@@ -47,6 +58,10 @@ func main() {
 		logging.Log.Fatalf("Error loading configuration: %v", err)
 	}
 
+	if err := database.Migrate(db); err != nil {
+		logging.Log.Fatalf("Error migrating database: %v", err)
+	}
+
 	// Close the database connection when done
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -62,18 +77,110 @@ func main() {
 
 	// Initialize product service
 	userRepository := exRepo.NewUserRepository(cfg.ExternalConnection.AuthService.Host)
-	imageRepository := repository.NewImagesRepository(cfg.ImagePath)
+	imageRepository, err := repository.NewImagesRepositoryFromConfig(cfg)
+	if err != nil {
+		logging.Log.Fatalf("Error creating image storage: %v", err)
+	}
 	productRepository := repository.NewProductRepository(db)
-	productService := service.NewProductService(productRepository, userRepository, imageRepository)
+	translationRepository := repository.NewTranslationRepository(db)
+	productImageRepository := repository.NewProductImageRepository(db)
+	uploadedImageRepository := repository.NewUploadedImageRepository(db)
+	menuEvents := service.NewMenuEvents(repository.NewOutboxRepository(db), cfg.MenuEvents)
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(db), repository.NewUnitOfWork(db), userRepository, cfg.Webhook)
+	productService := service.NewProductService(productRepository, userRepository, imageRepository, translationRepository, productImageRepository, uploadedImageRepository, repository.NewUnitOfWork(db), cfg.ImageValidation, menuEvents, webhookService)
 	productHandler := httpHandler.NewProductHandler(productService)
 
+	// create requests retried with the same Idempotency-Key get the first response again
+	addProductHandler, addCategoryHandler := productHandler.AddProductHandler, productHandler.AddCategoryHandler
+	if cfg.Idempotency.TTL > 0 {
+		idempotencyService := service.NewIdempotencyService(repository.NewIdempotencyRepository(db), userRepository, cfg.Idempotency.TTL, cfg.Idempotency.Lease)
+		idempotencyHandler := httpHandler.NewIdempotencyHandler(idempotencyService)
+		addProductHandler = idempotencyHandler.Idempotent(addProductHandler)
+		addCategoryHandler = idempotencyHandler.Idempotent(addCategoryHandler)
+		go idempotencyService.RunPurge(context.Background(), cfg.Idempotency.TTL)
+	}
+
 	httpRouter.GET("/product", productHandler.GetProductGroupsByCategoryHandler)
-	httpRouter.POST("/product", productHandler.AddProductHandler)
+	httpRouter.POST("/product", addProductHandler)
 	httpRouter.PUT("/product", productHandler.EditProductHandler)
+	httpRouter.PATCH("/product/{productID}", productHandler.PatchProductHandler)
+	httpRouter.POST("/product/bulk", productHandler.BulkUpdateProductsHandler)
+	httpRouter.POST("/product/{productID}/clone", productHandler.CloneProductHandler)
 	httpRouter.DELETE("/product", productHandler.DeactiveProductHandler)
-	httpRouter.POST("/category", productHandler.AddCategoryHandler)
+	httpRouter.POST("/category", addCategoryHandler)
 	httpRouter.PUT("/category", productHandler.EditCategoryHandler)
+	httpRouter.PATCH("/category/{categoryID}", productHandler.PatchCategoryHandler)
+	httpRouter.POST("/category/{categoryID}/clone", productHandler.CloneCategoryHandler)
 	httpRouter.DELETE("/category", productHandler.DeactiveCategoryHandler)
+	httpRouter.GET("/product/{productID}/translation", productHandler.GetProductTranslationsHandler)
+	httpRouter.PUT("/product/{productID}/translation/{locale}", productHandler.SaveProductTranslationHandler)
+	httpRouter.DELETE("/product/{productID}/translation/{locale}", productHandler.DeleteProductTranslationHandler)
+	httpRouter.GET("/category/{categoryID}/translation", productHandler.GetCategoryTranslationsHandler)
+	httpRouter.PUT("/category/{categoryID}/translation/{locale}", productHandler.SaveCategoryTranslationHandler)
+	httpRouter.DELETE("/category/{categoryID}/translation/{locale}", productHandler.DeleteCategoryTranslationHandler)
+	httpRouter.POST("/product/{productID}/image", productHandler.AddProductImageHandler)
+	httpRouter.PUT("/product/{productID}/image/order", productHandler.ReorderProductImagesHandler)
+	httpRouter.PUT("/product/{productID}/image/{imageID:[0-9]+}", productHandler.EditProductImageHandler)
+	httpRouter.DELETE("/product/{productID}/image/{imageID:[0-9]+}", productHandler.DeleteProductImageHandler)
+	httpRouter.POST("/image", productHandler.UploadImageHandler)
+	httpRouter.POST("/catalogue/import", productHandler.ImportCatalogueHandler)
+	httpRouter.GET("/catalogue/export", productHandler.ExportCatalogueHandler)
+	httpRouter.POST("/catalogue/clone", productHandler.CloneCatalogueHandler)
+
+	menuEventsHandler := httpHandler.NewMenuEventsHandler(productService, cfg.MenuEvents.Heartbeat)
+	httpRouter.GET("/menu/events", menuEventsHandler.WatchMenuHandler)
+
+	webhookHandler := httpHandler.NewWebhookHandler(webhookService)
+	httpRouter.POST("/webhook", webhookHandler.AddWebhookHandler)
+	httpRouter.GET("/webhook", webhookHandler.GetWebhooksHandler)
+	httpRouter.DELETE("/webhook/{webhookID:[0-9]+}", webhookHandler.DeleteWebhookHandler)
+	httpRouter.GET("/webhook/dead-letter", webhookHandler.GetDeadWebhookDeliveriesHandler)
+	httpRouter.POST("/webhook/delivery/{deliveryID:[0-9]+}/redeliver", webhookHandler.RedeliverWebhookHandler)
+
+	if flag.Arg(0) == "import-catalogue" {
+		importCatalogue(productService)
+		return
+	}
+	if flag.Arg(0) == "export-catalogue" {
+		exportCatalogue(productService)
+		return
+	}
+	if flag.Arg(0) == "clone-catalogue" {
+		cloneCatalogue(productService)
+		return
+	}
+
+	imageGCService := service.NewImageGCService(imageRepository, productImageRepository, uploadedImageRepository, repository.NewUnitOfWork(db))
+	if flag.Arg(0) == "gc-images" {
+		collectImages(imageGCService, cfg)
+		return
+	}
+	if cfg.ImageGC.Interval > 0 {
+		go imageGCService.RunImageGC(context.Background(), cfg.ImageGC.Interval, cfg.ImageGC.GracePeriod)
+	}
+
+	if cfg.Webhook.Interval > 0 {
+		go webhookService.RunWebhookDelivery(context.Background(), cfg.Webhook.Interval)
+	}
+
+	// the changes of the menus recorded in the outbox, by this instance or another, are sent to the watchers
+	go menuEvents.RunMenuEvents(context.Background())
+
+	// the domain events recorded in the outbox are published to the broker
+	if cfg.Outbox.Interval > 0 && cfg.Outbox.Broker.Driver != "" {
+		eventBroker, err := broker.NewBroker(cfg.Outbox.Broker)
+		if err != nil {
+			logging.Log.Fatalf("Error creating message broker: %v", err)
+		}
+		defer eventBroker.Close()
+
+		outboxRelayService := service.NewOutboxRelayService(repository.NewUnitOfWork(db), repository.NewOutboxRepository(db), eventBroker)
+		go outboxRelayService.RunOutboxRelay(context.Background(), cfg.Outbox.Interval, cfg.Outbox.BatchSize, cfg.Outbox.Retention)
+	}
+
+	imageService := service.NewImageService(imageRepository)
+	imageHandler := httpHandler.NewImageHandler(imageService)
+	httpRouter.GET("/images/{name}", imageHandler.ServeImageHandler)
 
 	// Start HTTP server
 	go func() {
@@ -134,3 +241,163 @@ func initLogging(logFolder string) {
 		}
 	}()
 }
+
+// migrateImages copies the images of the local image directory into the bucket of the image storage.
+func migrateImages(cfg *config.Config) {
+	target, err := repository.NewS3ImagesRepository(cfg.ImageStorage, cfg.ImageBaseURL, cfg.ImageRenditions)
+	if err != nil {
+		logging.Log.Fatalf("Error creating image storage: %v", err)
+	}
+
+	copied, err := repository.MigrateLocalImages(cfg.ImagePath, target)
+	if err != nil {
+		logging.Log.Fatalf("Error migrating images after %d images: %v", copied, err)
+	}
+	logging.Log.Infof("Migrated %d images from %s to bucket %s", copied, cfg.ImagePath, cfg.ImageStorage.Bucket)
+}
+
+// collectImages expires the uploads older than the grace period, reports the orphaned and missing images and
+// removes the orphans older than the grace period. With -dry-run nothing is expired nor removed.
+func collectImages(imageGCService service.ImageGCService, cfg *config.Config) {
+	flags := flag.NewFlagSet("gc-images", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "report orphaned images without removing them")
+	gracePeriod := flags.Duration("grace-period", cfg.ImageGC.GracePeriod, "keep orphaned images younger than this")
+	flags.Parse(flag.Args()[1:])
+
+	report, err := imageGCService.CollectImages(context.Background(), *gracePeriod, *dryRun)
+	if err != nil {
+		logging.Log.Fatalf("Error collecting images: %v", err)
+	}
+
+	for _, name := range report.ExpiredUploads {
+		fmt.Println("expired", name)
+	}
+	for _, name := range report.Orphans {
+		fmt.Println("orphan", name)
+	}
+	for _, name := range report.Missing {
+		fmt.Println("missing", name)
+	}
+	for _, name := range report.Deleted {
+		fmt.Println("deleted", name)
+	}
+	fmt.Printf("%d expired uploads, %d orphans, %d missing, %d deleted\n", len(report.ExpiredUploads), len(report.Orphans), len(report.Missing), len(report.Deleted))
+}
+
+// importCatalogue imports a CSV, XLSX or JSON catalogue file into the categories and products of a client.
+// Images may be local paths, relative to the directory of the file. With -dry-run nothing is saved.
+func importCatalogue(productService service.ProductService) {
+	flags := flag.NewFlagSet("import-catalogue", flag.ExitOnError)
+	clientID := flags.Uint("client", 0, "ID of the client the catalogue belongs to")
+	dryRun := flags.Bool("dry-run", false, "report the changes without saving them")
+	format := flags.String("format", "", "format of the file, csv, xlsx or json (default from the file extension)")
+	flags.Parse(flag.Args()[1:])
+
+	if *clientID == 0 || flags.NArg() != 1 {
+		logging.Log.Fatalf("Usage: import-catalogue -client <id> [-dry-run] [-format csv|xlsx|json] <file>")
+	}
+	fileName := flags.Arg(0)
+	if *format == "" {
+		*format = catalogue.FormatOf(fileName)
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		logging.Log.Fatalf("Error opening catalogue: %v", err)
+	}
+	defer file.Close()
+
+	rows, err := catalogue.ReadRows(file, *format)
+	if err != nil {
+		logging.Log.Fatalf("Error reading catalogue %s: %v", fileName, err)
+	}
+
+	request := &model.CatalogueImportRequest{
+		ClientID: *clientID,
+		Rows:     rows,
+		DryRun:   *dryRun,
+		ImageDir: filepath.Dir(fileName),
+	}
+	report, appError := productService.ImportClientCatalogue(context.Background(), request)
+	if report != nil {
+		for _, name := range report.CreatedCategories {
+			fmt.Println("create category", name)
+		}
+		for _, row := range report.Rows {
+			fmt.Printf("line %d %s %s / %s\n", row.Line, row.Action, row.Category, row.Name)
+			for _, fieldError := range row.Errors {
+				fmt.Printf("  %s\n", fieldError.Message)
+			}
+		}
+		fmt.Printf("%d created, %d updated, %d unchanged, %d failed, applied: %t\n", report.Created, report.Updated, report.Unchanged, report.Failed, report.Applied)
+	}
+	if appError.Code != service.SuccessError {
+		logging.Log.Errorf("Error importing catalogue: %s", appError.Message)
+		os.Exit(1)
+	}
+}
+
+// exportCatalogue exports the catalogue of a client to a file, or to the standard output with "-".
+func exportCatalogue(productService service.ProductService) {
+	flags := flag.NewFlagSet("export-catalogue", flag.ExitOnError)
+	clientID := flags.Uint("client", 0, "ID of the client the catalogue belongs to")
+	includeInactive := flags.Bool("include-inactive", false, "export the inactive categories and products too")
+	format := flags.String("format", "", "format of the file, csv, xlsx or json (default from the file extension)")
+	flags.Parse(flag.Args()[1:])
+
+	if *clientID == 0 || flags.NArg() != 1 {
+		logging.Log.Fatalf("Usage: export-catalogue -client <id> [-include-inactive] [-format csv|xlsx|json] <file|->")
+	}
+	fileName := flags.Arg(0)
+	if *format == "" {
+		*format = catalogue.FormatOf(fileName)
+	}
+	if *format == "" {
+		*format = catalogue.FormatCSV
+	}
+
+	request := &model.CatalogueExportRequest{
+		ClientID:        *clientID,
+		IncludeInactive: *includeInactive,
+	}
+	rows, appError := productService.ExportClientCatalogue(context.Background(), request)
+	if appError.Code != service.SuccessError {
+		logging.Log.Fatalf("Error exporting catalogue: %s", appError.Message)
+	}
+
+	var file bytes.Buffer
+	if err := catalogue.WriteRows(&file, rows, *format); err != nil {
+		logging.Log.Fatalf("Error writing catalogue: %v", err)
+	}
+
+	if fileName == "-" {
+		os.Stdout.Write(file.Bytes())
+		return
+	}
+	if err := os.WriteFile(fileName, file.Bytes(), 0644); err != nil {
+		logging.Log.Fatalf("Error writing catalogue %s: %v", fileName, err)
+	}
+	fmt.Fprintf(os.Stderr, "%d products exported to %s\n", len(rows), fileName)
+}
+
+// cloneCatalogue copies every category and product of a client, with their images, into another client.
+func cloneCatalogue(productService service.ProductService) {
+	flags := flag.NewFlagSet("clone-catalogue", flag.ExitOnError)
+	from := flags.Uint("from", 0, "ID of the client the catalogue is copied from")
+	to := flags.Uint("to", 0, "ID of the client the catalogue is copied into")
+	flags.Parse(flag.Args()[1:])
+
+	if *from == 0 || *to == 0 || *from == *to {
+		logging.Log.Fatalf("Usage: clone-catalogue -from <id> -to <id>")
+	}
+
+	request := &model.CloneCatalogueRequest{
+		SourceClientID: *from,
+		TargetClientID: *to,
+	}
+	report, appError := productService.CloneClientCatalogue(context.Background(), request)
+	if appError.Code != service.SuccessError {
+		logging.Log.Fatalf("Error cloning catalogue: %s", appError.Message)
+	}
+	fmt.Printf("%d categories, %d products, %d images copied\n", report.Categories, report.Products, report.Images)
+}
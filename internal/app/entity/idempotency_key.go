@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"time"
+)
+
+// IdempotencyKey records the first response to a create request sent with an Idempotency-Key header,
+// replayed when the request is retried. RequestHash identifies the method, path and body of the request;
+// StatusCode is zero while the first request is still in progress. The request in progress holds the key
+// under LeaseID until LeaseExpiresAt, and renews the lease while it runs.
+type IdempotencyKey struct {
+	ClientID       uint      `gorm:"primaryKey;autoIncrement:false" json:"clientId"`
+	Key            string    `gorm:"primaryKey;size:255" json:"key"`
+	RequestHash    string    `gorm:"size:64" json:"requestHash"`
+	StatusCode     int       `json:"statusCode"`
+	Response       string    `gorm:"type:text" json:"response"`
+	LeaseID        string    `gorm:"size:36" json:"-"`
+	LeaseExpiresAt time.Time `json:"-"`
+	CreatedAt      time.Time `json:"createdAt"`
+	ExpiresAt      time.Time `gorm:"index" json:"expiresAt"`
+}
+
+// Set the table name explicitly for GORM
+func (IdempotencyKey) TableName() string {
+	return "idempotency_key"
+}
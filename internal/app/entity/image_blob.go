@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"time"
+)
+
+// ImageBlob counts the references to a stored image. Images are stored under the hash of their
+// content, so identical uploads share one file, which is removed with its last reference.
+// BlurHash and DominantColor are the placeholders shown by clients while the image loads.
+type ImageBlob struct {
+	Name          string    `gorm:"primaryKey;size:255" json:"name"`
+	RefCount      int       `json:"refCount"`
+	Size          int64     `json:"size"`
+	BlurHash      string    `gorm:"size:64" json:"blurhash"`
+	DominantColor string    `gorm:"size:7" json:"dominantColor"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// Set the table name explicitly for GORM
+func (ImageBlob) TableName() string {
+	return "image_blob"
+}
@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"time"
+)
+
+// ImageRendition is a resized copy of a stored image, generated when the image is uploaded.
+type ImageRendition struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ImageName string    `gorm:"size:255;index" json:"-"`
+	Name      string    `json:"name"`
+	Format    string    `json:"format"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	FileName  string    `json:"fileName"`
+	CreatedAt time.Time `json:"-"`
+	URL       string    `gorm:"-" json:"url"`
+}
+
+// Set the table name explicitly for GORM
+func (ImageRendition) TableName() string {
+	return "image_rendition"
+}
@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"time"
+)
+
+// Domain events recorded in the outbox for the changes of the catalogue.
+const (
+	EventProductCreated     = "ProductCreated"
+	EventProductUpdated     = "ProductUpdated"
+	EventProductDeactivated = "ProductDeactivated"
+	EventCategoryChanged    = "CategoryChanged"
+)
+
+// Aggregates the domain events are about.
+const (
+	AggregateProduct  = "product"
+	AggregateCategory = "category"
+)
+
+// OutboxEvent is a domain event recorded in the transaction of the change it describes, and published
+// to the broker by the outbox relay. MenuEventType is the type of the change for the watchers of the menu
+// of the client, who get the events from the outbox in the order of their IDs. Payload is the JSON of
+// the product or category after the change; PublishedAt is nil until the event is published.
+type OutboxEvent struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Type          string     `gorm:"size:64" json:"type"`
+	MenuEventType string     `gorm:"size:32" json:"menuEventType"`
+	AggregateType string     `gorm:"size:32" json:"aggregateType"`
+	AggregateID   uint       `json:"aggregateId"`
+	ClientID      uint       `gorm:"index" json:"clientId"`
+	Payload       string     `gorm:"type:text" json:"payload"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	PublishedAt   *time.Time `gorm:"index" json:"publishedAt"`
+}
+
+// Set the table name explicitly for GORM
+func (OutboxEvent) TableName() string {
+	return "outbox_event"
+}
@@ -5,14 +5,21 @@ import (
 )
 
 type Product struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	CategoryID  uint      `json:"categoryId"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Image       string    `json:"image"`
-	Price       float64   `json:"price"`
-	IsActive    bool      `json:"isActive"`
-	CreatedAt   time.Time `json:"createdAt"`
+	ID                 uint              `gorm:"primaryKey" json:"id"`
+	CategoryID         uint              `json:"categoryId"`
+	Name               string            `json:"name"`
+	Description        string            `json:"description"`
+	Image              string            `json:"image"`
+	Price              float64           `json:"price"`
+	IsActive           bool              `json:"isActive"`
+	CreatedAt          time.Time         `json:"createdAt"`
+	Version            uint              `gorm:"not null;default:1" json:"version"`
+	Images             []ProductImage    `gorm:"foreignKey:ProductID" json:"images"`
+	Tags               []ProductTag      `gorm:"foreignKey:ProductID" json:"tags"`
+	ImageURL           string            `gorm:"-" json:"imageUrl"`
+	ImageSrcSet        map[string]string `gorm:"-" json:"imageSrcset"`
+	ImageBlurHash      string            `gorm:"-" json:"imageBlurhash"`
+	ImageDominantColor string            `gorm:"-" json:"imageDominantColor"`
 }
 
 // Set the table name explicitly for GORM
@@ -6,10 +6,11 @@ import (
 
 type ProductCategory struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	ClientID  uint      `json:"clientId"`
+	ClientID  uint      `gorm:"index" json:"clientId"`
 	Name      string    `json:"name"`
 	IsActive  bool      `json:"isActive"`
 	CreatedAt time.Time `json:"createdAt"`
+	Version   uint      `gorm:"not null;default:1" json:"version"`
 	Products  []Product `gorm:"foreignKey:CategoryID" json:"products"`
 }
 
@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"time"
+)
+
+// ProductImage is one picture of the gallery of a product.
+type ProductImage struct {
+	ID            uint              `gorm:"primaryKey" json:"id"`
+	ProductID     uint              `gorm:"index" json:"productId"`
+	FileName      string            `json:"fileName"`
+	AltText       string            `json:"altText"`
+	Position      int               `json:"position"`
+	IsPrimary     bool              `json:"isPrimary"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	URL           string            `gorm:"-" json:"url"`
+	Renditions    []ImageRendition  `gorm:"-" json:"renditions"`
+	SrcSet        map[string]string `gorm:"-" json:"srcset"`
+	BlurHash      string            `gorm:"-" json:"blurhash"`
+	DominantColor string            `gorm:"-" json:"dominantColor"`
+}
+
+// Set the table name explicitly for GORM
+func (ProductImage) TableName() string {
+	return "product_image"
+}
@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"time"
+)
+
+// ProductTag is a label of a product, e.g. "vegan" or "seasonal". Tags are stored lowercase.
+type ProductTag struct {
+	ProductID uint      `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	Tag       string    `gorm:"primaryKey;size:50" json:"tag"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// Set the table name explicitly for GORM
+func (ProductTag) TableName() string {
+	return "product_tag"
+}
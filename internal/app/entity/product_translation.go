@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"time"
+)
+
+// ProductTranslation holds the localised name and description of a product.
+type ProductTranslation struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ProductID   uint      `gorm:"uniqueIndex:idx_product_translation_locale" json:"productId"`
+	Locale      string    `gorm:"size:10;uniqueIndex:idx_product_translation_locale" json:"locale"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Set the table name explicitly for GORM
+func (ProductTranslation) TableName() string {
+	return "product_translation"
+}
+
+// ProductCategoryTranslation holds the localised name of a product category.
+type ProductCategoryTranslation struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CategoryID uint      `gorm:"uniqueIndex:idx_category_translation_locale" json:"categoryId"`
+	Locale     string    `gorm:"size:10;uniqueIndex:idx_category_translation_locale" json:"locale"`
+	Name       string    `json:"name"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Set the table name explicitly for GORM
+func (ProductCategoryTranslation) TableName() string {
+	return "product_category_translation"
+}
@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"time"
+)
+
+// UploadedImage is an image uploaded ahead of the product that references it.
+type UploadedImage struct {
+	ID            uint              `gorm:"primaryKey" json:"id"`
+	ClientID      uint              `gorm:"index" json:"clientId"`
+	FileName      string            `json:"fileName"`
+	ContentType   string            `json:"contentType"`
+	Size          int64             `json:"size"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	URL           string            `gorm:"-" json:"url"`
+	Renditions    []ImageRendition  `gorm:"-" json:"renditions"`
+	SrcSet        map[string]string `gorm:"-" json:"srcset"`
+	BlurHash      string            `gorm:"-" json:"blurhash"`
+	DominantColor string            `gorm:"-" json:"dominantColor"`
+}
+
+// Set the table name explicitly for GORM
+func (UploadedImage) TableName() string {
+	return "uploaded_image"
+}
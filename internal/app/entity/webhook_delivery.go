@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"time"
+)
+
+// Statuses of a webhook delivery. A pending delivery is sent at NextAttemptAt; a dead delivery failed
+// every attempt and is only sent again when redelivered.
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookDead      = "dead"
+)
+
+// WebhookDelivery is a menu event sent, or to be sent, to a webhook. EventID identifies the event, shared
+// by the deliveries of the event to the different webhooks of the client.
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	SubscriptionID uint       `gorm:"index" json:"subscriptionId"`
+	ClientID       uint       `gorm:"index" json:"clientId"`
+	EventID        string     `gorm:"size:36" json:"eventId"`
+	EventType      string     `gorm:"size:64" json:"eventType"`
+	Payload        string     `gorm:"type:text" json:"payload"`
+	Status         string     `gorm:"size:16;index:idx_webhook_delivery_due,priority:1" json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index:idx_webhook_delivery_due,priority:2" json:"nextAttemptAt"`
+	LastStatusCode int        `json:"lastStatusCode"`
+	LastError      string     `gorm:"size:1024" json:"lastError"`
+	DeliveredAt    *time.Time `json:"deliveredAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// Set the table name explicitly for GORM
+func (WebhookDelivery) TableName() string {
+	return "webhook_delivery"
+}
@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"time"
+)
+
+// WebhookSubscription is a URL of a client notified of the menu events of EventTypes. The requests are
+// signed with Secret, which is only returned when the subscription is created.
+type WebhookSubscription struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ClientID   uint      `gorm:"index" json:"clientId"`
+	URL        string    `gorm:"size:2048" json:"url"`
+	EventTypes []string  `gorm:"serializer:json;type:text" json:"eventTypes"`
+	Secret     string    `gorm:"size:255" json:"secret,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Set the table name explicitly for GORM
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscription"
+}
@@ -0,0 +1,60 @@
+package model
+
+import "maqhaa/product_service/internal/app/entity"
+
+// Types of the operations of a bulk product update.
+const (
+	BulkSetPrice     = "set_price"
+	BulkAdjustPrice  = "adjust_price"
+	BulkMoveCategory = "move_category"
+	BulkActivate     = "activate"
+	BulkDeactivate   = "deactivate"
+	BulkAddTag       = "add_tag"
+	BulkRemoveTag    = "remove_tag"
+)
+
+// ProductBulkOperation is a change applied to every selected product. Price is the new price of
+// set_price, Percent the change of adjust_price (-10 lowers prices by 10%), CategoryID the category
+// of move_category and Tag the tag of add_tag and remove_tag.
+type ProductBulkOperation struct {
+	Type       string  `json:"type" validate:"required,oneof=set_price adjust_price move_category activate deactivate add_tag remove_tag"`
+	Price      float64 `json:"price"`
+	Percent    float64 `json:"percent"`
+	CategoryID uint    `json:"category_id"`
+	Tag        string  `json:"tag"`
+}
+
+// ProductBulkRequest applies the operations, in order, to the products with the given IDs and to the
+// products in the given categories. Either every product is changed or, when any fails, none is.
+type ProductBulkRequest struct {
+	ClientID    uint                   `json:"-"`
+	ProductIDs  []uint                 `json:"product_ids" validate:"required_without=CategoryIDs,max=500,dive,gt=0"`
+	CategoryIDs []uint                 `json:"category_ids" validate:"max=50,dive,gt=0"`
+	Operations  []ProductBulkOperation `json:"operations" validate:"required,min=1,max=20"`
+}
+
+// Statuses of the products of a bulk update.
+const (
+	BulkStatusUpdated   = "updated"
+	BulkStatusUnchanged = "unchanged"
+	BulkStatusFailed    = "failed"
+)
+
+// ProductBulkResult is the outcome of a bulk update for one product. Product is the product as
+// changed by the operations, and is only saved when the report is applied.
+type ProductBulkResult struct {
+	ProductID uint            `json:"productId"`
+	Status    string          `json:"status"`
+	Message   string          `json:"message,omitempty"`
+	Errors    []FieldError    `json:"errors,omitempty"`
+	Product   *entity.Product `json:"product,omitempty"`
+}
+
+// ProductBulkReport is the outcome of a bulk update. Applied is only set once the changes are saved.
+type ProductBulkReport struct {
+	Applied   bool                `json:"applied"`
+	Updated   int                 `json:"updated"`
+	Unchanged int                 `json:"unchanged"`
+	Failed    int                 `json:"failed"`
+	Results   []ProductBulkResult `json:"results"`
+}
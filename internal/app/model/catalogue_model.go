@@ -0,0 +1,59 @@
+package model
+
+// CatalogueRow is a product of a catalogue file. The price and the active flag are kept as written,
+// so every row can be validated and reported on. Line is the line of the row in the file, the header
+// being line 1, or the position of the product in a JSON catalogue. Image is an http(s) URL, the URL or name of a stored image, or a local path.
+type CatalogueRow struct {
+	Line        int    `json:"line"`
+	Category    string `json:"category" validate:"required"`
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+	Price       string `json:"price" validate:"required,numeric"`
+	Image       string `json:"image"`
+	Active      string `json:"active" validate:"omitempty,oneof=true false yes no 1 0"`
+}
+
+// CatalogueImportRequest imports the rows of a catalogue file into the categories and products of a client.
+// Nothing is changed with DryRun, or when any row is invalid. Images given as local paths are only read
+// with an ImageDir, which relative paths are resolved against.
+type CatalogueImportRequest struct {
+	ClientID uint
+	Rows     []CatalogueRow
+	DryRun   bool
+	ImageDir string
+}
+
+// CatalogueExportRequest exports the categories and products of a client, by default only the active ones.
+type CatalogueExportRequest struct {
+	ClientID        uint
+	IncludeInactive bool
+}
+
+// Actions of the rows of a catalogue import.
+const (
+	CatalogueActionCreate    = "create"
+	CatalogueActionUpdate    = "update"
+	CatalogueActionUnchanged = "unchanged"
+	CatalogueActionError     = "error"
+)
+
+// CatalogueRowResult is the outcome of the import of a row.
+type CatalogueRowResult struct {
+	Line     int          `json:"line"`
+	Category string       `json:"category"`
+	Name     string       `json:"name"`
+	Action   string       `json:"action"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// CatalogueImportReport is the outcome of a catalogue import. Applied is only set once the changes are saved.
+type CatalogueImportReport struct {
+	DryRun            bool                 `json:"dryRun"`
+	Applied           bool                 `json:"applied"`
+	CreatedCategories []string             `json:"createdCategories"`
+	Created           int                  `json:"created"`
+	Updated           int                  `json:"updated"`
+	Unchanged         int                  `json:"unchanged"`
+	Failed            int                  `json:"failed"`
+	Rows              []CatalogueRowResult `json:"rows"`
+}
@@ -0,0 +1,32 @@
+package model
+
+// CloneProductRequest copies a product into CategoryID, a category of the same client, or into the
+// category of the product when 0. The copy is named Name, or after the product with a " (copy)" suffix
+// when it stays in the same category.
+type CloneProductRequest struct {
+	ProductID  uint   `json:"-"`
+	CategoryID uint   `json:"category_id"`
+	Name       string `json:"name" validate:"max=255"`
+}
+
+// CloneCategoryRequest copies a category with all its products. The copy is named Name, or after the
+// category with a " (copy)" suffix.
+type CloneCategoryRequest struct {
+	CategoryID uint   `json:"-"`
+	Name       string `json:"name" validate:"max=255"`
+}
+
+// CloneCatalogueRequest copies every category and product of a client into another client. Over the API,
+// the target client is the client of TargetToken, which must be an admin token too.
+type CloneCatalogueRequest struct {
+	SourceClientID uint   `json:"-"`
+	TargetClientID uint   `json:"-"`
+	TargetToken    string `json:"target_token"`
+}
+
+// CloneCatalogueReport counts the categories, products and product images copied by a catalogue clone.
+type CloneCatalogueReport struct {
+	Categories int `json:"categories"`
+	Products   int `json:"products"`
+	Images     int `json:"images"`
+}
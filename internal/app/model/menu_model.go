@@ -0,0 +1,43 @@
+package model
+
+import (
+	"maqhaa/product_service/internal/app/entity"
+	"time"
+)
+
+// Types of the menu events.
+const (
+	MenuSnapshot            = "snapshot"
+	MenuProductCreated      = "product_created"
+	MenuProductUpdated      = "product_updated"
+	MenuProductDeactivated  = "product_deactivated"
+	MenuProductAvailability = "product_availability"
+	MenuCategoryCreated     = "category_created"
+	MenuCategoryUpdated     = "category_updated"
+	MenuCategoryDeactivated = "category_deactivated"
+)
+
+// MenuEvent is a change of the menu of a client. Sequence is the ID of the change in the outbox, the
+// same on every instance; a watcher reconnecting after an event resumes from its sequence. Product and
+// Category are the product or category of the event as it is when delivered, and Categories the whole
+// menu of a snapshot.
+type MenuEvent struct {
+	Sequence   uint64                   `json:"sequence"`
+	Type       string                   `json:"type"`
+	ClientID   uint                     `json:"-"`
+	ProductID  uint                     `json:"productId,omitempty"`
+	CategoryID uint                     `json:"categoryId,omitempty"`
+	CreatedAt  time.Time                `json:"createdAt"`
+	Product    *entity.Product          `json:"product,omitempty"`
+	Category   *entity.ProductCategory  `json:"category,omitempty"`
+	Categories []entity.ProductCategory `json:"categories,omitempty"`
+}
+
+// WatchMenuRequest watches the menu of the client of Token. A watch starts with a snapshot of the menu,
+// unless AfterSequence is the sequence of an event still kept, in which case the events after it are
+// sent first.
+type WatchMenuRequest struct {
+	Token         string
+	Locale        string
+	AfterSequence uint64
+}
@@ -21,16 +21,102 @@ type GetProductResponse struct {
 	} `json:"data,omitempty"`
 }
 
+// ProductCategoryRequest adds or replaces a category. Version is the version the request was made from,
+// 0 for any version, and is set to the new version once the request succeeds. When adding, ID is set to
+// the ID of the new category.
 type ProductCategoryRequest struct {
 	ID       uint
+	Version  uint   `json:"-"`
 	Category string `json:"category" validate:"required"`
 }
 
+// ProductRequest adds or replaces a product. When editing, Version is the version the request was
+// made from, 0 for any version, and is set to the new version once the edit succeeds. When adding,
+// ID and Version are set to those of the new product.
 type ProductRequest struct {
 	ID          uint
+	Version     uint    `json:"-"`
 	CategoryID  uint    `json:"category_id" validate:"required"`
 	Name        string  `json:"name" validate:"required"`
 	Description string  `json:"description" validate:"required"`
-	Image       string  `json:"image" validate:"required"`
+	Image       string  `json:"image" validate:"required_without=ImageID"`
+	ImageID     uint    `json:"image_id"`
 	Price       float64 `json:"price" validate:"required"`
 }
+
+// ProductCategoryPatchRequest is a JSON merge patch of a category: only the fields present are changed.
+// Version works as in ProductCategoryRequest.
+type ProductCategoryPatchRequest struct {
+	ID       uint
+	Version  uint    `json:"-"`
+	Category *string `json:"category" validate:"omitempty,min=1"`
+}
+
+// ProductPatchRequest is a JSON merge patch of a product: only the fields present are changed.
+// The image is kept unless a new Image or ImageID is given. Version works as in ProductRequest.
+type ProductPatchRequest struct {
+	ID          uint
+	Version     uint     `json:"-"`
+	CategoryID  *uint    `json:"category_id" validate:"omitempty,gt=0"`
+	Name        *string  `json:"name" validate:"omitempty,min=1"`
+	Description *string  `json:"description"`
+	Image       *string  `json:"image" validate:"omitempty,min=1"`
+	ImageID     *uint    `json:"image_id" validate:"omitempty,gt=0"`
+	Price       *float64 `json:"price" validate:"omitempty,gt=0"`
+}
+
+type ProductTranslationRequest struct {
+	ProductID   uint
+	Locale      string
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+}
+
+type CategoryTranslationRequest struct {
+	CategoryID uint
+	Locale     string
+	Name       string `json:"name" validate:"required"`
+}
+
+// ProductImageRequest adds an image to the gallery of a product. A change of the gallery is a change of
+// the product, and Version works as in ProductRequest; the same goes for the other gallery requests.
+type ProductImageRequest struct {
+	ProductID uint
+	Version   uint   `json:"-"`
+	Image     string `json:"image" validate:"required_without=ImageID"`
+	ImageID   uint   `json:"image_id"`
+	AltText   string `json:"alt_text"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+type EditProductImageRequest struct {
+	ProductID uint
+	ImageID   uint
+	Version   uint   `json:"-"`
+	AltText   string `json:"alt_text"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+type RemoveProductImageRequest struct {
+	ProductID uint
+	ImageID   uint
+	Version   uint
+}
+
+type ReorderProductImagesRequest struct {
+	ProductID uint
+	Version   uint   `json:"-"`
+	ImageIDs  []uint `json:"image_ids" validate:"required,min=1"`
+}
+
+type ImageRequest struct {
+	Name   string
+	Width  uint   `json:"w" validate:"max=4000"`
+	Height uint   `json:"h" validate:"max=4000"`
+	Format string `json:"format" validate:"omitempty,oneof=jpeg png webp"`
+}
+
+// GetProductsRequest fetches several products of the client of the token at once.
+type GetProductsRequest struct {
+	ProductIDs []uint `json:"product_ids" validate:"required,min=1,max=100,dive,gt=0"`
+}
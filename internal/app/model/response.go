@@ -4,9 +4,18 @@ package model
 
 // HTTPResponse represents a standard HTTP response format.
 type HTTPResponse struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Code    int          `json:"code"`
+	Message string       `json:"message"`
+	Data    interface{}  `json:"data,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why a single field of a request failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
 }
 
 // NewHTTPResponse creates a new HTTPResponse instance with the provided code, message, and optional data.
@@ -17,3 +26,9 @@ func NewHTTPResponse(code int, message string, data interface{}) *HTTPResponse {
 		Data:    data,
 	}
 }
+
+// WithErrors attaches per-field validation errors to the response.
+func (r *HTTPResponse) WithErrors(errors []FieldError) *HTTPResponse {
+	r.Errors = errors
+	return r
+}
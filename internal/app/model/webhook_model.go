@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// WebhookRequest subscribes an https URL of the client to menu events, named as the events of the menu feed,
+// e.g. product_updated. The requests to the URL are signed with Secret, or with a secret generated when empty.
+type WebhookRequest struct {
+	URL        string   `json:"url" validate:"required,http_url,max=2048"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,max=7,unique,dive,oneof=product_created product_updated product_deactivated product_availability category_created category_updated category_deactivated"`
+	Secret     string   `json:"secret" validate:"omitempty,min=16,max=255"`
+}
+
+// WebhookEvent is the body of a webhook request. It names the product or category changed; the change
+// itself is read from the API. ID identifies the event, and is repeated when a delivery is retried.
+type WebhookEvent struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	ClientID   uint      `json:"clientId"`
+	ProductID  uint      `json:"productId,omitempty"`
+	CategoryID uint      `json:"categoryId,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
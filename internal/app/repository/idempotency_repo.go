@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/entity"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyRepository handles database interactions related to idempotency keys.
+type IdempotencyRepository interface {
+	// ReserveIdempotencyKey creates the key, or returns false when the client already used it.
+	ReserveIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) (bool, error)
+	GetIdempotencyKey(ctx context.Context, clientID uint, key string) (*entity.IdempotencyKey, error)
+	// TakeOverIdempotencyKey reserves again, under the lease of key, a key without a response whose lease
+	// expired at now, or returns false when the key got a response or was reserved again in the meantime.
+	TakeOverIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey, now time.Time) (bool, error)
+	// RenewIdempotencyLease extends the lease of a reserved key, or returns false when the lease was lost.
+	RenewIdempotencyLease(ctx context.Context, key *entity.IdempotencyKey) (bool, error)
+	// SaveIdempotencyResponse stores the response of a reserved key, unless another lease holds the key.
+	SaveIdempotencyResponse(ctx context.Context, key *entity.IdempotencyKey) error
+	// ReleaseIdempotencyKey removes a reserved key, unless another lease holds the key.
+	ReleaseIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error
+	// DeleteExpiredIdempotencyKey removes a key of a client, if it expired at now.
+	DeleteExpiredIdempotencyKey(ctx context.Context, clientID uint, key string, now time.Time) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository instance.
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{
+		db: db,
+	}
+}
+
+func (r *idempotencyRepository) ReserveIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) (bool, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ReserveIdempotencyKey %s", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *idempotencyRepository) GetIdempotencyKey(ctx context.Context, clientID uint, key string) (*entity.IdempotencyKey, error) {
+	var idempotencyKey entity.IdempotencyKey
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("client_id = ? AND `key` = ?", clientID, key).First(&idempotencyKey).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetIdempotencyKey %s", err.Error())
+		return nil, err
+	}
+	return &idempotencyKey, nil
+}
+
+func (r *idempotencyRepository) TakeOverIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey, now time.Time) (bool, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	updates := map[string]interface{}{
+		"RequestHash":    key.RequestHash,
+		"LeaseID":        key.LeaseID,
+		"LeaseExpiresAt": key.LeaseExpiresAt,
+		"ExpiresAt":      key.ExpiresAt,
+	}
+	result := r.db.Model(&entity.IdempotencyKey{}).
+		Where("client_id = ? AND `key` = ? AND status_code = 0 AND (lease_expires_at IS NULL OR lease_expires_at <= ?)", key.ClientID, key.Key, now).
+		Updates(updates)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error TakeOverIdempotencyKey %s", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *idempotencyRepository) RenewIdempotencyLease(ctx context.Context, key *entity.IdempotencyKey) (bool, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Model(&entity.IdempotencyKey{}).
+		Where("client_id = ? AND `key` = ? AND lease_id = ? AND status_code = 0", key.ClientID, key.Key, key.LeaseID).
+		Update("LeaseExpiresAt", key.LeaseExpiresAt)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error RenewIdempotencyLease %s", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *idempotencyRepository) SaveIdempotencyResponse(ctx context.Context, key *entity.IdempotencyKey) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	updates := map[string]interface{}{
+		"StatusCode": key.StatusCode,
+		"Response":   key.Response,
+	}
+	result := r.db.Model(&entity.IdempotencyKey{}).
+		Where("client_id = ? AND `key` = ? AND lease_id = ?", key.ClientID, key.Key, key.LeaseID).
+		Updates(updates)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error SaveIdempotencyResponse %s", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) ReleaseIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Where("client_id = ? AND `key` = ? AND lease_id = ?", key.ClientID, key.Key, key.LeaseID).Delete(&entity.IdempotencyKey{})
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ReleaseIdempotencyKey %s", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) DeleteExpiredIdempotencyKey(ctx context.Context, clientID uint, key string, now time.Time) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Where("client_id = ? AND `key` = ? AND expires_at <= ?", clientID, key, now).Delete(&entity.IdempotencyKey{})
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error DeleteExpiredIdempotencyKey %s", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys removes the keys expired at now and returns how many were removed.
+func (r *idempotencyRepository) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Where("expires_at <= ?", now).Delete(&entity.IdempotencyKey{})
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error DeleteExpiredIdempotencyKeys %s", result.Error.Error())
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// MigrateLocalImages copies every image of a local image directory into the target repository
+// and returns the number of images copied. Resized copies are skipped, they are generated again
+// on demand. Images already in the target are overwritten, so the migration can be run again.
+func MigrateLocalImages(imagePath string, target ImagesRepository) (int, error) {
+	entries, err := ioutil.ReadDir(imagePath)
+	if err != nil {
+		return 0, err
+	}
+
+	copied := 0
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(imagePath, entry.Name()))
+		if err != nil {
+			return copied, err
+		}
+		if err := target.SaveImage(data, entry.Name()); err != nil {
+			return copied, err
+		}
+		copied++
+	}
+	return copied, nil
+}
@@ -1,25 +1,79 @@
 package repository
 
 import (
+	"bytes"
 	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
 	"io/ioutil"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/config"
+	"maqhaa/product_service/internal/webp"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nfnt/resize"
 )
 
+// imageCacheDir is the directory, inside the image path, holding the resized copies of images.
+const imageCacheDir = "cache"
+
+// imageCacheMaxSize is the number of bytes of resized copies kept in the cache; the oldest are removed beyond it.
+const imageCacheMaxSize = 1 << 30
+
+// imageSizes are the widths and heights, besides the widths of the renditions, images are resized to.
+// Requested sizes are rounded up to one of them, so every image has a bounded number of resized copies.
+var imageSizes = []uint{100, 200, 400, 800, 1200, 1600, 2400}
+
+// ImagesRepository stores images. NewImagesRepository keeps them in a local directory and
+// NewS3ImagesRepository in an S3-compatible bucket; NewImagesRepositoryFromConfig picks one.
 type ImagesRepository interface {
 	SaveImage(data []byte, filename string) error
+	SaveRenditions(img image.Image, imageName string) ([]entity.ImageRendition, error)
+	OpenImage(imageName string) (ImageFile, error)
+	OpenResizedImage(imageName string, width uint, height uint, format string) (ImageFile, error)
 	RemoveImage(imageName string) error
+	ListImages() ([]fs.FileInfo, error)
 	GetPath() string
+	GetURL(imageName string) string
+	GetSignedURL(imageName string, expiry time.Duration) (string, error)
+}
+
+// ImageFile is a stored image opened for reading. Missing images are reported with an error matching os.ErrNotExist.
+type ImageFile interface {
+	io.ReadSeekCloser
+	Stat() (fs.FileInfo, error)
+}
+
+// NewImagesRepositoryFromConfig creates the ImagesRepository of the storage driver selected in the configuration.
+func NewImagesRepositoryFromConfig(cfg *config.Config) (ImagesRepository, error) {
+	switch cfg.ImageStorage.Driver {
+	case "", "local":
+		return NewImagesRepository(cfg.ImagePath, cfg.ImageBaseURL, cfg.ImageRenditions), nil
+	case "s3":
+		return NewS3ImagesRepository(cfg.ImageStorage, cfg.ImageBaseURL, cfg.ImageRenditions)
+	default:
+		return nil, fmt.Errorf("unsupported image storage driver: %s", cfg.ImageStorage.Driver)
+	}
 }
 
 type imagesRepository struct {
-	imagePath string
+	imagePath  string
+	baseURL    string
+	renditions []config.ImageRendition
 }
 
-func NewImagesRepository(imagePath string) ImagesRepository {
+func NewImagesRepository(imagePath string, baseURL string, renditions []config.ImageRendition) ImagesRepository {
 	return &imagesRepository{
-		imagePath: imagePath,
+		imagePath:  imagePath,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		renditions: renditions,
 	}
 }
 
@@ -32,24 +86,329 @@ func (r *imagesRepository) SaveImage(data []byte, filename string) error {
 	return ioutil.WriteFile(filepath.Join(r.imagePath, filename), data, 0644)
 }
 
+// SaveRenditions resizes the image to every configured rendition and stores the results next to it.
+func (r *imagesRepository) SaveRenditions(img image.Image, imageName string) ([]entity.ImageRendition, error) {
+	return saveRenditions(img, imageName, r.renditions, r.SaveImage)
+}
+
+// OpenImage opens a stored image. Names that are not plain file names are reported as not existing.
+func (r *imagesRepository) OpenImage(imageName string) (ImageFile, error) {
+	if !isImageName(imageName) {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(filepath.Join(r.imagePath, imageName))
+}
+
+// OpenResizedImage opens a copy of a stored image that fits in width x height, in the given format.
+// A zero width, height or format keeps the one of the image. Sizes are rounded up to the allowed
+// ones and images are never enlarged; when the copy would be the image itself, the image is opened.
+// Resized copies are generated on first use and kept in the cache directory, the oldest being
+// removed once it holds more than imageCacheMaxSize bytes.
+func (r *imagesRepository) OpenResizedImage(imageName string, width uint, height uint, format string) (ImageFile, error) {
+	if !isImageName(imageName) {
+		return nil, os.ErrNotExist
+	}
+
+	source, err := os.Open(filepath.Join(r.imagePath, imageName))
+	if err != nil {
+		return nil, err
+	}
+	width, height, format, resized, err := resizeTarget(source, imageName, width, height, format, r.renditions)
+	if err != nil {
+		source.Close()
+		return nil, err
+	}
+	if !resized {
+		return source, nil
+	}
+
+	cachePath := filepath.Join(r.imagePath, resizedImageName(imageName, width, height, format))
+	if file, err := os.Open(cachePath); err == nil {
+		source.Close()
+		return file, nil
+	}
+
+	data, err := resizeImage(source, width, height, format, imageName)
+	source.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// Write to a temporary file first so concurrent requests never read a partial copy.
+	if err := os.MkdirAll(filepath.Dir(cachePath), os.ModePerm); err != nil {
+		return nil, err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(cachePath), ".resize-*")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), cachePath); err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	file, err := os.Open(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	r.pruneCache()
+	return file, nil
+}
+
+// pruneCache removes the oldest resized copies while the cache directory holds more than imageCacheMaxSize bytes.
+func (r *imagesRepository) pruneCache() {
+	cacheDir := filepath.Join(r.imagePath, imageCacheDir)
+	entries, err := ioutil.ReadDir(cacheDir)
+	if err != nil {
+		return
+	}
+
+	cached := []fs.FileInfo{}
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			cached = append(cached, entry)
+		}
+	}
+	for _, name := range cacheOverflow(cached) {
+		os.Remove(filepath.Join(cacheDir, name))
+	}
+}
+
+// RemoveImage removes an image together with its renditions and its resized copies.
 func (r *imagesRepository) RemoveImage(imageName string) error {
+	for _, rendition := range r.renditions {
+		os.Remove(filepath.Join(r.imagePath, renditionName(imageName, rendition)))
+	}
+	if isImageName(imageName) {
+		cached, _ := filepath.Glob(filepath.Join(r.imagePath, resizedImagePrefix(imageName)+"*x*.*"))
+		for _, path := range cached {
+			os.Remove(path)
+		}
+	}
 
 	imagePath := filepath.Join(r.imagePath, imageName)
-	fmt.Println("remove image ", imagePath)
 	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
-		fmt.Println(fmt.Errorf("file does not exist: %s", imageName))
 		return fmt.Errorf("file does not exist: %s", imageName)
 	}
 
 	err := os.Remove(imagePath)
 	if err != nil {
-		fmt.Println(fmt.Errorf("failed to remove file: %w", err))
 		return fmt.Errorf("failed to remove file: %w", err)
 	}
-	fmt.Println("success remove image")
 	return nil
 }
 
+// ListImages lists the stored images and renditions. Resized copies are not listed.
+func (r *imagesRepository) ListImages() ([]fs.FileInfo, error) {
+	entries, err := ioutil.ReadDir(r.imagePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	images := []fs.FileInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		images = append(images, entry)
+	}
+	return images, nil
+}
+
 func (r *imagesRepository) GetPath() string {
 	return r.imagePath
 }
+
+// GetURL returns the public URL of an image, or an empty string when there is no image.
+func (r *imagesRepository) GetURL(imageName string) string {
+	if imageName == "" {
+		return ""
+	}
+	if r.baseURL == "" {
+		return imageName
+	}
+	return r.baseURL + "/" + imageName
+}
+
+// GetSignedURL returns the public URL of an image. Local images are served without authentication.
+func (r *imagesRepository) GetSignedURL(imageName string, expiry time.Duration) (string, error) {
+	return r.GetURL(imageName), nil
+}
+
+// saveRenditions resizes the image to every rendition and stores the results with save.
+func saveRenditions(img image.Image, imageName string, configured []config.ImageRendition, save func(data []byte, filename string) error) ([]entity.ImageRendition, error) {
+	renditions := []entity.ImageRendition{}
+	for _, rendition := range configured {
+		resized := img
+		if int(rendition.Width) < img.Bounds().Dx() {
+			resized = resize.Resize(rendition.Width, 0, img, resize.Lanczos3)
+		}
+
+		data, err := encodeImage(resized, rendition.Format)
+		if err != nil {
+			return nil, err
+		}
+
+		fileName := renditionName(imageName, rendition)
+		if err := save(data, fileName); err != nil {
+			return nil, err
+		}
+
+		renditions = append(renditions, entity.ImageRendition{
+			ImageName: imageName,
+			Name:      rendition.Name,
+			Format:    rendition.Format,
+			Width:     resized.Bounds().Dx(),
+			Height:    resized.Bounds().Dy(),
+			FileName:  fileName,
+		})
+	}
+	return renditions, nil
+}
+
+// resizeImage decodes an image and encodes a copy that fits in width x height. A zero width,
+// height or format keeps the one of the image, and images are never enlarged.
+func resizeImage(source io.Reader, width uint, height uint, format string, imageName string) ([]byte, error) {
+	img, _, err := image.Decode(source)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	switch {
+	case width > 0 && height > 0 && (int(width) < bounds.Dx() || int(height) < bounds.Dy()):
+		img = resize.Thumbnail(width, height, img, resize.Lanczos3)
+	case width > 0 && height == 0 && int(width) < bounds.Dx():
+		img = resize.Resize(width, 0, img, resize.Lanczos3)
+	case height > 0 && width == 0 && int(height) < bounds.Dy():
+		img = resize.Resize(0, height, img, resize.Lanczos3)
+	}
+
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(imageName), ".")
+	}
+	return encodeImage(img, format)
+}
+
+// resizeTarget reads the size of the image in source, positioned at its start, and returns the width,
+// height and format of its copy fitting in width x height. Sizes are rounded up to the allowed ones and
+// are zero when the image already fits, and the format defaults to the one of the image. resized is
+// false when the copy would be the image itself. source is left positioned at its start.
+func resizeTarget(source io.ReadSeeker, imageName string, width uint, height uint, format string, renditions []config.ImageRendition) (uint, uint, string, bool, error) {
+	cfg, _, err := image.DecodeConfig(source)
+	if err != nil {
+		return 0, 0, "", false, err
+	}
+	if _, err := source.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, "", false, err
+	}
+
+	sizes := append([]uint{}, imageSizes...)
+	for _, rendition := range renditions {
+		sizes = append(sizes, rendition.Width)
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+	width, height = allowedSize(width, sizes), allowedSize(height, sizes)
+	if int(width) >= cfg.Width {
+		width = 0
+	}
+	if int(height) >= cfg.Height {
+		height = 0
+	}
+
+	original := strings.TrimPrefix(filepath.Ext(imageName), ".")
+	if format == "" {
+		format = original
+	}
+	return width, height, format, width > 0 || height > 0 || format != original, nil
+}
+
+// allowedSize rounds size up to the smallest of the sorted sizes that is not smaller, or down to the
+// largest of them. A zero size stays zero.
+func allowedSize(size uint, sizes []uint) uint {
+	if size == 0 {
+		return 0
+	}
+	for _, allowed := range sizes {
+		if allowed >= size {
+			return allowed
+		}
+	}
+	return sizes[len(sizes)-1]
+}
+
+// cacheOverflow returns the names of the oldest of the cached files whose removal brings the size of
+// the cache down to imageCacheMaxSize bytes.
+func cacheOverflow(cached []fs.FileInfo) []string {
+	total := int64(0)
+	for _, file := range cached {
+		total += file.Size()
+	}
+	if total <= imageCacheMaxSize {
+		return nil
+	}
+
+	sort.Slice(cached, func(i, j int) bool { return cached[i].ModTime().Before(cached[j].ModTime()) })
+	names := []string{}
+	for _, file := range cached {
+		if total <= imageCacheMaxSize {
+			break
+		}
+		names = append(names, file.Name())
+		total -= file.Size()
+	}
+	return names
+}
+
+// resizedImageName returns the name of a resized copy in the cache directory, e.g. cache/PR-1-150x0.webp.
+func resizedImageName(imageName string, width uint, height uint, format string) string {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(imageName), ".")
+	}
+	return fmt.Sprintf("%s%dx%d.%s", resizedImagePrefix(imageName), width, height, format)
+}
+
+// resizedImagePrefix returns the prefix shared by the names of all the resized copies of an image.
+func resizedImagePrefix(imageName string) string {
+	return imageCacheDir + "/" + strings.TrimSuffix(imageName, filepath.Ext(imageName)) + "-"
+}
+
+// isImageName reports whether name is a plain file name, so it cannot point outside the image directory.
+func isImageName(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name && !strings.ContainsAny(name, `/\`)
+}
+
+// renditionName returns the file name of a rendition, e.g. PR-1700000000000-thumbnail.webp.
+func renditionName(imageName string, rendition config.ImageRendition) string {
+	return fmt.Sprintf("%s-%s.%s", strings.TrimSuffix(imageName, filepath.Ext(imageName)), rendition.Name, rendition.Format)
+}
+
+func encodeImage(img image.Image, format string) ([]byte, error) {
+	var b bytes.Buffer
+	switch format {
+	case "jpeg":
+		if err := jpeg.Encode(&b, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+	case "png":
+		if err := png.Encode(&b, img); err != nil {
+			return nil, err
+		}
+	case "webp":
+		if err := webp.Encode(&b, img); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported rendition format: %s", format)
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io/fs"
+	"maqhaa/library/logging"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/config"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type s3ImagesRepository struct {
+	client          *minio.Client
+	bucket          string
+	baseURL         string
+	signedURLExpiry time.Duration
+	renditions      []config.ImageRendition
+}
+
+// NewS3ImagesRepository creates an ImagesRepository that keeps images in a bucket of an S3-compatible service such as MinIO.
+// Image URLs are presigned when the storage has a SignedURLExpiry, otherwise they point to baseURL,
+// or to the bucket itself when there is no base URL.
+func NewS3ImagesRepository(storage config.ImageStorageConfig, baseURL string, renditions []config.ImageRendition) (ImagesRepository, error) {
+	client, err := minio.New(storage.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(storage.AccessKeyID, storage.SecretAccessKey, ""),
+		Secure: storage.UseSSL,
+		Region: storage.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating S3 client: %v", err)
+	}
+
+	return &s3ImagesRepository{
+		client:          client,
+		bucket:          storage.Bucket,
+		baseURL:         strings.TrimRight(baseURL, "/"),
+		signedURLExpiry: storage.SignedURLExpiry,
+		renditions:      renditions,
+	}, nil
+}
+
+func (r *s3ImagesRepository) SaveImage(data []byte, filename string) error {
+	_, err := r.client.PutObject(context.Background(), r.bucket, filename, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: mime.TypeByExtension(path.Ext(filename)),
+	})
+	return err
+}
+
+// SaveRenditions resizes the image to every configured rendition and stores the results next to it.
+func (r *s3ImagesRepository) SaveRenditions(img image.Image, imageName string) ([]entity.ImageRendition, error) {
+	return saveRenditions(img, imageName, r.renditions, r.SaveImage)
+}
+
+// OpenImage opens a stored image. Names that are not plain file names are reported as not existing.
+func (r *s3ImagesRepository) OpenImage(imageName string) (ImageFile, error) {
+	if !isImageName(imageName) {
+		return nil, os.ErrNotExist
+	}
+	return r.openObject(imageName)
+}
+
+// OpenResizedImage opens a copy of a stored image that fits in width x height, in the given format.
+// Sizes are rounded up to the allowed ones; when the copy would be the image itself, the image is opened.
+// Resized copies are generated on first use and kept in the cache prefix of the bucket, the oldest
+// being removed once it holds more than imageCacheMaxSize bytes.
+func (r *s3ImagesRepository) OpenResizedImage(imageName string, width uint, height uint, format string) (ImageFile, error) {
+	if !isImageName(imageName) {
+		return nil, os.ErrNotExist
+	}
+
+	source, err := r.openObject(imageName)
+	if err != nil {
+		return nil, err
+	}
+	width, height, format, resized, err := resizeTarget(source, imageName, width, height, format, r.renditions)
+	if err != nil {
+		source.Close()
+		return nil, err
+	}
+	if !resized {
+		return source, nil
+	}
+
+	cacheName := resizedImageName(imageName, width, height, format)
+	if file, err := r.openObject(cacheName); err == nil {
+		source.Close()
+		return file, nil
+	}
+
+	data, err := resizeImage(source, width, height, format, imageName)
+	source.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.SaveImage(data, cacheName); err != nil {
+		return nil, err
+	}
+	file, err := r.openObject(cacheName)
+	if err != nil {
+		return nil, err
+	}
+	r.pruneCache()
+	return file, nil
+}
+
+// pruneCache removes the oldest resized copies while the cache prefix holds more than imageCacheMaxSize bytes.
+func (r *s3ImagesRepository) pruneCache() {
+	ctx := context.Background()
+	cached := []fs.FileInfo{}
+	for object := range r.client.ListObjects(ctx, r.bucket, minio.ListObjectsOptions{Prefix: imageCacheDir + "/"}) {
+		if object.Err != nil {
+			return
+		}
+		cached = append(cached, s3FileInfo{info: object})
+	}
+	for _, name := range cacheOverflow(cached) {
+		r.client.RemoveObject(ctx, r.bucket, imageCacheDir+"/"+name, minio.RemoveObjectOptions{})
+	}
+}
+
+// RemoveImage removes an image together with its renditions and its resized copies.
+func (r *s3ImagesRepository) RemoveImage(imageName string) error {
+	ctx := context.Background()
+	for _, rendition := range r.renditions {
+		r.client.RemoveObject(ctx, r.bucket, renditionName(imageName, rendition), minio.RemoveObjectOptions{})
+	}
+	if isImageName(imageName) {
+		for object := range r.client.ListObjects(ctx, r.bucket, minio.ListObjectsOptions{Prefix: resizedImagePrefix(imageName)}) {
+			if object.Err == nil {
+				r.client.RemoveObject(ctx, r.bucket, object.Key, minio.RemoveObjectOptions{})
+			}
+		}
+	}
+
+	if _, err := r.client.StatObject(ctx, r.bucket, imageName, minio.StatObjectOptions{}); err != nil {
+		return fmt.Errorf("file does not exist: %s", imageName)
+	}
+	if err := r.client.RemoveObject(ctx, r.bucket, imageName, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+	return nil
+}
+
+// ListImages lists the stored images and renditions. Resized copies are not listed.
+func (r *s3ImagesRepository) ListImages() ([]fs.FileInfo, error) {
+	images := []fs.FileInfo{}
+	for object := range r.client.ListObjects(context.Background(), r.bucket, minio.ListObjectsOptions{}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		if strings.Contains(object.Key, "/") {
+			continue
+		}
+		images = append(images, s3FileInfo{info: object})
+	}
+	return images, nil
+}
+
+// GetPath returns the bucket holding the images.
+func (r *s3ImagesRepository) GetPath() string {
+	return r.bucket
+}
+
+// GetURL returns the URL of an image, or an empty string when there is no image.
+func (r *s3ImagesRepository) GetURL(imageName string) string {
+	if imageName == "" {
+		return ""
+	}
+
+	if r.signedURLExpiry > 0 {
+		signedURL, err := r.GetSignedURL(imageName, r.signedURLExpiry)
+		if err != nil {
+			logging.Log.Errorf("Error GetSignedURL %s", err.Error())
+			return ""
+		}
+		return signedURL
+	}
+
+	if r.baseURL != "" {
+		return r.baseURL + "/" + imageName
+	}
+	return r.client.EndpointURL().String() + "/" + r.bucket + "/" + imageName
+}
+
+// GetSignedURL returns a presigned URL giving read access to an image until it expires.
+func (r *s3ImagesRepository) GetSignedURL(imageName string, expiry time.Duration) (string, error) {
+	signedURL, err := r.client.PresignedGetObject(context.Background(), r.bucket, imageName, expiry, url.Values{})
+	if err != nil {
+		return "", err
+	}
+	return signedURL.String(), nil
+}
+
+// openObject opens an object after checking it exists, since minio opens objects lazily.
+func (r *s3ImagesRepository) openObject(name string) (ImageFile, error) {
+	ctx := context.Background()
+	info, err := r.client.StatObject(ctx, r.bucket, name, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+		}
+		return nil, err
+	}
+
+	object, err := r.client.GetObject(ctx, r.bucket, name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &s3ImageFile{Object: object, info: info}, nil
+}
+
+// s3ImageFile is an object of the bucket opened for reading.
+type s3ImageFile struct {
+	*minio.Object
+	info minio.ObjectInfo
+}
+
+func (f *s3ImageFile) Stat() (fs.FileInfo, error) {
+	return s3FileInfo{info: f.info}, nil
+}
+
+// s3FileInfo describes an object of the bucket as a file.
+type s3FileInfo struct {
+	info minio.ObjectInfo
+}
+
+func (i s3FileInfo) Name() string       { return path.Base(i.info.Key) }
+func (i s3FileInfo) Size() int64        { return i.info.Size }
+func (i s3FileInfo) Mode() fs.FileMode  { return 0444 }
+func (i s3FileInfo) ModTime() time.Time { return i.info.LastModified }
+func (i s3FileInfo) IsDir() bool        { return false }
+func (i s3FileInfo) Sys() interface{}   { return nil }
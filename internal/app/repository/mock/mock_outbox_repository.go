@@ -0,0 +1,144 @@
+package mock
+
+import (
+	"context"
+	"maqhaa/product_service/internal/app/entity"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MockOutboxRepository keeps the events of the outbox in memory.
+type MockOutboxRepository struct {
+	mutex  sync.Mutex
+	events []entity.OutboxEvent
+	lastID uint
+}
+
+func NewMockOutboxRepository() *MockOutboxRepository {
+	return &MockOutboxRepository{}
+}
+
+// AddOutboxEvent records an event, with the next ID unless it has one, as a committed transaction would.
+func (m *MockOutboxRepository) AddOutboxEvent(event entity.OutboxEvent) entity.OutboxEvent {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if event.ID == 0 {
+		event.ID = m.lastID + 1
+	}
+	if event.ID > m.lastID {
+		m.lastID = event.ID
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	m.events = append(m.events, event)
+	sort.Slice(m.events, func(i, j int) bool { return m.events[i].ID < m.events[j].ID })
+	return event
+}
+
+// ReserveOutboxEventID gives the next ID to an event not committed yet, to be added later with that ID.
+func (m *MockOutboxRepository) ReserveOutboxEventID() uint {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.lastID++
+	return m.lastID
+}
+
+// RemoveOutboxEvents removes the events up to an ID, as the purge of published events would.
+func (m *MockOutboxRepository) RemoveOutboxEvents(untilID uint) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	kept := []entity.OutboxEvent{}
+	for _, event := range m.events {
+		if event.ID > untilID {
+			kept = append(kept, event)
+		}
+	}
+	m.events = kept
+}
+
+func (m *MockOutboxRepository) LockUnpublishedOutboxEvents(ctx context.Context, limit int) ([]entity.OutboxEvent, error) {
+	return m.find(func(event entity.OutboxEvent) bool { return event.PublishedAt == nil }, limit), nil
+}
+
+func (m *MockOutboxRepository) MarkOutboxEventsPublished(ctx context.Context, IDs []uint, publishedAt time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i := range m.events {
+		for _, ID := range IDs {
+			if m.events[i].ID == ID {
+				m.events[i].PublishedAt = &publishedAt
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MockOutboxRepository) DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	kept := []entity.OutboxEvent{}
+	for _, event := range m.events {
+		if event.PublishedAt == nil || !event.PublishedAt.Before(before) {
+			kept = append(kept, event)
+		}
+	}
+	deleted := int64(len(m.events) - len(kept))
+	m.events = kept
+	return deleted, nil
+}
+
+func (m *MockOutboxRepository) GetOutboxEventsAfter(ctx context.Context, afterID uint, limit int) ([]entity.OutboxEvent, error) {
+	return m.find(func(event entity.OutboxEvent) bool { return event.ID > afterID }, limit), nil
+}
+
+func (m *MockOutboxRepository) GetOutboxEventsByIDs(ctx context.Context, IDs []uint) ([]entity.OutboxEvent, error) {
+	return m.find(func(event entity.OutboxEvent) bool {
+		for _, ID := range IDs {
+			if event.ID == ID {
+				return true
+			}
+		}
+		return false
+	}, -1), nil
+}
+
+func (m *MockOutboxRepository) GetClientOutboxEvents(ctx context.Context, clientID uint, afterID uint, untilID uint, limit int) ([]entity.OutboxEvent, error) {
+	return m.find(func(event entity.OutboxEvent) bool {
+		return event.ClientID == clientID && event.ID > afterID && event.ID <= untilID
+	}, limit), nil
+}
+
+func (m *MockOutboxRepository) GetOutboxEventIDRange(ctx context.Context) (uint, uint, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.events) == 0 {
+		return 0, 0, nil
+	}
+	return m.events[0].ID, m.events[len(m.events)-1].ID, nil
+}
+
+// find returns up to limit events matching, or all of them when limit is negative, in order of their IDs.
+func (m *MockOutboxRepository) find(matches func(event entity.OutboxEvent) bool, limit int) []entity.OutboxEvent {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	events := []entity.OutboxEvent{}
+	for _, event := range m.events {
+		if len(events) == limit {
+			break
+		}
+		if matches(event) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
@@ -0,0 +1,223 @@
+// internal/repository/outbox_repo.go
+
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/entity"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxRepository handles database interactions related to the outbox of domain events.
+// The events themselves are recorded by the ProductRepository and the TranslationRepository, in the
+// transaction of each write.
+type OutboxRepository interface {
+	// LockUnpublishedOutboxEvents fetches up to limit events not yet published, oldest first, and locks them
+	// until the end of the transaction. Relays running at the same time wait for each other, so the events
+	// are published in order.
+	LockUnpublishedOutboxEvents(ctx context.Context, limit int) ([]entity.OutboxEvent, error)
+	MarkOutboxEventsPublished(ctx context.Context, IDs []uint, publishedAt time.Time) error
+	// DeletePublishedOutboxEvents removes the events published before a time and returns how many were removed.
+	DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int64, error)
+	// GetOutboxEventsAfter fetches up to limit events with an ID greater than afterID, in order of their IDs.
+	GetOutboxEventsAfter(ctx context.Context, afterID uint, limit int) ([]entity.OutboxEvent, error)
+	GetOutboxEventsByIDs(ctx context.Context, IDs []uint) ([]entity.OutboxEvent, error)
+	// GetClientOutboxEvents fetches up to limit events of a client with an ID greater than afterID and up to
+	// untilID, in order of their IDs.
+	GetClientOutboxEvents(ctx context.Context, clientID uint, afterID uint, untilID uint, limit int) ([]entity.OutboxEvent, error)
+	// GetOutboxEventIDRange returns the lowest and the highest ID of the events kept, or zeros when there are none.
+	GetOutboxEventIDRange(ctx context.Context) (uint, uint, error)
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a new OutboxRepository instance.
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+func (r *outboxRepository) LockUnpublishedOutboxEvents(ctx context.Context, limit int) ([]entity.OutboxEvent, error) {
+	var events []entity.OutboxEvent
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("published_at IS NULL").
+		Order("id asc").
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error LockUnpublishedOutboxEvents %s", err.Error())
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *outboxRepository) MarkOutboxEventsPublished(ctx context.Context, IDs []uint, publishedAt time.Time) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if len(IDs) == 0 {
+		return nil
+	}
+
+	result := r.db.Model(&entity.OutboxEvent{}).Where("id IN ?", IDs).Update("published_at", publishedAt)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error MarkOutboxEventsPublished %s", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
+func (r *outboxRepository) DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Where("published_at < ?", before).Delete(&entity.OutboxEvent{})
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error DeletePublishedOutboxEvents %s", result.Error.Error())
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *outboxRepository) GetOutboxEventsAfter(ctx context.Context, afterID uint, limit int) ([]entity.OutboxEvent, error) {
+	var events []entity.OutboxEvent
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("id > ?", afterID).Order("id asc").Limit(limit).Find(&events).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetOutboxEventsAfter %s", err.Error())
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *outboxRepository) GetOutboxEventsByIDs(ctx context.Context, IDs []uint) ([]entity.OutboxEvent, error) {
+	var events []entity.OutboxEvent
+	if len(IDs) == 0 {
+		return events, nil
+	}
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("id IN ?", IDs).Order("id asc").Find(&events).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetOutboxEventsByIDs %s", err.Error())
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *outboxRepository) GetClientOutboxEvents(ctx context.Context, clientID uint, afterID uint, untilID uint, limit int) ([]entity.OutboxEvent, error) {
+	var events []entity.OutboxEvent
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.
+		Where("client_id = ? AND id > ? AND id <= ?", clientID, afterID, untilID).
+		Order("id asc").
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetClientOutboxEvents %s", err.Error())
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *outboxRepository) GetOutboxEventIDRange(ctx context.Context) (uint, uint, error) {
+	var IDRange struct {
+		FirstID uint
+		LastID  uint
+	}
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Model(&entity.OutboxEvent{}).
+		Select("COALESCE(MIN(id), 0) AS first_id, COALESCE(MAX(id), 0) AS last_id").
+		Scan(&IDRange).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetOutboxEventIDRange %s", err.Error())
+		return 0, 0, err
+	}
+	return IDRange.FirstID, IDRange.LastID, nil
+}
+
+// productEventPayload is the payload of the domain events of a product, without its images and tags.
+type productEventPayload struct {
+	ID          uint      `json:"id"`
+	ClientID    uint      `json:"clientId"`
+	CategoryID  uint      `json:"categoryId"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Image       string    `json:"image"`
+	Price       float64   `json:"price"`
+	IsActive    bool      `json:"isActive"`
+	Version     uint      `json:"version"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// categoryEventPayload is the payload of the domain events of a category, without its products.
+type categoryEventPayload struct {
+	ID        uint      `json:"id"`
+	ClientID  uint      `json:"clientId"`
+	Name      string    `json:"name"`
+	IsActive  bool      `json:"isActive"`
+	Version   uint      `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// recordProductEvent adds a domain event of a product, as written by db, to the outbox, with the type of
+// the menu event of the change.
+func recordProductEvent(db *gorm.DB, eventType string, menuEventType string, productID uint) error {
+	var product entity.Product
+	if err := db.Where("id = ?", productID).First(&product).Error; err != nil {
+		return err
+	}
+	var category entity.ProductCategory
+	if err := db.Select("id", "client_id").Where("id = ?", product.CategoryID).First(&category).Error; err != nil {
+		return err
+	}
+
+	return recordOutboxEvent(db, eventType, menuEventType, entity.AggregateProduct, product.ID, category.ClientID, productEventPayload{
+		ID:          product.ID,
+		ClientID:    category.ClientID,
+		CategoryID:  product.CategoryID,
+		Name:        product.Name,
+		Description: product.Description,
+		Image:       product.Image,
+		Price:       product.Price,
+		IsActive:    product.IsActive,
+		Version:     product.Version,
+		CreatedAt:   product.CreatedAt,
+	})
+}
+
+// recordCategoryEvent adds a CategoryChanged event of a category, as written by db, to the outbox, with
+// the type of the menu event of the change.
+func recordCategoryEvent(db *gorm.DB, menuEventType string, categoryID uint) error {
+	var category entity.ProductCategory
+	if err := db.Where("id = ?", categoryID).First(&category).Error; err != nil {
+		return err
+	}
+
+	return recordOutboxEvent(db, entity.EventCategoryChanged, menuEventType, entity.AggregateCategory, category.ID, category.ClientID, categoryEventPayload{
+		ID:        category.ID,
+		ClientID:  category.ClientID,
+		Name:      category.Name,
+		IsActive:  category.IsActive,
+		Version:   category.Version,
+		CreatedAt: category.CreatedAt,
+	})
+}
+
+func recordOutboxEvent(db *gorm.DB, eventType string, menuEventType string, aggregateType string, aggregateID uint, clientID uint, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return db.Create(&entity.OutboxEvent{
+		Type:          eventType,
+		MenuEventType: menuEventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		ClientID:      clientID,
+		Payload:       string(data),
+	}).Error
+}
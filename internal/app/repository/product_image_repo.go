@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/entity"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProductImageRepository handles database interactions related to the image gallery of products.
+type ProductImageRepository interface {
+	GetProductImages(ctx context.Context, productID uint) ([]entity.ProductImage, error)
+	GetProductImageByID(ctx context.Context, productID uint, imageID uint) (*entity.ProductImage, error)
+	AddProductImage(ctx context.Context, image *entity.ProductImage) error
+	EditProductImage(ctx context.Context, image *entity.ProductImage) error
+	DeleteProductImage(ctx context.Context, imageID uint) error
+	SetPrimaryProductImage(ctx context.Context, productID uint, imageID uint) error
+	ReorderProductImages(ctx context.Context, productID uint, imageIDs []uint) error
+	GetImageRenditions(ctx context.Context, imageNames []string) ([]entity.ImageRendition, error)
+	AddImageRenditions(ctx context.Context, renditions []entity.ImageRendition) error
+	DeleteImageRenditions(ctx context.Context, imageName string) error
+	AcquireImageBlob(ctx context.Context, imageName string, size int64) (bool, error)
+	ReleaseImageBlob(ctx context.Context, imageName string) (int, error)
+	GetReferencedImages(ctx context.Context) ([]string, error)
+	// LockImageReferences locks the blob of a stored image, and of the image a rendition was made of,
+	// until the end of the transaction, and reports whether the database still references the image.
+	LockImageReferences(ctx context.Context, imageName string) (bool, error)
+	SetImagePlaceholder(ctx context.Context, imageName string, blurHash string, dominantColor string) error
+	GetImageBlobs(ctx context.Context, imageNames []string) ([]entity.ImageBlob, error)
+}
+
+type productImageRepository struct {
+	db *gorm.DB
+}
+
+// NewProductImageRepository creates a new ProductImageRepository instance.
+func NewProductImageRepository(db *gorm.DB) ProductImageRepository {
+	return &productImageRepository{
+		db: db,
+	}
+}
+
+func (r *productImageRepository) GetProductImages(ctx context.Context, productID uint) ([]entity.ProductImage, error) {
+	var images []entity.ProductImage
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("product_id = ?", productID).Order("position asc, id asc").Find(&images).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": requestID}).Errorf("Error GetProductImages %s", err.Error())
+		return nil, err
+	}
+	return images, nil
+}
+
+func (r *productImageRepository) GetProductImageByID(ctx context.Context, productID uint, imageID uint) (*entity.ProductImage, error) {
+	var image entity.ProductImage
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("id = ? AND product_id = ?", imageID, productID).First(&image).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": requestID}).Errorf("Error GetProductImageByID %s", err.Error())
+		return nil, err
+	}
+	return &image, nil
+}
+
+func (r *productImageRepository) AddProductImage(ctx context.Context, image *entity.ProductImage) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Create(image).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error AddProductImage %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *productImageRepository) EditProductImage(ctx context.Context, image *entity.ProductImage) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Save(image).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error EditProductImage %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *productImageRepository) DeleteProductImage(ctx context.Context, imageID uint) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Delete(&entity.ProductImage{}, imageID).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error DeleteProductImage %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// SetPrimaryProductImage flags one image as primary and clears the flag on the other images of the product.
+func (r *productImageRepository) SetPrimaryProductImage(ctx context.Context, productID uint, imageID uint) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.ProductImage{}).Where("product_id = ?", productID).Update("is_primary", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.ProductImage{}).Where("id = ? AND product_id = ?", imageID, productID).Update("is_primary", true).Error
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error SetPrimaryProductImage %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// ReorderProductImages sets the position of every image to its index in imageIDs.
+func (r *productImageRepository) ReorderProductImages(ctx context.Context, productID uint, imageIDs []uint) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for position, imageID := range imageIDs {
+			if err := tx.Model(&entity.ProductImage{}).Where("id = ? AND product_id = ?", imageID, productID).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ReorderProductImages %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// GetImageRenditions returns the renditions of the images, ordered by width.
+func (r *productImageRepository) GetImageRenditions(ctx context.Context, imageNames []string) ([]entity.ImageRendition, error) {
+	var renditions []entity.ImageRendition
+	if len(imageNames) == 0 {
+		return renditions, nil
+	}
+
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("image_name IN ?", imageNames).Order("width asc, id asc").Find(&renditions).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": requestID}).Errorf("Error GetImageRenditions %s", err.Error())
+		return nil, err
+	}
+	return renditions, nil
+}
+
+func (r *productImageRepository) AddImageRenditions(ctx context.Context, renditions []entity.ImageRendition) error {
+	if len(renditions) == 0 {
+		return nil
+	}
+
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Create(&renditions).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error AddImageRenditions %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *productImageRepository) DeleteImageRenditions(ctx context.Context, imageName string) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("image_name = ?", imageName).Delete(&entity.ImageRendition{}).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error DeleteImageRenditions %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// AcquireImageBlob adds a reference to a stored image and reports whether it is the first one.
+func (r *productImageRepository) AcquireImageBlob(ctx context.Context, imageName string, size int64) (bool, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	var blob entity.ImageBlob
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{"ref_count": gorm.Expr("ref_count + 1")}),
+		}).Create(&entity.ImageBlob{Name: imageName, RefCount: 1, Size: size}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("name = ?", imageName).First(&blob).Error
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error AcquireImageBlob %s", err.Error())
+		return false, err
+	}
+	return blob.RefCount == 1, nil
+}
+
+// ReleaseImageBlob removes a reference to a stored image and returns the number of references left.
+// Images stored before reference counting have no blob and are reported as unreferenced.
+func (r *productImageRepository) ReleaseImageBlob(ctx context.Context, imageName string) (int, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	remaining := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var blob entity.ImageBlob
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", imageName).First(&blob).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if blob.RefCount <= 1 {
+			return tx.Delete(&blob).Error
+		}
+		remaining = blob.RefCount - 1
+		return tx.Model(&blob).Update("ref_count", remaining).Error
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ReleaseImageBlob %s", err.Error())
+		return 0, err
+	}
+	return remaining, nil
+}
+
+// SetImagePlaceholder stores the placeholders of a stored image.
+func (r *productImageRepository) SetImagePlaceholder(ctx context.Context, imageName string, blurHash string, dominantColor string) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	err := r.db.Model(&entity.ImageBlob{}).Where("name = ?", imageName).Updates(map[string]interface{}{
+		"blur_hash":      blurHash,
+		"dominant_color": dominantColor,
+	}).Error
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error SetImagePlaceholder %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// GetImageBlobs returns the stored images with the given names.
+func (r *productImageRepository) GetImageBlobs(ctx context.Context, imageNames []string) ([]entity.ImageBlob, error) {
+	var blobs []entity.ImageBlob
+	if len(imageNames) == 0 {
+		return blobs, nil
+	}
+
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("name IN ?", imageNames).Find(&blobs).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetImageBlobs %s", err.Error())
+		return nil, err
+	}
+	return blobs, nil
+}
+
+// GetReferencedImages returns the names of every image and rendition referenced by the database.
+func (r *productImageRepository) GetReferencedImages(ctx context.Context) ([]string, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	var names []string
+	err := r.db.Raw(`SELECT image FROM product WHERE image <> ''
+		UNION SELECT file_name FROM product_image
+		UNION SELECT file_name FROM uploaded_image
+		UNION SELECT name FROM image_blob
+		UNION SELECT image_name FROM image_rendition
+		UNION SELECT file_name FROM image_rendition`).Scan(&names).Error
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetReferencedImages %s", err.Error())
+		return nil, err
+	}
+	return names, nil
+}
+
+func (r *productImageRepository) LockImageReferences(ctx context.Context, imageName string) (bool, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	// locking the rows, or the gap where they would be, holds off the uploads acquiring them
+	query := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", imageName)
+	if source := renditionSource(imageName); source != "" {
+		query = query.Or("name LIKE ?", source+".%")
+	}
+	var blobs []entity.ImageBlob
+	if err := query.Find(&blobs).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error LockImageReferences %s", err.Error())
+		return false, err
+	}
+	if len(blobs) > 0 {
+		return true, nil
+	}
+
+	var count int64
+	err := r.db.Raw(`SELECT COUNT(*) FROM (SELECT image AS name FROM product WHERE image = ?
+		UNION ALL SELECT file_name FROM product_image WHERE file_name = ?
+		UNION ALL SELECT file_name FROM uploaded_image WHERE file_name = ?
+		UNION ALL SELECT image_name FROM image_rendition WHERE image_name = ? OR file_name = ?) refs`,
+		imageName, imageName, imageName, imageName, imageName).Scan(&count).Error
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error LockImageReferences %s", err.Error())
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// renditionSource returns the name, without extension and escaped for LIKE, of the image a rendition
+// such as 3f2a...-thumbnail.webp was made of, or an empty string for names without a rendition suffix.
+func renditionSource(imageName string) string {
+	base := strings.TrimSuffix(imageName, filepath.Ext(imageName))
+	i := strings.LastIndex(base, "-")
+	if i <= 0 {
+		return ""
+	}
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(base[:i])
+}
@@ -4,25 +4,41 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"maqhaa/library/logging"
 	"maqhaa/library/middleware"
 	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrVersionConflict is returned when a product or category was changed since the version being written was read.
+var ErrVersionConflict = errors.New("version conflict")
+
 // ProductRepository handles database interactions related to products.
+// Edits and deactivations only apply to the version given and increment it.
+// Every write of a product or category records its domain event in the outbox, in the same transaction.
 type ProductRepository interface {
 	GetProductGroupsByCategory(ctx context.Context, token string) ([]entity.ProductCategory, error)
 	AddProductCategory(ctx context.Context, category *entity.ProductCategory) error
 	EditProductCategory(ctx context.Context, category *entity.ProductCategory) error
 	GetProductByID(ctx context.Context, productID uint, token string) (*entity.Product, error)
+	GetProductsByIDs(ctx context.Context, productIDs []uint, token string) ([]entity.Product, error)
 	AddProduct(ctx context.Context, product *entity.Product) (*entity.Product, error)
 	EditProduct(ctx context.Context, product *entity.Product) error
 	GetProductCategoryByID(ctx context.Context, productCategoryID uint) (*entity.ProductCategory, error)
-	DeactivateProductCategory(ctx context.Context, ID uint) error
-	DeactivateProduct(ctx context.Context, ID uint) error
+	DeactivateProductCategory(ctx context.Context, ID uint, version uint) error
+	DeactivateProduct(ctx context.Context, ID uint, version uint) error
+	GetClientByToken(ctx context.Context, token string) (*entity.Client, error)
+	SetProductImage(ctx context.Context, productID uint, version uint, image string) error
+	GetClientCatalogue(ctx context.Context, clientID uint) ([]entity.ProductCategory, error)
+	LockClientCatalogue(ctx context.Context, clientID uint) ([]entity.ProductCategory, error)
+	LockClientProducts(ctx context.Context, clientID uint, productIDs []uint, categoryIDs []uint) ([]entity.Product, error)
+	AddProductTags(ctx context.Context, productID uint, tags []string) error
+	RemoveProductTags(ctx context.Context, productID uint, tags []string) error
 }
 
 // Implement the interface in the ProductRepository struct
@@ -49,24 +65,65 @@ func (r *productRepository) GetProductCategoryByID(ctx context.Context, productC
 
 func (r *productRepository) AddProduct(ctx context.Context, product *entity.Product) (*entity.Product, error) {
 	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
-	if err := r.db.Create(product).Error; err != nil {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		return recordProductEvent(tx, entity.EventProductCreated, model.MenuProductCreated, product.ID)
+	})
+	if err != nil {
 		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error create category  %s", err.Error())
 		return nil, err
 	}
 	return product, nil
 }
 
+// EditProduct saves the fields of a product at product.Version and increments the version.
+// Deactivating an active product records a ProductDeactivated event, any other edit a ProductUpdated event.
 func (r *productRepository) EditProduct(ctx context.Context, product *entity.Product) error {
-	if err := r.db.Save(product).Error; err != nil {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	updates := map[string]interface{}{
+		"CategoryID":  product.CategoryID,
+		"Name":        product.Name,
+		"Description": product.Description,
+		"Image":       product.Image,
+		"Price":       product.Price,
+		"IsActive":    product.IsActive,
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var previous entity.Product
+		if err := tx.Select("id", "is_active").Where("id = ?", product.ID).First(&previous).Error; err != nil {
+			return err
+		}
+		if err := updateVersion(tx.Model(&entity.Product{}), product.ID, product.Version, updates); err != nil {
+			return err
+		}
+
+		eventType, menuEventType := entity.EventProductUpdated, model.MenuProductUpdated
+		if previous.IsActive && !product.IsActive {
+			eventType = entity.EventProductDeactivated
+		}
+		if previous.IsActive != product.IsActive {
+			menuEventType = model.MenuProductAvailability
+		}
+		return recordProductEvent(tx, eventType, menuEventType, product.ID)
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error EditProduct  %s", err.Error())
 		return err
 	}
+	product.Version++
 	return nil
 }
 
 func (r *productRepository) GetProductByID(ctx context.Context, productID uint, token string) (*entity.Product, error) {
 	var product entity.Product
 	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
-	if err := r.db.Joins("JOIN product_category ON product_category.id = product.category_id").
+	if err := r.db.Preload("Images", orderProductImages).
+		Preload("Tags", orderProductTags).
+		Joins("JOIN product_category ON product_category.id = product.category_id").
 		Joins("JOIN client ON client.id = product_category.client_id").
 		Where("product.id = ? AND client.token = ?", productID, token).
 		First(&product).Error; err != nil {
@@ -77,6 +134,25 @@ func (r *productRepository) GetProductByID(ctx context.Context, productID uint,
 	return &product, nil
 }
 
+// GetProductsByIDs fetches the products with the given IDs of the client of the token, active or not.
+// IDs of other clients or of no product are left out.
+func (r *productRepository) GetProductsByIDs(ctx context.Context, productIDs []uint, token string) ([]entity.Product, error) {
+	var products []entity.Product
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Preload("Images", orderProductImages).
+		Preload("Tags", orderProductTags).
+		Joins("JOIN product_category ON product_category.id = product.category_id").
+		Joins("JOIN client ON client.id = product_category.client_id").
+		Where("product.id IN ? AND client.token = ?", productIDs, token).
+		Order("product.id asc").
+		Find(&products).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": requestID}).Errorf("Error GetProductsByIDs  %s", err.Error())
+		return nil, err
+	}
+
+	return products, nil
+}
+
 // GetProductGroupsByCategoryAndClientID fetches product categories with associated products,
 // grouped by category and filtered by client ID.
 func (r *productRepository) GetProductGroupsByCategory(ctx context.Context, token string) ([]entity.ProductCategory, error) {
@@ -85,6 +161,8 @@ func (r *productRepository) GetProductGroupsByCategory(ctx context.Context, toke
 
 	if err := r.db.
 		Preload("Products").
+		Preload("Products.Images", orderProductImages).
+		Preload("Products.Tags", orderProductTags).
 		Joins("LEFT JOIN product ON product_category.id = product.category_id").
 		Joins("LEFT JOIN client ON client.id = product_category.client_id").
 		Where("client.token = ?", token).Order("product_category.id asc").
@@ -99,26 +177,42 @@ func (r *productRepository) GetProductGroupsByCategory(ctx context.Context, toke
 
 func (r *productRepository) AddProductCategory(ctx context.Context, category *entity.ProductCategory) error {
 	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
-	result := r.db.Create(category)
-	if result.Error != nil {
-		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error create category  %s", result.Error.Error())
-		return result.Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(category).Error; err != nil {
+			return err
+		}
+		return recordCategoryEvent(tx, model.MenuCategoryCreated, category.ID)
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error create category  %s", err.Error())
+		return err
 	}
 	return nil
 }
 
-// EditProductCategory edits an existing ProductCategory in the database.
+// EditProductCategory edits an existing ProductCategory at category.Version and increments the version.
 func (r *productRepository) EditProductCategory(ctx context.Context, category *entity.ProductCategory) error {
-	result := r.db.Model(category).Updates(category)
 	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
-	if result.Error != nil {
-		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error create category  %s", result.Error.Error())
-		return result.Error
+
+	updates := map[string]interface{}{
+		"Name": category.Name,
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersion(tx.Model(&entity.ProductCategory{}), category.ID, category.Version, updates); err != nil {
+			return err
+		}
+		return recordCategoryEvent(tx, model.MenuCategoryUpdated, category.ID)
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error EditProductCategory  %s", err.Error())
+		return err
 	}
+	category.Version++
 	return nil
 }
 
-func (r *productRepository) DeactivateProductCategory(ctx context.Context, ID uint) error {
+func (r *productRepository) DeactivateProductCategory(ctx context.Context, ID uint, version uint) error {
 
 	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
 
@@ -127,15 +221,20 @@ func (r *productRepository) DeactivateProductCategory(ctx context.Context, ID ui
 	}
 
 	// Perform the update operation
-	result := r.db.Model(&entity.ProductCategory{}).Where("id = ?", ID).Updates(updates)
-	if result.Error != nil {
-		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error UpdateUser  %s", result.Error.Error())
-		return result.Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersion(tx.Model(&entity.ProductCategory{}), ID, version, updates); err != nil {
+			return err
+		}
+		return recordCategoryEvent(tx, model.MenuCategoryDeactivated, ID)
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error UpdateUser  %s", err.Error())
+		return err
 	}
 	return nil
 }
 
-func (r *productRepository) DeactivateProduct(ctx context.Context, ID uint) error {
+func (r *productRepository) DeactivateProduct(ctx context.Context, ID uint, version uint) error {
 
 	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
 
@@ -144,10 +243,168 @@ func (r *productRepository) DeactivateProduct(ctx context.Context, ID uint) erro
 	}
 
 	// Perform the update operation
-	result := r.db.Model(&entity.Product{}).Where("id = ?", ID).Updates(updates)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersion(tx.Model(&entity.Product{}), ID, version, updates); err != nil {
+			return err
+		}
+		return recordProductEvent(tx, entity.EventProductDeactivated, model.MenuProductDeactivated, ID)
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error UpdateUser  %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *productRepository) GetClientByToken(ctx context.Context, token string) (*entity.Client, error) {
+	var client entity.Client
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("token = ?", token).First(&client).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": requestID}).Errorf("Error GetClientByToken  %s", err.Error())
+		return nil, err
+	}
+	return &client, nil
+}
+
+// SetProductImage updates the primary image file of a product at version, after a change of its gallery,
+// and increments the version.
+func (r *productRepository) SetProductImage(ctx context.Context, productID uint, version uint, image string) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	updates := map[string]interface{}{
+		"image": image,
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersion(tx.Model(&entity.Product{}), productID, version, updates); err != nil {
+			return err
+		}
+		return recordProductEvent(tx, entity.EventProductUpdated, model.MenuProductUpdated, productID)
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error SetProductImage  %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// GetClientCatalogue fetches all the categories of a client with their products and tags, active or not.
+func (r *productRepository) GetClientCatalogue(ctx context.Context, clientID uint) ([]entity.ProductCategory, error) {
+	var categories []entity.ProductCategory
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	if err := r.db.
+		Preload("Products", func(db *gorm.DB) *gorm.DB {
+			return db.Order("product.id asc")
+		}).
+		Preload("Products.Tags", orderProductTags).
+		Where("client_id = ?", clientID).Order("id asc").
+		Find(&categories).
+		Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": requestID}).Errorf("Error GetClientCatalogue  %s", err.Error())
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// LockClientCatalogue fetches all the categories of a client with their products and tags, as GetClientCatalogue,
+// and locks them until the end of the transaction. The lock on the categories of the client also holds back
+// the categories added to the client by other transactions.
+func (r *productRepository) LockClientCatalogue(ctx context.Context, clientID uint) ([]entity.ProductCategory, error) {
+	var categories []entity.ProductCategory
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	if err := r.db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Products", func(db *gorm.DB) *gorm.DB {
+			return db.Clauses(clause.Locking{Strength: "UPDATE"}).Order("product.id asc")
+		}).
+		Preload("Products.Tags", orderProductTags).
+		Where("client_id = ?", clientID).Order("id asc").
+		Find(&categories).
+		Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": requestID}).Errorf("Error LockClientCatalogue  %s", err.Error())
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// LockClientProducts fetches the products of a client with the given IDs or in the given categories,
+// with their tags, and locks them until the end of the transaction.
+func (r *productRepository) LockClientProducts(ctx context.Context, clientID uint, productIDs []uint, categoryIDs []uint) ([]entity.Product, error) {
+	var products []entity.Product
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	// An empty list of IDs is written as IN (NULL), which matches no product.
+	selection := r.db.Where("product.id IN ?", productIDs).Or("product.category_id IN ?", categoryIDs)
+
+	if err := r.db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Tags", orderProductTags).
+		Joins("JOIN product_category ON product_category.id = product.category_id").
+		Where("product_category.client_id = ?", clientID).
+		Where(selection).
+		Order("product.id asc").
+		Find(&products).
+		Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": requestID}).Errorf("Error LockClientProducts  %s", err.Error())
+		return nil, err
+	}
+	return products, nil
+}
+
+// AddProductTags adds tags to a product, keeping the tags it already has.
+func (r *productRepository) AddProductTags(ctx context.Context, productID uint, tags []string) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if len(tags) == 0 {
+		return nil
+	}
+
+	productTags := make([]entity.ProductTag, 0, len(tags))
+	for _, tag := range tags {
+		productTags = append(productTags, entity.ProductTag{ProductID: productID, Tag: tag})
+	}
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&productTags).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error AddProductTags  %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// RemoveProductTags removes tags from a product.
+func (r *productRepository) RemoveProductTags(ctx context.Context, productID uint, tags []string) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if len(tags) == 0 {
+		return nil
+	}
+
+	if err := r.db.Where("product_id = ? AND tag IN ?", productID, tags).Delete(&entity.ProductTag{}).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error RemoveProductTags  %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// updateVersion applies the updates to the row with the id only when it is still at the version,
+// and increments the version. ErrVersionConflict is returned when the row is at another version.
+func updateVersion(db *gorm.DB, ID uint, version uint, updates map[string]interface{}) error {
+	updates["version"] = gorm.Expr("version + 1")
+	result := db.Where("id = ? AND version = ?", ID, version).Updates(updates)
 	if result.Error != nil {
-		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error UpdateUser  %s", result.Error.Error())
 		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
 	return nil
 }
+
+// orderProductImages sorts preloaded product images by their gallery position.
+func orderProductImages(db *gorm.DB) *gorm.DB {
+	return db.Order("product_image.position asc, product_image.id asc")
+}
+
+// orderProductTags sorts preloaded product tags alphabetically.
+func orderProductTags(db *gorm.DB) *gorm.DB {
+	return db.Order("product_tag.tag asc")
+}
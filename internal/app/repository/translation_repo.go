@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TranslationRepository handles database interactions related to localised product content.
+type TranslationRepository interface {
+	GetProductTranslations(ctx context.Context, productID uint) ([]entity.ProductTranslation, error)
+	GetProductTranslationsByLocales(ctx context.Context, productIDs []uint, locales []string) ([]entity.ProductTranslation, error)
+	SaveProductTranslation(ctx context.Context, translation *entity.ProductTranslation) error
+	DeleteProductTranslation(ctx context.Context, productID uint, locale string) error
+	GetCategoryTranslations(ctx context.Context, categoryID uint) ([]entity.ProductCategoryTranslation, error)
+	GetCategoryTranslationsByLocales(ctx context.Context, categoryIDs []uint, locales []string) ([]entity.ProductCategoryTranslation, error)
+	SaveCategoryTranslation(ctx context.Context, translation *entity.ProductCategoryTranslation) error
+	DeleteCategoryTranslation(ctx context.Context, categoryID uint, locale string) error
+}
+
+type translationRepository struct {
+	db *gorm.DB
+}
+
+// NewTranslationRepository creates a new TranslationRepository instance.
+func NewTranslationRepository(db *gorm.DB) TranslationRepository {
+	return &translationRepository{
+		db: db,
+	}
+}
+
+func (r *translationRepository) GetProductTranslations(ctx context.Context, productID uint) ([]entity.ProductTranslation, error) {
+	var translations []entity.ProductTranslation
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("product_id = ?", productID).Order("locale asc").Find(&translations).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": requestID}).Errorf("Error GetProductTranslations %s", err.Error())
+		return nil, err
+	}
+	return translations, nil
+}
+
+func (r *translationRepository) GetProductTranslationsByLocales(ctx context.Context, productIDs []uint, locales []string) ([]entity.ProductTranslation, error) {
+	var translations []entity.ProductTranslation
+	if len(productIDs) == 0 || len(locales) == 0 {
+		return translations, nil
+	}
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("product_id IN ? AND locale IN ?", productIDs, locales).Find(&translations).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": requestID}).Errorf("Error GetProductTranslationsByLocales %s", err.Error())
+		return nil, err
+	}
+	return translations, nil
+}
+
+// SaveProductTranslation inserts the translation or replaces the existing one for the same product and locale,
+// and records a ProductUpdated event of the product.
+func (r *translationRepository) SaveProductTranslation(ctx context.Context, translation *entity.ProductTranslation) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "product_id"}, {Name: "locale"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "description", "updated_at"}),
+		}).Create(translation).Error; err != nil {
+			return err
+		}
+		return recordProductEvent(tx, entity.EventProductUpdated, model.MenuProductUpdated, translation.ProductID)
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error SaveProductTranslation %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *translationRepository) DeleteProductTranslation(ctx context.Context, productID uint, locale string) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("product_id = ? AND locale = ?", productID, locale).Delete(&entity.ProductTranslation{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return recordProductEvent(tx, entity.EventProductUpdated, model.MenuProductUpdated, productID)
+	})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error DeleteProductTranslation %s", err.Error())
+	}
+	return err
+}
+
+func (r *translationRepository) GetCategoryTranslations(ctx context.Context, categoryID uint) ([]entity.ProductCategoryTranslation, error) {
+	var translations []entity.ProductCategoryTranslation
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("category_id = ?", categoryID).Order("locale asc").Find(&translations).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": requestID}).Errorf("Error GetCategoryTranslations %s", err.Error())
+		return nil, err
+	}
+	return translations, nil
+}
+
+func (r *translationRepository) GetCategoryTranslationsByLocales(ctx context.Context, categoryIDs []uint, locales []string) ([]entity.ProductCategoryTranslation, error) {
+	var translations []entity.ProductCategoryTranslation
+	if len(categoryIDs) == 0 || len(locales) == 0 {
+		return translations, nil
+	}
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("category_id IN ? AND locale IN ?", categoryIDs, locales).Find(&translations).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": requestID}).Errorf("Error GetCategoryTranslationsByLocales %s", err.Error())
+		return nil, err
+	}
+	return translations, nil
+}
+
+// SaveCategoryTranslation inserts the translation or replaces the existing one for the same category and locale,
+// and records a CategoryChanged event of the category.
+func (r *translationRepository) SaveCategoryTranslation(ctx context.Context, translation *entity.ProductCategoryTranslation) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "category_id"}, {Name: "locale"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "updated_at"}),
+		}).Create(translation).Error; err != nil {
+			return err
+		}
+		return recordCategoryEvent(tx, model.MenuCategoryUpdated, translation.CategoryID)
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error SaveCategoryTranslation %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *translationRepository) DeleteCategoryTranslation(ctx context.Context, categoryID uint, locale string) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("category_id = ? AND locale = ?", categoryID, locale).Delete(&entity.ProductCategoryTranslation{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return recordCategoryEvent(tx, model.MenuCategoryUpdated, categoryID)
+	})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error DeleteCategoryTranslation %s", err.Error())
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+// internal/repository/unit_of_work.go
+
+package repository
+
+import (
+	"context"
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Repositories are the database repositories of a unit of work, all writing in the same transaction.
+type Repositories struct {
+	Product       ProductRepository
+	Translation   TranslationRepository
+	ProductImage  ProductImageRepository
+	UploadedImage UploadedImageRepository
+	Outbox        OutboxRepository
+	Webhook       WebhookRepository
+}
+
+// UnitOfWork runs a group of repository calls in one database transaction.
+type UnitOfWork interface {
+	// Do commits the transaction when fn succeeds and rolls it back when fn returns an error.
+	Do(ctx context.Context, fn func(repositories *Repositories) error) error
+}
+
+type unitOfWork struct {
+	db *gorm.DB
+}
+
+// NewUnitOfWork creates a new UnitOfWork instance.
+func NewUnitOfWork(db *gorm.DB) UnitOfWork {
+	return &unitOfWork{
+		db: db,
+	}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(repositories *Repositories) error) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	err := u.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&Repositories{
+			Product:       NewProductRepository(tx),
+			Translation:   NewTranslationRepository(tx),
+			ProductImage:  NewProductImageRepository(tx),
+			UploadedImage: NewUploadedImageRepository(tx),
+			Outbox:        NewOutboxRepository(tx),
+			Webhook:       NewWebhookRepository(tx),
+		})
+	})
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error UnitOfWork %s", err.Error())
+		return err
+	}
+	return nil
+}
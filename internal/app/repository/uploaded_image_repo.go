@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/entity"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// UploadedImageRepository handles database interactions related to uploaded images.
+type UploadedImageRepository interface {
+	AddUploadedImage(ctx context.Context, image *entity.UploadedImage) error
+	GetUploadedImageByID(ctx context.Context, imageID uint, clientID uint) (*entity.UploadedImage, error)
+	// GetExpiredUploadedImages fetches up to limit uploads created before a time, oldest first.
+	GetExpiredUploadedImages(ctx context.Context, before time.Time, limit int) ([]entity.UploadedImage, error)
+	DeleteUploadedImage(ctx context.Context, imageID uint) error
+}
+
+type uploadedImageRepository struct {
+	db *gorm.DB
+}
+
+// NewUploadedImageRepository creates a new UploadedImageRepository instance.
+func NewUploadedImageRepository(db *gorm.DB) UploadedImageRepository {
+	return &uploadedImageRepository{
+		db: db,
+	}
+}
+
+func (r *uploadedImageRepository) AddUploadedImage(ctx context.Context, image *entity.UploadedImage) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Create(image).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error AddUploadedImage %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *uploadedImageRepository) GetUploadedImageByID(ctx context.Context, imageID uint, clientID uint) (*entity.UploadedImage, error) {
+	var image entity.UploadedImage
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("id = ? AND client_id = ?", imageID, clientID).First(&image).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": requestID}).Errorf("Error GetUploadedImageByID %s", err.Error())
+		return nil, err
+	}
+	return &image, nil
+}
+
+func (r *uploadedImageRepository) GetExpiredUploadedImages(ctx context.Context, before time.Time, limit int) ([]entity.UploadedImage, error) {
+	var images []entity.UploadedImage
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("created_at < ?", before).Order("id asc").Limit(limit).Find(&images).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetExpiredUploadedImages %s", err.Error())
+		return nil, err
+	}
+	return images, nil
+}
+
+func (r *uploadedImageRepository) DeleteUploadedImage(ctx context.Context, imageID uint) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Delete(&entity.UploadedImage{}, imageID).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error DeleteUploadedImage %s", err.Error())
+		return err
+	}
+	return nil
+}
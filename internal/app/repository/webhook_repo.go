@@ -0,0 +1,169 @@
+// internal/repository/webhook_repo.go
+
+package repository
+
+import (
+	"context"
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/entity"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookRepository handles database interactions related to webhook subscriptions and their deliveries.
+type WebhookRepository interface {
+	AddWebhookSubscription(ctx context.Context, subscription *entity.WebhookSubscription) error
+	GetWebhookSubscriptions(ctx context.Context, clientID uint) ([]entity.WebhookSubscription, error)
+	GetWebhookSubscriptionByID(ctx context.Context, ID uint) (*entity.WebhookSubscription, error)
+	// DeleteWebhookSubscription removes a subscription of a client, and returns false when the client has none with the ID.
+	DeleteWebhookSubscription(ctx context.Context, clientID uint, ID uint) (bool, error)
+	AddWebhookDeliveries(ctx context.Context, deliveries []entity.WebhookDelivery) error
+	// LockDueWebhookDeliveries fetches up to limit pending deliveries due at now, oldest first, and locks them
+	// until the end of the transaction. Deliveries locked by another transaction are skipped.
+	LockDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]entity.WebhookDelivery, error)
+	// PostponeWebhookDeliveries moves the next attempt of deliveries to a later time.
+	PostponeWebhookDeliveries(ctx context.Context, IDs []uint, nextAttemptAt time.Time) error
+	// SaveWebhookDelivery saves the status and the attempts of a delivery.
+	SaveWebhookDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+	GetWebhookDeliveryByID(ctx context.Context, clientID uint, ID uint) (*entity.WebhookDelivery, error)
+	// GetDeadWebhookDeliveries fetches up to limit dead deliveries of a client, most recent first.
+	GetDeadWebhookDeliveries(ctx context.Context, clientID uint, limit int) ([]entity.WebhookDelivery, error)
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a new WebhookRepository instance.
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{
+		db: db,
+	}
+}
+
+func (r *webhookRepository) AddWebhookSubscription(ctx context.Context, subscription *entity.WebhookSubscription) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Create(subscription).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error AddWebhookSubscription %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *webhookRepository) GetWebhookSubscriptions(ctx context.Context, clientID uint) ([]entity.WebhookSubscription, error) {
+	var subscriptions []entity.WebhookSubscription
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("client_id = ?", clientID).Order("id asc").Find(&subscriptions).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetWebhookSubscriptions %s", err.Error())
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *webhookRepository) GetWebhookSubscriptionByID(ctx context.Context, ID uint) (*entity.WebhookSubscription, error) {
+	var subscription entity.WebhookSubscription
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("id = ?", ID).First(&subscription).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetWebhookSubscriptionByID %s", err.Error())
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *webhookRepository) DeleteWebhookSubscription(ctx context.Context, clientID uint, ID uint) (bool, error) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	result := r.db.Where("client_id = ? AND id = ?", clientID, ID).Delete(&entity.WebhookSubscription{})
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error DeleteWebhookSubscription %s", result.Error.Error())
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *webhookRepository) AddWebhookDeliveries(ctx context.Context, deliveries []entity.WebhookDelivery) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := r.db.Create(&deliveries).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error AddWebhookDeliveries %s", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *webhookRepository) LockDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", entity.WebhookPending, now).
+		Order("next_attempt_at asc, id asc").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error LockDueWebhookDeliveries %s", err.Error())
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepository) PostponeWebhookDeliveries(ctx context.Context, IDs []uint, nextAttemptAt time.Time) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if len(IDs) == 0 {
+		return nil
+	}
+
+	result := r.db.Model(&entity.WebhookDelivery{}).Where("id IN ?", IDs).Update("next_attempt_at", nextAttemptAt)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error PostponeWebhookDeliveries %s", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
+func (r *webhookRepository) SaveWebhookDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	updates := map[string]interface{}{
+		"Status":         delivery.Status,
+		"Attempts":       delivery.Attempts,
+		"NextAttemptAt":  delivery.NextAttemptAt,
+		"LastStatusCode": delivery.LastStatusCode,
+		"LastError":      delivery.LastError,
+		"DeliveredAt":    delivery.DeliveredAt,
+	}
+	result := r.db.Model(&entity.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates)
+	if result.Error != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error SaveWebhookDelivery %s", result.Error.Error())
+		return result.Error
+	}
+	return nil
+}
+
+func (r *webhookRepository) GetWebhookDeliveryByID(ctx context.Context, clientID uint, ID uint) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.Where("client_id = ? AND id = ?", clientID, ID).First(&delivery).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetWebhookDeliveryByID %s", err.Error())
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *webhookRepository) GetDeadWebhookDeliveries(ctx context.Context, clientID uint, limit int) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	if err := r.db.
+		Where("client_id = ? AND status = ?", clientID, entity.WebhookDead).
+		Order("id desc").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetDeadWebhookDeliveries %s", err.Error())
+		return nil, err
+	}
+	return deliveries, nil
+}
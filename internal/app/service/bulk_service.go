@@ -0,0 +1,230 @@
+// internal/service/bulk_service.go
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxTagLength is the longest tag of a product, in bytes.
+const maxTagLength = 50
+
+// bulkChange is a product changed by the operations of a bulk update, with the tags added and removed
+// and the menu event of the change.
+type bulkChange struct {
+	product    *entity.Product
+	addTags    []string
+	removeTags []string
+	event      model.MenuEvent
+}
+
+// BulkUpdateProductsService applies the operations of the request to the selected products of the client
+// of the token, in one transaction. The report has one result per product; when any product fails, the
+// report is returned with an invalid request error and nothing is saved.
+func (s *productServiceImpl) BulkUpdateProductsService(ctx context.Context, request *model.ProductBulkRequest, token string) (*model.ProductBulkReport, AppError) {
+	validate := newValidator()
+	if err := validate.Struct(request); err != nil {
+		return nil, *NewValidationError(err)
+	}
+	if fieldErrors := checkBulkOperations(request.Operations); len(fieldErrors) > 0 {
+		return nil, *newFieldErrors(fieldErrors)
+	}
+
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return nil, *NewInvalidTokenError()
+	}
+	request.ClientID = uint(user.ClientId)
+
+	for _, operation := range request.Operations {
+		if operation.Type != model.BulkMoveCategory {
+			continue
+		}
+		if _, appError := s.getClientCategory(ctx, operation.CategoryID, user); appError.Code != SuccessError {
+			return nil, appError
+		}
+	}
+
+	report := &model.ProductBulkReport{Results: []model.ProductBulkResult{}}
+	appError := s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		products, err := tx.productRepository.LockClientProducts(ctx, request.ClientID, request.ProductIDs, request.CategoryIDs)
+		if err != nil {
+			return *NewQueryDBError()
+		}
+
+		changes := []bulkChange{}
+		found := map[uint]bool{}
+		for i := range products {
+			found[products[i].ID] = true
+			change, fieldErrors := applyBulkOperations(products[i], request.Operations)
+
+			result := model.ProductBulkResult{ProductID: products[i].ID, Product: change.product}
+			switch {
+			case len(fieldErrors) > 0:
+				result.Status = model.BulkStatusFailed
+				result.Errors = fieldErrors
+				result.Product = nil
+				report.Failed++
+			case change.changesProduct(&products[i]):
+				result.Status = model.BulkStatusUpdated
+				report.Updated++
+				change.event = newProductChangeEvent(request.ClientID, &products[i], change.product)
+				changes = append(changes, change)
+			default:
+				result.Status = model.BulkStatusUnchanged
+				report.Unchanged++
+			}
+			report.Results = append(report.Results, result)
+		}
+
+		for _, productID := range request.ProductIDs {
+			if found[productID] {
+				continue
+			}
+			found[productID] = true
+			notFound := NewProductNotFoundError()
+			report.Results = append(report.Results, model.ProductBulkResult{
+				ProductID: productID,
+				Status:    model.BulkStatusFailed,
+				Message:   notFound.Message,
+			})
+			report.Failed++
+		}
+
+		if report.Failed > 0 {
+			return *NewInvalidRequestError(fmt.Sprintf("(%d failed products)", report.Failed))
+		}
+
+		for _, change := range changes {
+			if err := tx.productRepository.EditProduct(ctx, change.product); err != nil {
+				return newVersionedUpdateError(err)
+			}
+			if err := tx.productRepository.AddProductTags(ctx, change.product.ID, change.addTags); err != nil {
+				return *NewUpdateQueryDBError()
+			}
+			if err := tx.productRepository.RemoveProductTags(ctx, change.product.ID, change.removeTags); err != nil {
+				return *NewUpdateQueryDBError()
+			}
+			tx.publishMenuEvents(change.event)
+		}
+		return *NewSuccessError()
+	})
+	if appError.Code != SuccessError {
+		if report.Failed == 0 {
+			return nil, appError
+		}
+		return report, appError
+	}
+
+	report.Applied = true
+	return report, *NewSuccessError()
+}
+
+// checkBulkOperations validates the fields each type of operation needs and normalises the tags.
+func checkBulkOperations(operations []model.ProductBulkOperation) []model.FieldError {
+	fieldErrors := []model.FieldError{}
+	for i := range operations {
+		operation := &operations[i]
+		field := func(name string) string {
+			return fmt.Sprintf("operations[%d].%s", i, name)
+		}
+
+		if err := newValidator().Struct(operation); err != nil {
+			for _, fieldError := range NewValidationError(err).Errors {
+				fieldErrors = append(fieldErrors, newFieldError(field(fieldError.Field), fieldError.Rule, fieldError.Param))
+			}
+			continue
+		}
+
+		switch operation.Type {
+		case model.BulkSetPrice:
+			if operation.Price <= 0 {
+				fieldErrors = append(fieldErrors, newFieldError(field("price"), "gt", "0"))
+			}
+		case model.BulkAdjustPrice:
+			if operation.Percent == 0 {
+				fieldErrors = append(fieldErrors, newFieldError(field("percent"), "required", ""))
+			} else if operation.Percent <= -100 {
+				fieldErrors = append(fieldErrors, newFieldError(field("percent"), "gt", "-100"))
+			}
+		case model.BulkMoveCategory:
+			if operation.CategoryID == 0 {
+				fieldErrors = append(fieldErrors, newFieldError(field("category_id"), "required", ""))
+			}
+		case model.BulkAddTag, model.BulkRemoveTag:
+			operation.Tag = strings.ToLower(strings.TrimSpace(operation.Tag))
+			if operation.Tag == "" {
+				fieldErrors = append(fieldErrors, newFieldError(field("tag"), "required", ""))
+			} else if len(operation.Tag) > maxTagLength {
+				fieldErrors = append(fieldErrors, newFieldError(field("tag"), "max", strconv.Itoa(maxTagLength)))
+			}
+		}
+	}
+	return fieldErrors
+}
+
+// applyBulkOperations applies the operations, in order, to a copy of the product.
+func applyBulkOperations(product entity.Product, operations []model.ProductBulkOperation) (bulkChange, []model.FieldError) {
+	tags := map[string]bool{}
+	for _, tag := range product.Tags {
+		tags[tag.Tag] = true
+	}
+
+	var fieldErrors []model.FieldError
+	for i, operation := range operations {
+		switch operation.Type {
+		case model.BulkSetPrice:
+			product.Price = operation.Price
+		case model.BulkAdjustPrice:
+			product.Price = math.Round(product.Price*(100+operation.Percent)) / 100
+			if product.Price <= 0 {
+				fieldErrors = append(fieldErrors, newFieldError(fmt.Sprintf("operations[%d].percent", i), "gt", "0"))
+			}
+		case model.BulkMoveCategory:
+			product.CategoryID = operation.CategoryID
+		case model.BulkActivate:
+			product.IsActive = true
+		case model.BulkDeactivate:
+			product.IsActive = false
+		case model.BulkAddTag:
+			tags[operation.Tag] = true
+		case model.BulkRemoveTag:
+			delete(tags, operation.Tag)
+		}
+	}
+
+	change := bulkChange{product: &product}
+	current := map[string]bool{}
+	for _, tag := range product.Tags {
+		current[tag.Tag] = true
+		if !tags[tag.Tag] {
+			change.removeTags = append(change.removeTags, tag.Tag)
+		}
+	}
+
+	product.Tags = make([]entity.ProductTag, 0, len(tags))
+	for tag := range tags {
+		product.Tags = append(product.Tags, entity.ProductTag{ProductID: product.ID, Tag: tag})
+		if !current[tag] {
+			change.addTags = append(change.addTags, tag)
+		}
+	}
+	sort.Slice(product.Tags, func(i, j int) bool { return product.Tags[i].Tag < product.Tags[j].Tag })
+	sort.Strings(change.addTags)
+	return change, fieldErrors
+}
+
+// changesProduct reports whether the operations change the product.
+func (c *bulkChange) changesProduct(previous *entity.Product) bool {
+	return len(c.addTags) > 0 || len(c.removeTags) > 0 ||
+		c.product.Price != previous.Price ||
+		c.product.CategoryID != previous.CategoryID ||
+		c.product.IsActive != previous.IsActive
+}
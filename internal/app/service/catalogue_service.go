@@ -0,0 +1,461 @@
+// internal/service/catalogue_service.go
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/safehttp"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// catalogueImageTimeout bounds the download of the image of a catalogue row.
+	catalogueImageTimeout = 30 * time.Second
+	// catalogueDownloadTimeout bounds the downloads of all the images of a catalogue; the images not
+	// downloaded in time fail their rows.
+	catalogueDownloadTimeout = 2 * time.Minute
+	// catalogueImageWorkers is the number of images of a catalogue downloaded at the same time.
+	catalogueImageWorkers = 8
+)
+
+// catalogueImageClient downloads the images of catalogues, from public addresses only.
+var catalogueImageClient = safehttp.NewClient(catalogueImageTimeout)
+
+// catalogueDownload is the image downloaded from a URL of a catalogue, or the error downloading it.
+type catalogueDownload struct {
+	data []byte
+	err  error
+}
+
+// catalogueChange is a valid row of a catalogue import with the product it creates or updates.
+type catalogueChange struct {
+	category string
+	// product is the existing product, nil when the row creates one.
+	product  *entity.Product
+	imported entity.Product
+	// imageData is a new image to store, imageName a stored image to reference; both are empty
+	// when the image of the product is kept.
+	imageData []byte
+	imageName string
+}
+
+// ImportCatalogueService imports a catalogue into the categories and products of the client of the token.
+func (s *productServiceImpl) ImportCatalogueService(ctx context.Context, request *model.CatalogueImportRequest, token string) (*model.CatalogueImportReport, AppError) {
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return nil, *NewInvalidTokenError()
+	}
+
+	request.ClientID = uint(user.ClientId)
+	return s.ImportClientCatalogue(ctx, request)
+}
+
+// ImportClientCatalogue validates every row of a catalogue and reports the products it creates and updates.
+// Products are matched by category and name, ignoring case, and missing categories are created.
+// The changes are saved in one transaction, unless the import is a dry run or a row is invalid. The rows
+// are then matched against the catalogue locked in that transaction, so concurrent imports of a client
+// do not create the same categories and products twice.
+func (s *productServiceImpl) ImportClientCatalogue(ctx context.Context, request *model.CatalogueImportRequest) (*model.CatalogueImportReport, AppError) {
+	downloads := s.downloadCatalogueImages(ctx, request.Rows)
+
+	if request.DryRun {
+		categories, err := s.productRepository.GetClientCatalogue(ctx, request.ClientID)
+		if err != nil {
+			return nil, *NewQueryDBError()
+		}
+
+		report, _, _ := s.matchCatalogue(ctx, request, categories, downloads)
+		if report.Failed > 0 {
+			return report, *NewInvalidRequestError(fmt.Sprintf("(%d invalid rows)", report.Failed))
+		}
+		return report, *NewSuccessError()
+	}
+
+	var report *model.CatalogueImportReport
+	appError := s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		categories, err := tx.productRepository.LockClientCatalogue(ctx, request.ClientID)
+		if err != nil {
+			return *NewQueryDBError()
+		}
+
+		var changes []catalogueChange
+		var categoryIDs map[string]uint
+		report, changes, categoryIDs = tx.matchCatalogue(ctx, request, categories, downloads)
+		if report.Failed > 0 {
+			return *NewInvalidRequestError(fmt.Sprintf("(%d invalid rows)", report.Failed))
+		}
+
+		for _, name := range report.CreatedCategories {
+			category := &entity.ProductCategory{ClientID: request.ClientID, Name: name, IsActive: true}
+			if err := tx.productRepository.AddProductCategory(ctx, category); err != nil {
+				return *NewUpdateQueryDBError()
+			}
+			categoryIDs[catalogueKey(name)] = category.ID
+			tx.publishMenuEvents(newCategoryEvent(model.MenuCategoryCreated, category))
+		}
+
+		for _, change := range changes {
+			if appError := tx.applyCatalogueChange(ctx, &change, request.ClientID, categoryIDs[change.category]); appError.Code != SuccessError {
+				return appError
+			}
+		}
+		return *NewSuccessError()
+	})
+	if appError.Code != SuccessError {
+		return report, appError
+	}
+
+	report.Applied = true
+	return report, *NewSuccessError()
+}
+
+// matchCatalogue matches the rows of a catalogue with the categories of the client and checks them. It returns
+// the report of the import, the changes of the valid rows and the IDs of the existing categories by key.
+func (s *productServiceImpl) matchCatalogue(ctx context.Context, request *model.CatalogueImportRequest, categories []entity.ProductCategory, downloads map[string]catalogueDownload) (*model.CatalogueImportReport, []catalogueChange, map[string]uint) {
+	categoryIDs := map[string]uint{}
+	products := map[string]*entity.Product{}
+	for i := range categories {
+		categoryIDs[catalogueKey(categories[i].Name)] = categories[i].ID
+		for j := range categories[i].Products {
+			product := &categories[i].Products[j]
+			products[catalogueKey(categories[i].Name, product.Name)] = product
+		}
+	}
+
+	report := &model.CatalogueImportReport{
+		DryRun:            request.DryRun,
+		CreatedCategories: []string{},
+		Rows:              []model.CatalogueRowResult{},
+	}
+	changes := []catalogueChange{}
+	newCategories := map[string]bool{}
+	imported := map[string]bool{}
+
+	for _, row := range request.Rows {
+		result := model.CatalogueRowResult{Line: row.Line, Category: row.Category, Name: row.Name}
+		key := catalogueKey(row.Category, row.Name)
+
+		var change *catalogueChange
+		var fieldErrors []model.FieldError
+		if imported[key] {
+			fieldErrors = []model.FieldError{newFieldError("name", "unique", "")}
+		} else {
+			change, fieldErrors = s.checkCatalogueRow(ctx, row, products[key], request.ImageDir, downloads)
+			imported[key] = true
+		}
+
+		if len(fieldErrors) > 0 {
+			result.Action = model.CatalogueActionError
+			result.Errors = fieldErrors
+			report.Failed++
+			report.Rows = append(report.Rows, result)
+			continue
+		}
+
+		change.category = catalogueKey(row.Category)
+		if _, ok := categoryIDs[change.category]; !ok && !newCategories[change.category] {
+			newCategories[change.category] = true
+			report.CreatedCategories = append(report.CreatedCategories, row.Category)
+		}
+
+		switch {
+		case change.product == nil:
+			result.Action = model.CatalogueActionCreate
+			report.Created++
+		case change.changesProduct():
+			result.Action = model.CatalogueActionUpdate
+			report.Updated++
+		default:
+			result.Action = model.CatalogueActionUnchanged
+			report.Unchanged++
+		}
+		report.Rows = append(report.Rows, result)
+		changes = append(changes, *change)
+	}
+
+	return report, changes, categoryIDs
+}
+
+// ExportCatalogueService exports the catalogue of the client of the token.
+func (s *productServiceImpl) ExportCatalogueService(ctx context.Context, request *model.CatalogueExportRequest, token string) ([]model.CatalogueRow, AppError) {
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return nil, *NewInvalidTokenError()
+	}
+
+	request.ClientID = uint(user.ClientId)
+	return s.ExportClientCatalogue(ctx, request)
+}
+
+// ExportClientCatalogue returns the products of a client as catalogue rows, which import back unchanged.
+// Inactive products, and the products of inactive categories, are only exported with IncludeInactive.
+// Images are exported as the URLs of the stored images.
+func (s *productServiceImpl) ExportClientCatalogue(ctx context.Context, request *model.CatalogueExportRequest) ([]model.CatalogueRow, AppError) {
+	categories, err := s.productRepository.GetClientCatalogue(ctx, request.ClientID)
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	rows := []model.CatalogueRow{}
+	for _, category := range categories {
+		if !category.IsActive && !request.IncludeInactive {
+			continue
+		}
+
+		for _, product := range category.Products {
+			if !product.IsActive && !request.IncludeInactive {
+				continue
+			}
+
+			row := model.CatalogueRow{
+				Line:        len(rows) + 2,
+				Category:    category.Name,
+				Name:        product.Name,
+				Description: product.Description,
+				Price:       strconv.FormatFloat(product.Price, 'f', -1, 64),
+				Active:      strconv.FormatBool(product.IsActive),
+			}
+			if product.Image != "" {
+				row.Image = s.imageRepository.GetURL(product.Image)
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows, *NewSuccessError()
+}
+
+// checkCatalogueRow validates a row against the existing product, if any, and loads its new image.
+// Images at http(s) URLs are taken from the downloads of the catalogue.
+func (s *productServiceImpl) checkCatalogueRow(ctx context.Context, row model.CatalogueRow, product *entity.Product, imageDir string, downloads map[string]catalogueDownload) (*catalogueChange, []model.FieldError) {
+	if err := newValidator().Struct(row); err != nil {
+		return nil, NewValidationError(err).Errors
+	}
+
+	price, _ := strconv.ParseFloat(row.Price, 64)
+	if price <= 0 {
+		return nil, []model.FieldError{newFieldError("price", "gt", "0")}
+	}
+
+	change := &catalogueChange{
+		product: product,
+		imported: entity.Product{
+			Name:        row.Name,
+			Description: row.Description,
+			Price:       price,
+			IsActive:    row.Active == "" || row.Active == "true" || row.Active == "yes" || row.Active == "1",
+		},
+	}
+	if product != nil {
+		change.imported.Image = product.Image
+		if row.Active == "" {
+			change.imported.IsActive = product.IsActive
+		}
+	}
+
+	if row.Image == "" || (product != nil && (row.Image == product.Image || row.Image == s.imageRepository.GetURL(product.Image))) {
+		return change, nil
+	}
+
+	if imageName := s.storedImageName(row.Image); imageName != "" {
+		blobs, err := s.productImageRepository.GetImageBlobs(ctx, []string{imageName})
+		if err == nil && len(blobs) == 1 {
+			change.imageName = imageName
+			change.imported.Image = imageName
+			return change, nil
+		}
+	}
+
+	var imageData []byte
+	var err error
+	if download, ok := downloads[row.Image]; ok {
+		imageData, err = download.data, download.err
+	} else {
+		imageData, err = loadCatalogueImage(ctx, row.Image, imageDir, s.maxImageBytes())
+	}
+	if err != nil {
+		return nil, []model.FieldError{{Field: "image", Rule: "image", Message: err.Error()}}
+	}
+	if _, _, appError := ValidateImage(imageData, s.imageValidation); appError.Code != SuccessError {
+		return nil, []model.FieldError{{Field: "image", Rule: "image", Message: appError.Message}}
+	}
+	change.imageData = imageData
+	return change, nil
+}
+
+// changesProduct reports whether the row changes the existing product.
+func (c *catalogueChange) changesProduct() bool {
+	return c.imageData != nil || c.imageName != "" ||
+		c.imported.Name != c.product.Name ||
+		c.imported.Description != c.product.Description ||
+		c.imported.Price != c.product.Price ||
+		c.imported.IsActive != c.product.IsActive
+}
+
+// applyCatalogueChange creates or updates the product of a valid row in a category of the client.
+func (s *productServiceImpl) applyCatalogueChange(ctx context.Context, change *catalogueChange, clientID uint, categoryID uint) AppError {
+	if change.product != nil && !change.changesProduct() {
+		return *NewSuccessError()
+	}
+
+	product := change.imported
+	product.CategoryID = categoryID
+
+	if change.imageData != nil {
+		imageName, appError := s.saveImage(ctx, change.imageData)
+		if appError.Code != SuccessError {
+			return appError
+		}
+		product.Image = imageName
+	}
+	if change.imageName != "" {
+		if _, err := s.productImageRepository.AcquireImageBlob(ctx, change.imageName, 0); err != nil {
+			return *NewUpdateQueryDBError()
+		}
+	}
+
+	if change.product == nil {
+		appError := s.addProduct(ctx, &product)
+		if appError.Code == SuccessError {
+			s.publishMenuEvents(newProductEvent(model.MenuProductCreated, clientID, &product))
+		}
+		return appError
+	}
+
+	product.ID = change.product.ID
+	product.CreatedAt = change.product.CreatedAt
+	product.Version = change.product.Version
+	if err := s.productRepository.EditProduct(ctx, &product); err != nil {
+		return newVersionedUpdateError(err)
+	}
+	s.publishMenuEvents(newProductChangeEvent(clientID, change.product, &product))
+
+	if product.Image == change.product.Image {
+		return *NewSuccessError()
+	}
+	return s.replacePrimaryImage(ctx, change.product, product.Image)
+}
+
+// storedImageName returns the name of the stored image a catalogue refers to by name or URL, or "" for other images.
+func (s *productServiceImpl) storedImageName(location string) string {
+	if !strings.Contains(location, "/") {
+		return location
+	}
+
+	name := path.Base(location)
+	if location == s.imageRepository.GetURL(name) {
+		return name
+	}
+	return ""
+}
+
+// maxImageBytes is the largest image read for a catalogue row.
+func (s *productServiceImpl) maxImageBytes() int64 {
+	if s.imageValidation.MaxBytes > 0 {
+		return s.imageValidation.MaxBytes
+	}
+	return MaxImageUploadSize
+}
+
+// downloadCatalogueImages downloads the images at the http(s) URLs of the rows of a catalogue, each URL once,
+// catalogueImageWorkers at a time and within catalogueDownloadTimeout in all. The images stored by the
+// service are not downloaded.
+func (s *productServiceImpl) downloadCatalogueImages(ctx context.Context, rows []model.CatalogueRow) map[string]catalogueDownload {
+	downloads := map[string]catalogueDownload{}
+	locations := []string{}
+	for _, row := range rows {
+		if _, ok := downloads[row.Image]; ok || !isHTTPURL(row.Image) || s.storedImageName(row.Image) != "" {
+			continue
+		}
+		downloads[row.Image] = catalogueDownload{}
+		locations = append(locations, row.Image)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, catalogueDownloadTimeout)
+	defer cancel()
+
+	results := make([]catalogueDownload, len(locations))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < catalogueImageWorkers && i < len(locations); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results[job].data, results[job].err = loadCatalogueImage(ctx, locations[job], "", s.maxImageBytes())
+			}
+		}()
+	}
+	for job := range locations {
+		jobs <- job
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, location := range locations {
+		downloads[location] = results[i]
+	}
+	return downloads
+}
+
+// isHTTPURL reports whether a location of a catalogue image is an http(s) URL.
+func isHTTPURL(location string) bool {
+	u, err := url.Parse(location)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// loadCatalogueImage reads the image of a catalogue row from an http(s) URL of a public address or, with an
+// image directory, from a local path. A byte more than maxBytes is read, so oversized images fail their validation.
+func loadCatalogueImage(ctx context.Context, location string, imageDir string, maxBytes int64) ([]byte, error) {
+	if isHTTPURL(location) {
+		ctx, cancel := context.WithTimeout(ctx, catalogueImageTimeout)
+		defer cancel()
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+		if err != nil {
+			return nil, err
+		}
+		response, err := catalogueImageClient.Do(request)
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s", location, response.Status)
+		}
+		return io.ReadAll(io.LimitReader(response.Body, maxBytes+1))
+	}
+
+	if imageDir == "" {
+		return nil, fmt.Errorf("%s: not an http(s) URL", location)
+	}
+	if !filepath.IsAbs(location) {
+		location = filepath.Join(imageDir, location)
+	}
+	file, err := os.Open(location)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(io.LimitReader(file, maxBytes+1))
+}
+
+// catalogueKey identifies a category, or a product with the name of its category, ignoring case.
+func catalogueKey(names ...string) string {
+	for i, name := range names {
+		names[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+	return strings.Join(names, "\x00")
+}
@@ -0,0 +1,270 @@
+// internal/service/clone_service.go
+
+package service
+
+import (
+	"context"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"time"
+)
+
+// copySuffix is added to the name of a product or category copied next to the original.
+const copySuffix = " (copy)"
+
+// CloneProductService copies a product of the client of the token with its gallery, translations and tags.
+// Stored images are shared by the copy, which takes its own reference to them.
+func (s *productServiceImpl) CloneProductService(ctx context.Context, request *model.CloneProductRequest, token string) (*entity.Product, AppError) {
+	validate := newValidator()
+	if err := validate.Struct(request); err != nil {
+		return nil, *NewValidationError(err)
+	}
+
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return nil, *NewInvalidTokenError()
+	}
+
+	source, appError := s.getClientProduct(ctx, request.ProductID, token, user)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	categoryID := source.CategoryID
+	if request.CategoryID != 0 {
+		if _, appError := s.getClientCategory(ctx, request.CategoryID, user); appError.Code != SuccessError {
+			return nil, appError
+		}
+		categoryID = request.CategoryID
+	}
+
+	name := request.Name
+	if name == "" {
+		name = source.Name
+		if categoryID == source.CategoryID {
+			name += copySuffix
+		}
+	}
+
+	var clone *entity.Product
+	appError = s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		var appError AppError
+		clone, _, appError = tx.cloneProduct(ctx, source, categoryID, name)
+		if appError.Code == SuccessError {
+			tx.publishMenuEvents(newProductEvent(model.MenuProductCreated, uint(user.ClientId), clone))
+		}
+		return appError
+	})
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+	return clone, appError
+}
+
+// CloneCategoryService copies a category of the client of the token with its translations and all its products.
+func (s *productServiceImpl) CloneCategoryService(ctx context.Context, request *model.CloneCategoryRequest, token string) (*entity.ProductCategory, AppError) {
+	validate := newValidator()
+	if err := validate.Struct(request); err != nil {
+		return nil, *NewValidationError(err)
+	}
+
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return nil, *NewInvalidTokenError()
+	}
+
+	source, appError := s.getClientCategory(ctx, request.CategoryID, user)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	name := request.Name
+	if name == "" {
+		name = source.Name + copySuffix
+	}
+
+	var clone *entity.ProductCategory
+	appError = s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		products, err := tx.productRepository.LockClientProducts(ctx, source.ClientID, nil, []uint{source.ID})
+		if err != nil {
+			return *NewQueryDBError()
+		}
+		source.Products = products
+
+		var appError AppError
+		clone, _, appError = tx.cloneCategory(ctx, source, source.ClientID, name)
+		return appError
+	})
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+	return clone, appError
+}
+
+// CloneCatalogueService copies the catalogue of the client of the token into the client of the target token.
+// Both tokens must be admin tokens, of different clients.
+func (s *productServiceImpl) CloneCatalogueService(ctx context.Context, request *model.CloneCatalogueRequest, token string) (*model.CloneCatalogueReport, AppError) {
+	if request.TargetToken == "" {
+		return nil, *newFieldErrors([]model.FieldError{newFieldError("target_token", "required", "")})
+	}
+
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return nil, *NewInvalidTokenError()
+	}
+
+	target := s.getAdminUser(ctx, request.TargetToken)
+	if target == nil {
+		return nil, *NewInvalidTokenError()
+	}
+	if target.ClientId == user.ClientId {
+		return nil, *NewInvalidRequestError("target_token")
+	}
+
+	request.SourceClientID = uint(user.ClientId)
+	request.TargetClientID = uint(target.ClientId)
+	return s.CloneClientCatalogue(ctx, request)
+}
+
+// CloneClientCatalogue copies every category and product of the source client, active or not, into the
+// target client, in one transaction. The copies are added next to the catalogue the target already has.
+func (s *productServiceImpl) CloneClientCatalogue(ctx context.Context, request *model.CloneCatalogueRequest) (*model.CloneCatalogueReport, AppError) {
+	report := &model.CloneCatalogueReport{}
+	appError := s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		categories, err := tx.productRepository.GetClientCatalogue(ctx, request.SourceClientID)
+		if err != nil {
+			return *NewQueryDBError()
+		}
+
+		for i := range categories {
+			clone, images, appError := tx.cloneCategory(ctx, &categories[i], request.TargetClientID, categories[i].Name)
+			if appError.Code != SuccessError {
+				return appError
+			}
+			report.Categories++
+			report.Products += len(clone.Products)
+			report.Images += images
+		}
+		return *NewSuccessError()
+	})
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+	return report, appError
+}
+
+// cloneCategory copies a category, with its translations and the products it was loaded with, into a client.
+// It returns the copy with the copied products and the number of product images copied.
+func (s *productServiceImpl) cloneCategory(ctx context.Context, source *entity.ProductCategory, clientID uint, name string) (*entity.ProductCategory, int, AppError) {
+	clone := &entity.ProductCategory{
+		ClientID: clientID,
+		Name:     name,
+		IsActive: source.IsActive,
+	}
+	if err := s.productRepository.AddProductCategory(ctx, clone); err != nil {
+		return nil, 0, *NewUpdateQueryDBError()
+	}
+	s.publishMenuEvents(newCategoryEvent(model.MenuCategoryCreated, clone))
+
+	translations, err := s.translationRepository.GetCategoryTranslations(ctx, source.ID)
+	if err != nil {
+		return nil, 0, *NewQueryDBError()
+	}
+	for _, translation := range translations {
+		translation.ID = 0
+		translation.CategoryID = clone.ID
+		translation.CreatedAt, translation.UpdatedAt = time.Time{}, time.Time{}
+		if err := s.translationRepository.SaveCategoryTranslation(ctx, &translation); err != nil {
+			return nil, 0, *NewUpdateQueryDBError()
+		}
+	}
+
+	images := 0
+	clone.Products = make([]entity.Product, 0, len(source.Products))
+	for i := range source.Products {
+		product, productImages, appError := s.cloneProduct(ctx, &source.Products[i], clone.ID, source.Products[i].Name)
+		if appError.Code != SuccessError {
+			return nil, 0, appError
+		}
+		clone.Products = append(clone.Products, *product)
+		images += productImages
+		s.publishMenuEvents(newProductEvent(model.MenuProductCreated, clientID, product))
+	}
+	return clone, images, *NewSuccessError()
+}
+
+// cloneProduct copies a product, with its gallery, translations and tags, into a category.
+// It returns the copy and the number of product images copied.
+func (s *productServiceImpl) cloneProduct(ctx context.Context, source *entity.Product, categoryID uint, name string) (*entity.Product, int, AppError) {
+	clone := &entity.Product{
+		CategoryID:  categoryID,
+		Name:        name,
+		Description: source.Description,
+		Image:       source.Image,
+		Price:       source.Price,
+		IsActive:    source.IsActive,
+	}
+	if _, err := s.productRepository.AddProduct(ctx, clone); err != nil {
+		return nil, 0, *NewUpdateQueryDBError()
+	}
+
+	gallery, err := s.getGallery(ctx, source)
+	if err != nil {
+		return nil, 0, *NewQueryDBError()
+	}
+	for _, image := range gallery {
+		if appError := s.acquireClonedImage(ctx, image.FileName); appError.Code != SuccessError {
+			return nil, 0, appError
+		}
+
+		copied := entity.ProductImage{
+			ProductID: clone.ID,
+			FileName:  image.FileName,
+			AltText:   image.AltText,
+			Position:  image.Position,
+			IsPrimary: image.IsPrimary,
+		}
+		if err := s.productImageRepository.AddProductImage(ctx, &copied); err != nil {
+			return nil, 0, *NewUpdateQueryDBError()
+		}
+		clone.Images = append(clone.Images, copied)
+	}
+
+	translations, err := s.translationRepository.GetProductTranslations(ctx, source.ID)
+	if err != nil {
+		return nil, 0, *NewQueryDBError()
+	}
+	for _, translation := range translations {
+		translation.ID = 0
+		translation.ProductID = clone.ID
+		translation.CreatedAt, translation.UpdatedAt = time.Time{}, time.Time{}
+		if err := s.translationRepository.SaveProductTranslation(ctx, &translation); err != nil {
+			return nil, 0, *NewUpdateQueryDBError()
+		}
+	}
+
+	tags := make([]string, 0, len(source.Tags))
+	for _, tag := range source.Tags {
+		tags = append(tags, tag.Tag)
+		clone.Tags = append(clone.Tags, entity.ProductTag{ProductID: clone.ID, Tag: tag.Tag})
+	}
+	if err := s.productRepository.AddProductTags(ctx, clone.ID, tags); err != nil {
+		return nil, 0, *NewUpdateQueryDBError()
+	}
+	return clone, len(gallery), *NewSuccessError()
+}
+
+// acquireClonedImage adds a reference to a stored image for a copied product image.
+func (s *productServiceImpl) acquireClonedImage(ctx context.Context, imageName string) AppError {
+	created, err := s.productImageRepository.AcquireImageBlob(ctx, imageName, 0)
+	if err != nil {
+		return *NewUpdateQueryDBError()
+	}
+	// images stored before reference counting also need a reference for the original
+	if created {
+		if _, err := s.productImageRepository.AcquireImageBlob(ctx, imageName, 0); err != nil {
+			return *NewUpdateQueryDBError()
+		}
+	}
+	return *NewSuccessError()
+}
@@ -1,6 +1,9 @@
 package service
 
-import "fmt"
+import (
+	"fmt"
+	"maqhaa/product_service/internal/app/model"
+)
 
 const (
 	SuccessError   = 00
@@ -31,6 +34,17 @@ const (
 	InvalidTotal               = 206
 	InvalidTotalMessage        = "Invalid Total"
 
+	ImageTooLarge                 = 207
+	ImageTooLargeMessage          = "Image Too Large %s"
+	InvalidImageDimensions        = 208
+	InvalidImageDimensionsMessage = "Invalid Image Dimensions %s"
+	UnsupportedImageFormat        = 209
+	UnsupportedImageFormatMessage = "Unsupported Image Format %s"
+	InvalidImage                  = 210
+	InvalidImageMessage           = "Invalid Image"
+	VersionRequired               = 211
+	VersionRequiredMessage        = "Version Required"
+
 	//300 to 399: Database-related errors
 	QueryError              = 301
 	QueryErrorMessage       = "Error query database"
@@ -39,14 +53,33 @@ const (
 
 	//600 to 699: Business-specific errors
 	//product service error 600 -620
-	DateCategoryNotFound        = 601
-	DateCategoryNotFoundMessage = "Data Not Found"
+	DateCategoryNotFound           = 601
+	DateCategoryNotFoundMessage    = "Data Not Found"
+	TranslationNotFound            = 602
+	TranslationNotFoundMessage     = "Translation Not Found"
+	ProductImageNotFound           = 603
+	ProductImageNotFoundMessage    = "Product Image Not Found"
+	ImageNotFound                  = 604
+	ImageNotFoundMessage           = "Image Not Found"
+	VersionConflict                = 605
+	VersionConflictMessage         = "Version Conflict"
+	IdempotencyKeyConflict         = 606
+	IdempotencyKeyConflictMessage  = "Idempotency Key Conflict"
+	RequestInProgress              = 607
+	RequestInProgressMessage       = "Request In Progress"
+	WatchLagging                   = 608
+	WatchLaggingMessage            = "Watch Lagging"
+	WebhookNotFound                = 609
+	WebhookNotFoundMessage         = "Webhook Not Found"
+	WebhookDeliveryNotFound        = 610
+	WebhookDeliveryNotFoundMessage = "Webhook Delivery Not Found"
 )
 
 // AppError represents an application-specific error.
 type AppError struct {
 	Code    int
 	Message string
+	Errors  []model.FieldError
 }
 
 // NewAppError creates a new instance of AppError.
@@ -107,3 +140,59 @@ func NewInvalidProductPriceError() *AppError {
 func NewInvalidTotalError() *AppError {
 	return NewAppError(InvalidTotal, InvalidTotalMessage)
 }
+
+func NewImageTooLargeError(s string) *AppError {
+	return NewAppError(ImageTooLarge, fmt.Sprintf(ImageTooLargeMessage, s))
+}
+
+func NewInvalidImageDimensionsError(s string) *AppError {
+	return NewAppError(InvalidImageDimensions, fmt.Sprintf(InvalidImageDimensionsMessage, s))
+}
+
+func NewUnsupportedImageFormatError(s string) *AppError {
+	return NewAppError(UnsupportedImageFormat, fmt.Sprintf(UnsupportedImageFormatMessage, s))
+}
+
+func NewInvalidImageError() *AppError {
+	return NewAppError(InvalidImage, InvalidImageMessage)
+}
+
+func NewVersionRequiredError() *AppError {
+	return NewAppError(VersionRequired, VersionRequiredMessage)
+}
+
+func NewVersionConflictError() *AppError {
+	return NewAppError(VersionConflict, VersionConflictMessage)
+}
+
+func NewIdempotencyKeyConflictError() *AppError {
+	return NewAppError(IdempotencyKeyConflict, IdempotencyKeyConflictMessage)
+}
+
+func NewRequestInProgressError() *AppError {
+	return NewAppError(RequestInProgress, RequestInProgressMessage)
+}
+
+func NewWatchLaggingError() *AppError {
+	return NewAppError(WatchLagging, WatchLaggingMessage)
+}
+
+func NewWebhookNotFoundError() *AppError {
+	return NewAppError(WebhookNotFound, WebhookNotFoundMessage)
+}
+
+func NewWebhookDeliveryNotFoundError() *AppError {
+	return NewAppError(WebhookDeliveryNotFound, WebhookDeliveryNotFoundMessage)
+}
+
+func NewTranslationNotFoundError() *AppError {
+	return NewAppError(TranslationNotFound, TranslationNotFoundMessage)
+}
+
+func NewProductImageNotFoundError() *AppError {
+	return NewAppError(ProductImageNotFound, ProductImageNotFoundMessage)
+}
+
+func NewImageNotFoundError() *AppError {
+	return NewAppError(ImageNotFound, ImageNotFoundMessage)
+}
@@ -0,0 +1,208 @@
+// internal/service/idempotency_service.go
+
+package service
+
+import (
+	"context"
+	"errors"
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	exRepo "maqhaa/product_service/external/repository"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/repository"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// defaultIdempotencyLease is the lease of the keys when none is configured.
+const defaultIdempotencyLease = time.Minute
+
+// IdempotencyService replays the first response to a create request retried with the same Idempotency-Key.
+// Keys are scoped to the client of the token and kept for the TTL of the service.
+type IdempotencyService interface {
+	// BeginRequest looks up the key of a request identified by requestHash. A retried request gets the key
+	// with the first response; a new request gets the key reserved for it under a new lease, without a
+	// status code, and its response must then be saved with CompleteRequest. A key whose lease expired
+	// without a response, because the server stopped while handling its request, is reserved again.
+	BeginRequest(ctx context.Context, token string, key string, requestHash string) (*entity.IdempotencyKey, AppError)
+	// HoldRequest renews the lease of a reserved key until the returned function is called, so the key of
+	// a request still in progress is never reserved again, however long the request takes.
+	HoldRequest(ctx context.Context, key *entity.IdempotencyKey) (stop func())
+	// CompleteRequest saves the response to a reserved key. The key is released instead on server errors,
+	// so the request can be retried. Nothing is saved once the lease was lost to a retry.
+	CompleteRequest(ctx context.Context, key *entity.IdempotencyKey, statusCode int, response string)
+	PurgeExpiredKeys(ctx context.Context) (int64, error)
+	RunPurge(ctx context.Context, interval time.Duration)
+}
+
+type idempotencyServiceImpl struct {
+	idempotencyRepository repository.IdempotencyRepository
+	userRepository        exRepo.UserRepository
+	ttl                   time.Duration
+	lease                 time.Duration
+}
+
+// NewIdempotencyService creates a new IdempotencyService instance. Keys are kept for ttl, and reserved for
+// a request under a lease renewed while the request is in progress; a zero lease is one minute.
+func NewIdempotencyService(idempotencyRepository repository.IdempotencyRepository, userRepository exRepo.UserRepository, ttl time.Duration, lease time.Duration) IdempotencyService {
+	if lease <= 0 {
+		lease = defaultIdempotencyLease
+	}
+	return &idempotencyServiceImpl{
+		idempotencyRepository: idempotencyRepository,
+		userRepository:        userRepository,
+		ttl:                   ttl,
+		lease:                 lease,
+	}
+}
+
+func (s *idempotencyServiceImpl) BeginRequest(ctx context.Context, token string, key string, requestHash string) (*entity.IdempotencyKey, AppError) {
+	user, err := s.userRepository.GetUser(ctx, token)
+	if err != nil || !user.IsLogin {
+		return nil, *NewInvalidTokenError()
+	}
+
+	now := time.Now()
+	reserved := &entity.IdempotencyKey{
+		ClientID:       uint(user.ClientId),
+		Key:            key,
+		RequestHash:    requestHash,
+		LeaseID:        uuid.New().String(),
+		LeaseExpiresAt: now.Add(s.lease),
+		ExpiresAt:      now.Add(s.ttl),
+	}
+
+	// a second attempt is made when the key found has expired, or was removed in the meantime
+	for attempt := 0; attempt < 2; attempt++ {
+		created, err := s.idempotencyRepository.ReserveIdempotencyKey(ctx, reserved)
+		if err != nil {
+			return nil, *NewUpdateQueryDBError()
+		}
+		if created {
+			return reserved, *NewSuccessError()
+		}
+
+		existing, err := s.idempotencyRepository.GetIdempotencyKey(ctx, reserved.ClientID, key)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, *NewQueryDBError()
+		}
+
+		if existing.ExpiresAt.After(now) {
+			if existing.RequestHash != requestHash {
+				return nil, *NewIdempotencyKeyConflictError()
+			}
+			if existing.StatusCode == 0 {
+				return s.takeOverKey(ctx, reserved, existing, now)
+			}
+			return existing, *NewSuccessError()
+		}
+
+		if err := s.idempotencyRepository.DeleteExpiredIdempotencyKey(ctx, reserved.ClientID, key, now); err != nil {
+			return nil, *NewUpdateQueryDBError()
+		}
+	}
+	return nil, *NewRequestInProgressError()
+}
+
+// takeOverKey reserves a key without a response for the request of reserved, once the lease of the
+// request holding it expired.
+func (s *idempotencyServiceImpl) takeOverKey(ctx context.Context, reserved *entity.IdempotencyKey, existing *entity.IdempotencyKey, now time.Time) (*entity.IdempotencyKey, AppError) {
+	if existing.LeaseExpiresAt.After(now) {
+		return nil, *NewRequestInProgressError()
+	}
+
+	taken, err := s.idempotencyRepository.TakeOverIdempotencyKey(ctx, reserved, now)
+	if err != nil {
+		return nil, *NewUpdateQueryDBError()
+	}
+	if !taken {
+		return nil, *NewRequestInProgressError()
+	}
+	return reserved, *NewSuccessError()
+}
+
+func (s *idempotencyServiceImpl) HoldRequest(ctx context.Context, key *entity.IdempotencyKey) func() {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	// the request goes on when its client disconnects, and so does the lease
+	ctx = context.WithoutCancel(ctx)
+	renewed := *key
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(s.lease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				renewed.LeaseExpiresAt = time.Now().Add(s.lease)
+				held, err := s.idempotencyRepository.RenewIdempotencyLease(ctx, &renewed)
+				if err != nil {
+					continue
+				}
+				if !held {
+					logging.Log.WithFields(logrus.Fields{"request_id": logID}).Warnf("Lost the lease of idempotency key %s", key.Key)
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (s *idempotencyServiceImpl) CompleteRequest(ctx context.Context, key *entity.IdempotencyKey, statusCode int, response string) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	if statusCode >= http.StatusInternalServerError {
+		if err := s.idempotencyRepository.ReleaseIdempotencyKey(ctx, key); err != nil {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ReleaseIdempotencyKey %s", err.Error())
+		}
+		return
+	}
+
+	key.StatusCode = statusCode
+	key.Response = response
+	if err := s.idempotencyRepository.SaveIdempotencyResponse(ctx, key); err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error SaveIdempotencyResponse %s", err.Error())
+	}
+}
+
+// PurgeExpiredKeys removes the expired keys and returns how many were removed.
+func (s *idempotencyServiceImpl) PurgeExpiredKeys(ctx context.Context) (int64, error) {
+	return s.idempotencyRepository.DeleteExpiredIdempotencyKeys(ctx, time.Now())
+}
+
+// RunPurge removes the expired keys every interval until the context is done.
+func (s *idempotencyServiceImpl) RunPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeExpiredKeys(ctx)
+			if err != nil {
+				logging.Log.Errorf("Error PurgeExpiredKeys %s", err.Error())
+				continue
+			}
+			logging.Log.Infof("Purged %d expired idempotency keys", purged)
+		}
+	}
+}
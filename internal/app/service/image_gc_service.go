@@ -0,0 +1,197 @@
+// internal/service/image_gc_service.go
+
+package service
+
+import (
+	"context"
+	"maqhaa/library/logging"
+	"maqhaa/product_service/internal/app/repository"
+	"sort"
+	"time"
+)
+
+// ImageGCReport is the outcome of a garbage collection of stored images.
+type ImageGCReport struct {
+	// Orphans are stored images no longer referenced by the database.
+	Orphans []string `json:"orphans"`
+	// Missing are images referenced by the database but not stored.
+	Missing []string `json:"missing"`
+	// Deleted are the orphans removed, those older than the grace period.
+	Deleted []string `json:"deleted"`
+	// ExpiredUploads are the images of the uploads older than the grace period, whose references were released.
+	ExpiredUploads []string `json:"expiredUploads"`
+}
+
+// ImageGCService removes the stored images no longer referenced by the database.
+type ImageGCService interface {
+	CollectImages(ctx context.Context, gracePeriod time.Duration, dryRun bool) (*ImageGCReport, error)
+	RunImageGC(ctx context.Context, interval time.Duration, gracePeriod time.Duration)
+}
+
+// expiredUploadsBatch is the number of expired uploads released in one transaction.
+const expiredUploadsBatch = 100
+
+type imageGCServiceImpl struct {
+	imageRepository         repository.ImagesRepository
+	productImageRepository  repository.ProductImageRepository
+	uploadedImageRepository repository.UploadedImageRepository
+	unitOfWork              repository.UnitOfWork
+}
+
+// NewImageGCService creates a new ImageGCService instance.
+func NewImageGCService(imageRepository repository.ImagesRepository, productImageRepository repository.ProductImageRepository, uploadedImageRepository repository.UploadedImageRepository, unitOfWork repository.UnitOfWork) ImageGCService {
+	return &imageGCServiceImpl{
+		imageRepository:         imageRepository,
+		productImageRepository:  productImageRepository,
+		uploadedImageRepository: uploadedImageRepository,
+		unitOfWork:              unitOfWork,
+	}
+}
+
+// CollectImages reconciles the stored images with the images referenced by the database.
+// Uploads older than the grace period expire first: their references are released, so the images
+// never attached to a product become orphans; products hold references of their own. Orphans older
+// than the grace period are removed; younger ones may belong to an upload still in progress.
+// With dryRun set, nothing is expired nor removed.
+func (s *imageGCServiceImpl) CollectImages(ctx context.Context, gracePeriod time.Duration, dryRun bool) (*ImageGCReport, error) {
+	report := &ImageGCReport{Orphans: []string{}, Missing: []string{}, Deleted: []string{}, ExpiredUploads: []string{}}
+	cutoff := time.Now().Add(-gracePeriod)
+	if !dryRun {
+		if err := s.expireUploads(ctx, cutoff, report); err != nil {
+			return nil, err
+		}
+	}
+
+	stored, err := s.imageRepository.ListImages()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := s.productImageRepository.GetReferencedImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	referenced := map[string]bool{}
+	for _, name := range names {
+		referenced[name] = true
+	}
+
+	for _, image := range stored {
+		if referenced[image.Name()] {
+			delete(referenced, image.Name())
+			continue
+		}
+
+		report.Orphans = append(report.Orphans, image.Name())
+		if dryRun || image.ModTime().After(cutoff) {
+			continue
+		}
+		removed, err := s.removeOrphan(ctx, image.Name(), cutoff)
+		if err != nil {
+			logging.Log.Errorf("Error RemoveImage %s: %s", image.Name(), err.Error())
+			continue
+		}
+		if removed {
+			report.Deleted = append(report.Deleted, image.Name())
+		}
+	}
+
+	for name := range referenced {
+		report.Missing = append(report.Missing, name)
+	}
+	sort.Strings(report.Missing)
+	return report, nil
+}
+
+// removeOrphan removes an orphan unless it was referenced or stored again since the images were listed:
+// stored images are named after their content, so an upload may bring an orphan back at any time. The
+// references are checked again with the blob of the image locked, which holds off the uploads acquiring
+// it until the image is removed; uploads store the image once they hold a reference.
+func (s *imageGCServiceImpl) removeOrphan(ctx context.Context, imageName string, cutoff time.Time) (bool, error) {
+	removed := false
+	err := s.unitOfWork.Do(ctx, func(repositories *repository.Repositories) error {
+		referenced, err := repositories.ProductImage.LockImageReferences(ctx, imageName)
+		if err != nil || referenced {
+			return err
+		}
+
+		file, err := s.imageRepository.OpenImage(imageName)
+		if err != nil {
+			return err
+		}
+		info, err := file.Stat()
+		file.Close()
+		if err != nil || info.ModTime().After(cutoff) {
+			return err
+		}
+
+		if err := s.imageRepository.RemoveImage(imageName); err != nil {
+			return err
+		}
+		removed = true
+		return nil
+	})
+	return removed, err
+}
+
+// expireUploads deletes the uploads created before cutoff and releases their references to their images.
+// The renditions of an image are deleted with its last reference; the files are left to the collection.
+func (s *imageGCServiceImpl) expireUploads(ctx context.Context, cutoff time.Time, report *ImageGCReport) error {
+	for {
+		uploads, err := s.uploadedImageRepository.GetExpiredUploadedImages(ctx, cutoff, expiredUploadsBatch)
+		if err != nil || len(uploads) == 0 {
+			return err
+		}
+
+		err = s.unitOfWork.Do(ctx, func(repositories *repository.Repositories) error {
+			for _, upload := range uploads {
+				if err := repositories.UploadedImage.DeleteUploadedImage(ctx, upload.ID); err != nil {
+					return err
+				}
+				remaining, err := repositories.ProductImage.ReleaseImageBlob(ctx, upload.FileName)
+				if err != nil {
+					return err
+				}
+				if remaining == 0 {
+					if err := repositories.ProductImage.DeleteImageRenditions(ctx, upload.FileName); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, upload := range uploads {
+			report.ExpiredUploads = append(report.ExpiredUploads, upload.FileName)
+		}
+		if len(uploads) < expiredUploadsBatch {
+			return nil
+		}
+	}
+}
+
+// RunImageGC collects the images every interval until the context is done.
+func (s *imageGCServiceImpl) RunImageGC(ctx context.Context, interval time.Duration, gracePeriod time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.CollectImages(ctx, gracePeriod, false)
+			if err != nil {
+				logging.Log.Errorf("Error CollectImages %s", err.Error())
+				continue
+			}
+			logging.Log.Infof("Image GC expired %d uploads, found %d orphans and %d missing images, deleted %d orphans", len(report.ExpiredUploads), len(report.Orphans), len(report.Missing), len(report.Deleted))
+			for _, name := range report.Missing {
+				logging.Log.Warnf("Image GC missing image %s", name)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// internal/service/image_service.go
+
+package service
+
+import (
+	"context"
+	"errors"
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/repository"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ImageService handles business logic related to serving stored images.
+type ImageService interface {
+	GetImageService(ctx context.Context, request *model.ImageRequest) (repository.ImageFile, AppError)
+}
+
+type imageServiceImpl struct {
+	imageRepository repository.ImagesRepository
+}
+
+// NewImageService creates a new ImageService instance.
+func NewImageService(imageRepository repository.ImagesRepository) ImageService {
+	return &imageServiceImpl{
+		imageRepository: imageRepository,
+	}
+}
+
+// GetImageService opens a stored image, resized and converted when the request asks for it.
+func (s *imageServiceImpl) GetImageService(ctx context.Context, request *model.ImageRequest) (repository.ImageFile, AppError) {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	validate := newValidator()
+	if err := validate.Struct(request); err != nil {
+		return nil, *NewValidationError(err)
+	}
+
+	var file repository.ImageFile
+	var err error
+	if request.Width == 0 && request.Height == 0 && request.Format == "" {
+		file, err = s.imageRepository.OpenImage(request.Name)
+	} else {
+		file, err = s.imageRepository.OpenResizedImage(request.Name, request.Width, request.Height, request.Format)
+	}
+
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, *NewImageNotFoundError()
+		}
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetImageService %s", err.Error())
+		return nil, *NewGeneralSystemError()
+	}
+
+	return file, *NewSuccessError()
+}
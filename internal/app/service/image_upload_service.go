@@ -0,0 +1,161 @@
+// internal/service/image_upload_service.go
+
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"maqhaa/product_service/external/model"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/placeholder"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// MaxImageUploadSize is the largest upload, in bytes, read by UploadImageService.
+// The configured image validation may accept less.
+const MaxImageUploadSize = 10 << 20
+
+// UploadImageService stores an image read from an upload stream so products can reference it by ID.
+func (s *productServiceImpl) UploadImageService(ctx context.Context, file io.Reader, token string) (*entity.UploadedImage, AppError) {
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return nil, *NewInvalidTokenError()
+	}
+
+	imageData, err := io.ReadAll(io.LimitReader(file, MaxImageUploadSize+1))
+	if err != nil {
+		return nil, *NewInvalidRequestError(err.Error())
+	}
+
+	if len(imageData) > MaxImageUploadSize {
+		return nil, *NewImageTooLargeError(fmt.Sprintf("(max %d bytes)", MaxImageUploadSize))
+	}
+
+	image := &entity.UploadedImage{
+		ClientID:    uint(user.ClientId),
+		ContentType: http.DetectContentType(imageData),
+		Size:        int64(len(imageData)),
+	}
+	appError := s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		fileName, appError := tx.saveImage(ctx, imageData)
+		if appError.Code != SuccessError {
+			return appError
+		}
+
+		image.FileName = fileName
+		if err := tx.uploadedImageRepository.AddUploadedImage(ctx, image); err != nil {
+			return *NewUpdateQueryDBError()
+		}
+		return *NewSuccessError()
+	})
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+	fileName := image.FileName
+
+	renditions, err := s.productImageRepository.GetImageRenditions(ctx, []string{fileName})
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	blobs, err := s.productImageRepository.GetImageBlobs(ctx, []string{fileName})
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	image.URL = s.imageRepository.GetURL(image.FileName)
+	image.Renditions = renditions
+	image.SrcSet = s.setRenditionURLs(image.Renditions)
+	for _, blob := range blobs {
+		image.BlurHash = blob.BlurHash
+		image.DominantColor = blob.DominantColor
+	}
+	return image, *NewSuccessError()
+}
+
+// resolveImage returns the file name of the image of a request, either an image uploaded before
+// and referenced by its ID, or a new image sent as base64. Either way the caller holds a new
+// reference to the image, to be dropped with releaseImage.
+func (s *productServiceImpl) resolveImage(ctx context.Context, base64Image string, imageID uint, user *model.UserData) (string, AppError) {
+	if imageID != 0 {
+		image, err := s.uploadedImageRepository.GetUploadedImageByID(ctx, imageID, uint(user.ClientId))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", *NewImageNotFoundError()
+			}
+			return "", *NewQueryDBError()
+		}
+
+		created, err := s.productImageRepository.AcquireImageBlob(ctx, image.FileName, image.Size)
+		if err != nil {
+			return "", *NewUpdateQueryDBError()
+		}
+		// images uploaded before reference counting also need a reference for their upload
+		if created {
+			if _, err := s.productImageRepository.AcquireImageBlob(ctx, image.FileName, image.Size); err != nil {
+				return "", *NewUpdateQueryDBError()
+			}
+		}
+		return image.FileName, *NewSuccessError()
+	}
+
+	imageData, err := base64.StdEncoding.DecodeString(base64Image)
+	if err != nil {
+		return "", *NewInvalidRequestError(err.Error())
+	}
+	return s.saveImage(ctx, imageData)
+}
+
+// saveImage stores an image under the hash of its content and adds a reference to it.
+// The placeholders and the renditions are only generated the first time an image is stored.
+func (s *productServiceImpl) saveImage(ctx context.Context, imageData []byte) (string, AppError) {
+	img, format, appError := ValidateImage(imageData, s.imageValidation)
+	if appError.Code != SuccessError {
+		return "", appError
+	}
+
+	imageName, compressedImage, err := compressImage(img, format)
+	if err != nil {
+		return "", *NewUnsupportedImageFormatError(format)
+	}
+
+	// the reference is taken before the image is stored, so the image GC cannot remove it in between
+	created, err := s.productImageRepository.AcquireImageBlob(ctx, imageName, int64(len(compressedImage)))
+	if err != nil {
+		return "", *NewUpdateQueryDBError()
+	}
+	if created && s.imageChanges != nil {
+		s.imageChanges.stored = append(s.imageChanges.stored, imageName)
+	}
+
+	// storing the same content again is harmless, and repairs a file lost from the storage
+	if err := s.imageRepository.SaveImage(compressedImage, imageName); err != nil {
+		s.releaseImage(ctx, imageName)
+		return "", *NewInvalidRequestError(err.Error())
+	}
+	if !created {
+		return imageName, *NewSuccessError()
+	}
+
+	if err := s.productImageRepository.SetImagePlaceholder(ctx, imageName, placeholder.BlurHash(img), placeholder.DominantColor(img)); err != nil {
+		s.releaseImage(ctx, imageName)
+		return "", *NewUpdateQueryDBError()
+	}
+
+	renditions, err := s.imageRepository.SaveRenditions(img, imageName)
+	if err != nil {
+		s.releaseImage(ctx, imageName)
+		return "", *NewInvalidRequestError(err.Error())
+	}
+
+	if err := s.productImageRepository.AddImageRenditions(ctx, renditions); err != nil {
+		s.releaseImage(ctx, imageName)
+		return "", *NewUpdateQueryDBError()
+	}
+	return imageName, *NewSuccessError()
+}
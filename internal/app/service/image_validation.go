@@ -0,0 +1,172 @@
+// internal/service/image_validation.go
+
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"maqhaa/product_service/internal/config"
+
+	// Register the decoders of the formats recognised, even when not allowed, so they are reported as unsupported.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// ValidateImage checks the raw bytes of an image against the validation rules and decodes it.
+// The header is checked before the pixels are decoded, so oversized images are rejected cheaply.
+// JPEG images are turned upright according to their EXIF orientation; the metadata itself is
+// dropped when the image is encoded again.
+func ValidateImage(imageData []byte, rules config.ImageValidationConfig) (image.Image, string, AppError) {
+	if len(imageData) == 0 {
+		return nil, "", *NewInvalidImageError()
+	}
+	if rules.MaxBytes > 0 && int64(len(imageData)) > rules.MaxBytes {
+		return nil, "", *NewImageTooLargeError(fmt.Sprintf("(max %d bytes)", rules.MaxBytes))
+	}
+
+	header, format, err := image.DecodeConfig(bytes.NewReader(imageData))
+	if err != nil {
+		return nil, "", *NewInvalidImageError()
+	}
+
+	if !isAllowedFormat(format, rules.AllowedFormats) {
+		return nil, "", *NewUnsupportedImageFormatError(format)
+	}
+
+	if header.Width < rules.MinWidth || header.Height < rules.MinHeight {
+		return nil, "", *NewInvalidImageDimensionsError(fmt.Sprintf("(%dx%d, min %dx%d)", header.Width, header.Height, rules.MinWidth, rules.MinHeight))
+	}
+	if (rules.MaxWidth > 0 && header.Width > rules.MaxWidth) || (rules.MaxHeight > 0 && header.Height > rules.MaxHeight) {
+		return nil, "", *NewInvalidImageDimensionsError(fmt.Sprintf("(%dx%d, max %dx%d)", header.Width, header.Height, rules.MaxWidth, rules.MaxHeight))
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		return nil, "", *NewInvalidImageError()
+	}
+
+	if format == "jpeg" {
+		img = orientImage(img, exifOrientation(imageData))
+	}
+	return img, format, *NewSuccessError()
+}
+
+func isAllowedFormat(format string, allowedFormats []string) bool {
+	if len(allowedFormats) == 0 {
+		return true
+	}
+	for _, allowed := range allowedFormats {
+		if allowed == format {
+			return true
+		}
+	}
+	return false
+}
+
+// exifOrientation returns the orientation tag of the EXIF metadata of a JPEG image,
+// or 1 (upright) when the image has none.
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1
+	}
+
+	// Walk the segments up to the start of the scan, looking for the APP1 Exif segment.
+	for i := 2; i+4 <= len(data) && data[i] == 0xff; {
+		marker := data[i+1]
+		if marker == 0xda {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			break
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag (0x0112) from the first IFD of a TIFF header.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// orientImage flips and rotates an image stored with an EXIF orientation so it is displayed upright.
+func orientImage(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	// orientations 5 to 8 swap the width and the height
+	if orientation >= 5 {
+		width, height = height, width
+	}
+	oriented := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	w, h := bounds.Dx(), bounds.Dy()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			oriented.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return oriented
+}
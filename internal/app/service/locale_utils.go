@@ -0,0 +1,41 @@
+package service
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// NormalizeLocale returns the lower-case base language of a locale tag, e.g. "en" for "en-US".
+// An empty string is returned when the tag cannot be parsed.
+func NormalizeLocale(locale string) string {
+	tag, err := language.Parse(strings.TrimSpace(locale))
+	if err != nil {
+		return ""
+	}
+
+	base, confidence := tag.Base()
+	if confidence == language.No {
+		return ""
+	}
+	return base.String()
+}
+
+// ParseAcceptLanguage returns the most preferred locale of an Accept-Language header value.
+func ParseAcceptLanguage(header string) string {
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return ""
+	}
+
+	for _, tag := range tags {
+		// the "*" wildcard is parsed as "mul" and does not select a locale
+		if tag == language.Make("mul") {
+			continue
+		}
+		if locale := NormalizeLocale(tag.String()); locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
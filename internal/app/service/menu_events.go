@@ -0,0 +1,359 @@
+// internal/service/menu_events.go
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"maqhaa/library/logging"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/repository"
+	"maqhaa/product_service/internal/config"
+	"sync"
+	"time"
+)
+
+const (
+	// menuEventBatchSize is the number of events read from the outbox at a time.
+	menuEventBatchSize = 500
+	// menuEventLateTimeout is how long the events passed after the gap timeout are still looked up, so
+	// the events of a transaction committing late are delivered late rather than never.
+	menuEventLateTimeout = 10 * time.Minute
+	// menuEventMaxSkipped bounds the number of events passed after the gap timeout that are looked up.
+	menuEventMaxSkipped = 1000
+)
+
+// domainMenuEventTypes are the types of the menu events of the domain events recorded without one.
+var domainMenuEventTypes = map[string]string{
+	entity.EventProductCreated:     model.MenuProductCreated,
+	entity.EventProductUpdated:     model.MenuProductUpdated,
+	entity.EventProductDeactivated: model.MenuProductDeactivated,
+	entity.EventCategoryChanged:    model.MenuCategoryUpdated,
+}
+
+// MenuEvents delivers the changes of the menu of every client to the watchers of that menu. The changes
+// are the events of the outbox, recorded in the transaction of each write by any instance, and their
+// sequence is the ID of the event, so a watcher reconnecting to another instance resumes where it was.
+type MenuEvents interface {
+	// Watch starts watching the menu of a client, resuming after an event when afterSequence is not 0.
+	Watch(ctx context.Context, clientID uint, afterSequence uint64) (*MenuWatch, error)
+	// WakeMenuEvents starts a delivery as soon as the events recorded by this instance are committed.
+	WakeMenuEvents()
+	// DeliverMenuEvents delivers the events recorded in the outbox since the last delivery, and returns
+	// how many were delivered.
+	DeliverMenuEvents(ctx context.Context) (int, error)
+	RunMenuEvents(ctx context.Context)
+}
+
+// MenuWatch receives the events of a menu delivered after the watch started.
+type MenuWatch struct {
+	// Sequence is the sequence of the last event delivered before the watch started.
+	Sequence uint64
+	// Resumed reports whether the events after the sequence the watch resumed from are all still kept,
+	// in which case they are in Missed. Otherwise the watcher needs a new snapshot of the menu.
+	Resumed bool
+	Missed  []model.MenuEvent
+	// Events is closed when the watch is stopped, or when the watcher falls behind the events.
+	Events <-chan model.MenuEvent
+
+	events  chan model.MenuEvent
+	lagging bool
+	stop    func()
+}
+
+// Stop stops the watch and closes its events.
+func (w *MenuWatch) Stop() {
+	w.stop()
+}
+
+// Lagging reports whether the watch was stopped because the watcher fell behind the events.
+func (w *MenuWatch) Lagging() bool {
+	return w.lagging
+}
+
+type menuEventsImpl struct {
+	outboxRepository repository.OutboxRepository
+	config           config.MenuEventsConfig
+	// wake starts a delivery as soon as events are committed.
+	wake chan struct{}
+	// delivery runs one delivery at a time.
+	delivery sync.Mutex
+	// gapSince is when the delivery first waited for an event not committed before the next one.
+	gapSince time.Time
+	// skipped are the IDs passed after the gap timeout, and when they were passed.
+	skipped map[uint]time.Time
+
+	mutex   sync.Mutex
+	started bool
+	// cursor is the ID of the last event delivered; every event before it is delivered, or skipped.
+	cursor  uint
+	watches map[uint]map[*MenuWatch]bool
+}
+
+// NewMenuEvents creates a new MenuEvents instance reading the events from the outbox. Settings missing
+// from the configuration get the defaults of config-prod.yaml.
+func NewMenuEvents(outboxRepository repository.OutboxRepository, menuEventsConfig config.MenuEventsConfig) MenuEvents {
+	if menuEventsConfig.Interval <= 0 {
+		menuEventsConfig.Interval = time.Second
+	}
+	if menuEventsConfig.GapTimeout <= 0 {
+		menuEventsConfig.GapTimeout = 5 * time.Second
+	}
+	if menuEventsConfig.Buffer < 1 {
+		menuEventsConfig.Buffer = 1
+	}
+
+	return &menuEventsImpl{
+		outboxRepository: outboxRepository,
+		config:           menuEventsConfig,
+		wake:             make(chan struct{}, 1),
+		skipped:          map[uint]time.Time{},
+		watches:          map[uint]map[*MenuWatch]bool{},
+	}
+}
+
+func (m *menuEventsImpl) Watch(ctx context.Context, clientID uint, afterSequence uint64) (*MenuWatch, error) {
+	if err := m.start(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	watch := &MenuWatch{
+		Sequence: uint64(m.cursor),
+		events:   make(chan model.MenuEvent, m.config.Buffer),
+	}
+	watch.Events = watch.events
+	watch.stop = func() {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		if m.watches[clientID][watch] {
+			delete(m.watches[clientID], watch)
+			if len(m.watches[clientID]) == 0 {
+				delete(m.watches, clientID)
+			}
+			close(watch.events)
+		}
+	}
+	if m.watches[clientID] == nil {
+		m.watches[clientID] = map[*MenuWatch]bool{}
+	}
+	m.watches[clientID][watch] = true
+	m.mutex.Unlock()
+
+	// an unknown sequence, or one older than the events kept, gets a snapshot
+	if afterSequence == 0 || afterSequence > watch.Sequence {
+		return watch, nil
+	}
+	if afterSequence == watch.Sequence {
+		watch.Resumed = true
+		return watch, nil
+	}
+
+	firstID, _, err := m.outboxRepository.GetOutboxEventIDRange(ctx)
+	if err != nil {
+		watch.Stop()
+		return nil, err
+	}
+	if firstID == 0 || uint64(firstID) > afterSequence {
+		return watch, nil
+	}
+
+	// the events delivered after the watch started are in its events
+	events, err := m.outboxRepository.GetClientOutboxEvents(ctx, clientID, uint(afterSequence), uint(watch.Sequence), m.config.History+1)
+	if err != nil {
+		watch.Stop()
+		return nil, err
+	}
+	if len(events) > m.config.History {
+		return watch, nil
+	}
+
+	watch.Resumed = true
+	for _, event := range events {
+		watch.Missed = append(watch.Missed, newOutboxMenuEvent(event))
+	}
+	return watch, nil
+}
+
+func (m *menuEventsImpl) WakeMenuEvents() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// DeliverMenuEvents delivers the events in the order of their IDs. IDs are given when an event is recorded,
+// but the transactions recording them may commit in another order, so the events after an ID not committed
+// yet are held back until it is, or until the gap timeout, when the ID is passed: the transaction rolled back,
+// or is taking long. An event of a passed ID committing later is delivered late, out of order.
+func (m *menuEventsImpl) DeliverMenuEvents(ctx context.Context) (int, error) {
+	m.delivery.Lock()
+	defer m.delivery.Unlock()
+
+	if err := m.start(ctx); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	late, err := m.deliverSkipped(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	events, err := m.outboxRepository.GetOutboxEventsAfter(ctx, m.cursor, menuEventBatchSize)
+	if err != nil {
+		return late, err
+	}
+
+	cursor := m.cursor
+	ready := []entity.OutboxEvent{}
+	for _, event := range events {
+		if event.ID != cursor+1 {
+			if m.gapSince.IsZero() {
+				m.gapSince = now
+			}
+			if now.Sub(m.gapSince) < m.config.GapTimeout {
+				break
+			}
+			m.skip(cursor+1, event.ID, now)
+		}
+		m.gapSince = time.Time{}
+		cursor = event.ID
+		ready = append(ready, event)
+	}
+
+	m.deliver(ready, cursor)
+	return late + len(ready), nil
+}
+
+// RunMenuEvents delivers the events every interval, and as soon as events are committed, until the context
+// is done.
+func (m *menuEventsImpl) RunMenuEvents(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-m.wake:
+		}
+
+		// a full batch means more events are waiting
+		for {
+			delivered, err := m.DeliverMenuEvents(ctx)
+			if err != nil {
+				logging.Log.Errorf("Error DeliverMenuEvents %s", err.Error())
+				break
+			}
+			if delivered < menuEventBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// start delivers the events recorded after the first use of the instance.
+func (m *menuEventsImpl) start(ctx context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.started {
+		return nil
+	}
+
+	_, lastID, err := m.outboxRepository.GetOutboxEventIDRange(ctx)
+	if err != nil {
+		return err
+	}
+	m.cursor = lastID
+	m.started = true
+	return nil
+}
+
+// skip passes the IDs from and up to before to, which are looked up again by the next deliveries.
+func (m *menuEventsImpl) skip(from uint, to uint, now time.Time) {
+	for ID := from; ID < to && len(m.skipped) < menuEventMaxSkipped; ID++ {
+		m.skipped[ID] = now
+	}
+}
+
+// deliverSkipped delivers the events of the IDs passed that are committed now, and stops looking up the
+// IDs passed longer than menuEventLateTimeout ago.
+func (m *menuEventsImpl) deliverSkipped(ctx context.Context, now time.Time) (int, error) {
+	if len(m.skipped) == 0 {
+		return 0, nil
+	}
+
+	IDs := make([]uint, 0, len(m.skipped))
+	for ID, skippedAt := range m.skipped {
+		if now.Sub(skippedAt) > menuEventLateTimeout {
+			delete(m.skipped, ID)
+			continue
+		}
+		IDs = append(IDs, ID)
+	}
+
+	events, err := m.outboxRepository.GetOutboxEventsByIDs(ctx, IDs)
+	if err != nil {
+		return 0, err
+	}
+	for _, event := range events {
+		delete(m.skipped, event.ID)
+	}
+
+	m.deliver(events, m.cursor)
+	return len(events), nil
+}
+
+// deliver sends the events to the watchers of their clients and moves the cursor. A watcher whose events
+// are full is stopped as lagging.
+func (m *menuEventsImpl) deliver(events []entity.OutboxEvent, cursor uint) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, event := range events {
+		menuEvent := newOutboxMenuEvent(event)
+		for watch := range m.watches[menuEvent.ClientID] {
+			select {
+			case watch.events <- menuEvent:
+			default:
+				watch.lagging = true
+				delete(m.watches[menuEvent.ClientID], watch)
+				close(watch.events)
+			}
+		}
+		if len(m.watches[menuEvent.ClientID]) == 0 {
+			delete(m.watches, menuEvent.ClientID)
+		}
+	}
+	m.cursor = cursor
+}
+
+// newOutboxMenuEvent returns the menu event of an event of the outbox, whose sequence is the ID of the event.
+func newOutboxMenuEvent(event entity.OutboxEvent) model.MenuEvent {
+	menuEvent := model.MenuEvent{
+		Sequence:  uint64(event.ID),
+		Type:      event.MenuEventType,
+		ClientID:  event.ClientID,
+		CreatedAt: event.CreatedAt,
+	}
+	if menuEvent.Type == "" {
+		menuEvent.Type = domainMenuEventTypes[event.Type]
+	}
+
+	switch event.AggregateType {
+	case entity.AggregateProduct:
+		var product struct {
+			CategoryID uint `json:"categoryId"`
+		}
+		// the category is only informative; the product is loaded again when the event is sent
+		_ = json.Unmarshal([]byte(event.Payload), &product)
+		menuEvent.ProductID = event.AggregateID
+		menuEvent.CategoryID = product.CategoryID
+	case entity.AggregateCategory:
+		menuEvent.CategoryID = event.AggregateID
+	}
+	return menuEvent
+}
@@ -0,0 +1,130 @@
+// internal/service/menu_service.go
+
+package service
+
+import (
+	"context"
+	"errors"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WatchMenu sends the changes of the menu of the client of the token until ctx is done or send fails.
+// The watch starts with a snapshot of the menu, or with the events missed since the sequence the request
+// resumes from. A watcher falling behind the events is stopped with a watch lagging error, and catches up
+// by watching again after the last event it received.
+func (s *productServiceImpl) WatchMenu(ctx context.Context, request *model.WatchMenuRequest, send func(event *model.MenuEvent) error) AppError {
+	client, err := s.productRepository.GetClientByToken(ctx, request.Token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return *NewInvalidTokenError()
+		}
+		return *NewQueryDBError()
+	}
+	if s.menuEvents == nil {
+		return *NewGeneralSystemError()
+	}
+
+	watch, err := s.menuEvents.Watch(ctx, client.ID, request.AfterSequence)
+	if err != nil {
+		return *NewQueryDBError()
+	}
+	defer watch.Stop()
+
+	if !watch.Resumed {
+		categories, appError := s.GetProductGroupsByCategory(ctx, request.Token, request.Locale)
+		// a client without categories has an empty menu
+		if appError.Code == InvalidToken {
+			categories, appError = []entity.ProductCategory{}, *NewSuccessError()
+		}
+		if appError.Code != SuccessError {
+			return appError
+		}
+
+		snapshot := &model.MenuEvent{Sequence: watch.Sequence, Type: model.MenuSnapshot, ClientID: client.ID, CreatedAt: time.Now(), Categories: categories}
+		if err := send(snapshot); err != nil {
+			return *NewGeneralSystemError()
+		}
+	}
+
+	for _, event := range watch.Missed {
+		if err := send(s.loadMenuEvent(ctx, event, request)); err != nil {
+			return *NewGeneralSystemError()
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return *NewSuccessError()
+		case event, ok := <-watch.Events:
+			if !ok {
+				if watch.Lagging() {
+					return *NewWatchLaggingError()
+				}
+				return *NewSuccessError()
+			}
+			if err := send(s.loadMenuEvent(ctx, event, request)); err != nil {
+				return *NewGeneralSystemError()
+			}
+		}
+	}
+}
+
+// loadMenuEvent adds the product or category of an event, as it is now and in the locale of the watcher.
+func (s *productServiceImpl) loadMenuEvent(ctx context.Context, event model.MenuEvent, request *model.WatchMenuRequest) *model.MenuEvent {
+	switch {
+	case event.ProductID != 0:
+		if product, appError := s.GetProductByID(ctx, event.ProductID, request.Token, request.Locale); appError.Code == SuccessError {
+			event.Product = product
+		}
+	case event.CategoryID != 0:
+		if category, appError := s.GetProductCategoryByID(ctx, event.CategoryID, request.Token, request.Locale); appError.Code == SuccessError {
+			event.Category = category
+		}
+	}
+	return &event
+}
+
+// publishMenuEvents records the menu events of the writes of a transaction, whose webhooks are queued
+// by inTransaction. The watchers of the menu get the events from the outbox instead, where the
+// repositories record them with each write.
+func (s *productServiceImpl) publishMenuEvents(events ...model.MenuEvent) {
+	*s.pendingMenuEvents = append(*s.pendingMenuEvents, events...)
+}
+
+// notifyMenuEvents starts the delivery of the menu events of a committed transaction to the watchers
+// of the menu, and of the webhooks queued with them.
+func (s *productServiceImpl) notifyMenuEvents(events []model.MenuEvent) {
+	if len(events) == 0 {
+		return
+	}
+	if s.menuEvents != nil {
+		s.menuEvents.WakeMenuEvents()
+	}
+	if s.webhooks != nil {
+		s.webhooks.WakeWebhookDelivery()
+	}
+}
+
+// newProductEvent returns a menu event of a product of a client.
+func newProductEvent(eventType string, clientID uint, product *entity.Product) model.MenuEvent {
+	return model.MenuEvent{Type: eventType, ClientID: clientID, ProductID: product.ID, CategoryID: product.CategoryID}
+}
+
+// newProductChangeEvent returns the menu event of a changed product: an availability event when the
+// product was activated or deactivated, otherwise an update.
+func newProductChangeEvent(clientID uint, previous *entity.Product, product *entity.Product) model.MenuEvent {
+	if previous.IsActive != product.IsActive {
+		return newProductEvent(model.MenuProductAvailability, clientID, product)
+	}
+	return newProductEvent(model.MenuProductUpdated, clientID, product)
+}
+
+// newCategoryEvent returns a menu event of a category.
+func newCategoryEvent(eventType string, category *entity.ProductCategory) model.MenuEvent {
+	return model.MenuEvent{Type: eventType, ClientID: category.ClientID, CategoryID: category.ID}
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"fmt"
+	"maqhaa/product_service/internal/app/model"
+	"strings"
+)
+
+// DefaultLanguage is the language of the messages defined in errors_utils.go.
+const DefaultLanguage = "en"
+
+// messageCatalogue holds the translations of the error messages keyed by error code and locale.
+// Messages with a "%s" verb carry a detail that is kept as is when translating.
+var messageCatalogue = map[int]map[string]string{
+	SuccessError: {
+		"en": SuccessMessage,
+		"id": "Sukses",
+	},
+	GenaralSystemError: {
+		"en": GenaralSystemErrorMessage,
+		"id": "Kesalahan Sistem Umum",
+	},
+	InvalidUsername: {
+		"en": InvalidUsernameMessage,
+		"id": "Pengguna tidak ditemukan",
+	},
+	InvalidPassword: {
+		"en": InvalidPasswordMessage,
+		"id": "Kata Sandi Tidak Valid",
+	},
+	InvalidFormatError: {
+		"en": InvalidFormatErrorMessage,
+		"id": "Format Permintaan Tidak Valid",
+	},
+	InvalidToken: {
+		"en": InvalidTokendMessage,
+		"id": "Token Tidak Valid",
+	},
+	InvalidRequestError: {
+		"en": InvalidRequestMessage,
+		"id": "Permintaan Tidak Valid %s",
+	},
+	ProductNotFound: {
+		"en": ProductNotFoundMessage,
+		"id": "Produk Tidak Ditemukan",
+	},
+	InvalidProductPrice: {
+		"en": InvalidProductPriceMessage,
+		"id": "Harga Produk Tidak Valid",
+	},
+	InvalidTotal: {
+		"en": InvalidTotalMessage,
+		"id": "Total Tidak Valid",
+	},
+	ImageTooLarge: {
+		"en": ImageTooLargeMessage,
+		"id": "Gambar Terlalu Besar %s",
+	},
+	InvalidImageDimensions: {
+		"en": InvalidImageDimensionsMessage,
+		"id": "Dimensi Gambar Tidak Valid %s",
+	},
+	UnsupportedImageFormat: {
+		"en": UnsupportedImageFormatMessage,
+		"id": "Format Gambar Tidak Didukung %s",
+	},
+	InvalidImage: {
+		"en": InvalidImageMessage,
+		"id": "Gambar Tidak Valid",
+	},
+	VersionRequired: {
+		"en": VersionRequiredMessage,
+		"id": "Versi Wajib Diisi",
+	},
+	QueryError: {
+		"en": QueryErrorMessage,
+		"id": "Gagal membaca database",
+	},
+	UpdateQueryError: {
+		"en": UpdateQueryErrorMessage,
+		"id": "Gagal memperbarui database",
+	},
+	DateCategoryNotFound: {
+		"en": DateCategoryNotFoundMessage,
+		"id": "Data Tidak Ditemukan",
+	},
+	TranslationNotFound: {
+		"en": TranslationNotFoundMessage,
+		"id": "Terjemahan Tidak Ditemukan",
+	},
+	ProductImageNotFound: {
+		"en": ProductImageNotFoundMessage,
+		"id": "Gambar Produk Tidak Ditemukan",
+	},
+	ImageNotFound: {
+		"en": ImageNotFoundMessage,
+		"id": "Gambar Tidak Ditemukan",
+	},
+	VersionConflict: {
+		"en": VersionConflictMessage,
+		"id": "Konflik Versi",
+	},
+	IdempotencyKeyConflict: {
+		"en": IdempotencyKeyConflictMessage,
+		"id": "Konflik Idempotency Key",
+	},
+	RequestInProgress: {
+		"en": RequestInProgressMessage,
+		"id": "Permintaan Sedang Diproses",
+	},
+	WatchLagging: {
+		"en": WatchLaggingMessage,
+		"id": "Pemantauan Tertinggal",
+	},
+	WebhookNotFound: {
+		"en": WebhookNotFoundMessage,
+		"id": "Webhook Tidak Ditemukan",
+	},
+	WebhookDeliveryNotFound: {
+		"en": WebhookDeliveryNotFoundMessage,
+		"id": "Pengiriman Webhook Tidak Ditemukan",
+	},
+}
+
+// fieldMessageCatalogue holds the per-field validation messages keyed by validation rule and locale.
+// {field} and {param} are replaced by the field name and the rule parameter.
+var fieldMessageCatalogue = map[string]map[string]string{
+	"required": {
+		"en": "{field} is required",
+		"id": "{field} wajib diisi",
+	},
+	"required_without": {
+		"en": "{field} is required when {param} is empty",
+		"id": "{field} wajib diisi jika {param} kosong",
+	},
+	"min": {
+		"en": "{field} must be at least {param}",
+		"id": "{field} minimal {param}",
+	},
+	"max": {
+		"en": "{field} must be at most {param}",
+		"id": "{field} maksimal {param}",
+	},
+	"gt": {
+		"en": "{field} must be greater than {param}",
+		"id": "{field} harus lebih besar dari {param}",
+	},
+	"gte": {
+		"en": "{field} must be greater than or equal to {param}",
+		"id": "{field} harus lebih besar atau sama dengan {param}",
+	},
+	"oneof": {
+		"en": "{field} must be one of {param}",
+		"id": "{field} harus salah satu dari {param}",
+	},
+	"http_url": {
+		"en": "{field} must be an http or https URL",
+		"id": "{field} harus berupa URL http atau https",
+	},
+	"https_url": {
+		"en": "{field} must be an https URL",
+		"id": "{field} harus berupa URL https",
+	},
+	"numeric": {
+		"en": "{field} must be a number",
+		"id": "{field} harus berupa angka",
+	},
+	"unique": {
+		"en": "{field} is repeated",
+		"id": "{field} berulang",
+	},
+	"": {
+		"en": "{field} is invalid",
+		"id": "{field} tidak valid",
+	},
+}
+
+// LocalizeMessage translates the message of an error code into the locale.
+// The message is returned unchanged when the code or locale has no translation.
+func LocalizeMessage(code int, message string, locale string) string {
+	messages, ok := messageCatalogue[code]
+	if !ok {
+		return message
+	}
+
+	localized, ok := messages[NormalizeLocale(locale)]
+	if !ok {
+		return message
+	}
+
+	prefix, suffix, hasDetail := strings.Cut(messages[DefaultLanguage], "%s")
+	if !hasDetail {
+		return localized
+	}
+
+	prefix = strings.TrimSpace(prefix)
+	if !strings.HasPrefix(message, prefix) || !strings.HasSuffix(message, suffix) {
+		return message
+	}
+	detail := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(message, prefix), suffix))
+	return strings.TrimSpace(fmt.Sprintf(localized, detail))
+}
+
+// LocalizeFieldErrors translates the messages of validation errors into the locale.
+func LocalizeFieldErrors(fieldErrors []model.FieldError, locale string) []model.FieldError {
+	locale = NormalizeLocale(locale)
+	if locale == "" {
+		return fieldErrors
+	}
+
+	localized := make([]model.FieldError, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		fieldError.Message = fieldErrorMessage(fieldError.Field, fieldError.Rule, fieldError.Param, locale)
+		localized = append(localized, fieldError)
+	}
+	return localized
+}
+
+func fieldErrorMessage(field string, rule string, param string, locale string) string {
+	messages, ok := fieldMessageCatalogue[rule]
+	if !ok {
+		messages = fieldMessageCatalogue[""]
+	}
+
+	template, ok := messages[locale]
+	if !ok {
+		template = messages[DefaultLanguage]
+	}
+	return strings.NewReplacer("{field}", field, "{param}", param).Replace(template)
+}
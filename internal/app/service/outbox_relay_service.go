@@ -0,0 +1,147 @@
+// internal/service/outbox_relay_service.go
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maqhaa/library/logging"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/repository"
+	"maqhaa/product_service/internal/broker"
+	"strconv"
+	"time"
+)
+
+const (
+	// outboxBatchSize is the number of events relayed at a time when no batch size is set.
+	outboxBatchSize = 100
+	// outboxPurgeInterval is how often the relay removes the events published longer than the retention ago.
+	outboxPurgeInterval = time.Hour
+)
+
+// OutboxRelayService publishes the domain events recorded in the outbox to the broker.
+type OutboxRelayService interface {
+	RelayEvents(ctx context.Context, batchSize int) (int, error)
+	RunOutboxRelay(ctx context.Context, interval time.Duration, batchSize int, retention time.Duration)
+}
+
+type outboxRelayServiceImpl struct {
+	unitOfWork       repository.UnitOfWork
+	outboxRepository repository.OutboxRepository
+	broker           broker.Broker
+}
+
+// NewOutboxRelayService creates a new OutboxRelayService instance.
+func NewOutboxRelayService(unitOfWork repository.UnitOfWork, outboxRepository repository.OutboxRepository, messageBroker broker.Broker) OutboxRelayService {
+	return &outboxRelayServiceImpl{
+		unitOfWork:       unitOfWork,
+		outboxRepository: outboxRepository,
+		broker:           messageBroker,
+	}
+}
+
+// RelayEvents publishes up to batchSize unpublished events, oldest first, and returns how many were
+// published. Relaying stops at the first event the broker does not accept, so that the events are
+// published in order; that event and the following ones are published by the next relay.
+// An event published but not marked published, because the transaction did not commit, is published again.
+func (s *outboxRelayServiceImpl) RelayEvents(ctx context.Context, batchSize int) (int, error) {
+	var published []uint
+	var publishError error
+	if batchSize <= 0 {
+		batchSize = outboxBatchSize
+	}
+
+	err := s.unitOfWork.Do(ctx, func(repositories *repository.Repositories) error {
+		published, publishError = nil, nil
+		events, err := repositories.Outbox.LockUnpublishedOutboxEvents(ctx, batchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			message, err := newDomainEventMessage(event)
+			if err != nil {
+				publishError = fmt.Errorf("event %d: %v", event.ID, err)
+				break
+			}
+			if err := s.broker.Publish(ctx, message); err != nil {
+				publishError = fmt.Errorf("event %d: %v", event.ID, err)
+				break
+			}
+			published = append(published, event.ID)
+		}
+		return repositories.Outbox.MarkOutboxEventsPublished(ctx, published, time.Now())
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(published), publishError
+}
+
+// RunOutboxRelay relays the events every interval until the context is done, and removes the events
+// published longer than retention ago, unless retention is zero.
+func (s *outboxRelayServiceImpl) RunOutboxRelay(ctx context.Context, interval time.Duration, batchSize int, retention time.Duration) {
+	if batchSize <= 0 {
+		batchSize = outboxBatchSize
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	purge := time.NewTicker(outboxPurgeInterval)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// a full batch means more events are waiting
+			for {
+				published, err := s.RelayEvents(ctx, batchSize)
+				if err != nil {
+					logging.Log.Errorf("Error RelayEvents after %d events: %s", published, err.Error())
+					break
+				}
+				if published < batchSize {
+					break
+				}
+			}
+		case <-purge.C:
+			if retention <= 0 {
+				continue
+			}
+			deleted, err := s.outboxRepository.DeletePublishedOutboxEvents(ctx, time.Now().Add(-retention))
+			if err != nil {
+				logging.Log.Errorf("Error DeletePublishedOutboxEvents %s", err.Error())
+				continue
+			}
+			logging.Log.Infof("Outbox purge removed %d published events", deleted)
+		}
+	}
+}
+
+// newDomainEventMessage returns the broker message of an event of the outbox, keyed by its aggregate so
+// the events of a product, or of a category, stay in order.
+func newDomainEventMessage(event entity.OutboxEvent) (broker.Message, error) {
+	body, err := json.Marshal(model.DomainEvent{
+		ID:            strconv.FormatUint(uint64(event.ID), 10),
+		Type:          event.Type,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		ClientID:      event.ClientID,
+		OccurredAt:    event.CreatedAt,
+		Data:          json.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return broker.Message{}, err
+	}
+
+	return broker.Message{
+		ID:      strconv.FormatUint(uint64(event.ID), 10),
+		Subject: event.Type,
+		Key:     fmt.Sprintf("%s-%d", event.AggregateType, event.AggregateID),
+		Body:    body,
+	}, nil
+}
@@ -0,0 +1,409 @@
+// internal/service/product_image_service.go
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// imageDetails holds what is stored alongside the images of products, keyed by image name.
+type imageDetails struct {
+	renditions map[string][]entity.ImageRendition
+	blobs      map[string]entity.ImageBlob
+}
+
+// setImageURLs fills the public URLs, the renditions and the placeholders of the primary image and of the gallery of a product.
+func (s *productServiceImpl) setImageURLs(product *entity.Product, details *imageDetails) {
+	product.ImageURL = s.imageRepository.GetURL(product.Image)
+	product.ImageSrcSet = s.setRenditionURLs(append([]entity.ImageRendition{}, details.renditions[product.Image]...))
+	product.ImageBlurHash = details.blobs[product.Image].BlurHash
+	product.ImageDominantColor = details.blobs[product.Image].DominantColor
+	for i := range product.Images {
+		image := &product.Images[i]
+		image.URL = s.imageRepository.GetURL(image.FileName)
+		image.Renditions = append([]entity.ImageRendition{}, details.renditions[image.FileName]...)
+		image.SrcSet = s.setRenditionURLs(image.Renditions)
+		image.BlurHash = details.blobs[image.FileName].BlurHash
+		image.DominantColor = details.blobs[image.FileName].DominantColor
+	}
+}
+
+// getImageDetails returns the renditions and the placeholders of the images of the products.
+func (s *productServiceImpl) getImageDetails(ctx context.Context, products []*entity.Product) (*imageDetails, error) {
+	imageNames := []string{}
+	for _, product := range products {
+		if product.Image != "" {
+			imageNames = append(imageNames, product.Image)
+		}
+		for _, image := range product.Images {
+			imageNames = append(imageNames, image.FileName)
+		}
+	}
+
+	renditions, err := s.productImageRepository.GetImageRenditions(ctx, imageNames)
+	if err != nil {
+		return nil, err
+	}
+	blobs, err := s.productImageRepository.GetImageBlobs(ctx, imageNames)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &imageDetails{
+		renditions: map[string][]entity.ImageRendition{},
+		blobs:      map[string]entity.ImageBlob{},
+	}
+	for _, rendition := range renditions {
+		details.renditions[rendition.ImageName] = append(details.renditions[rendition.ImageName], rendition)
+	}
+	for _, blob := range blobs {
+		details.blobs[blob.Name] = blob
+	}
+	return details, nil
+}
+
+// setRenditionURLs fills the public URLs of the renditions and returns a srcset per format,
+// e.g. {"webp": "https://.../PR-1-thumbnail.webp 150w, https://.../PR-1-medium.webp 600w"}.
+func (s *productServiceImpl) setRenditionURLs(renditions []entity.ImageRendition) map[string]string {
+	sort.SliceStable(renditions, func(i, j int) bool {
+		return renditions[i].Width < renditions[j].Width
+	})
+
+	srcSet := map[string]string{}
+	for i := range renditions {
+		rendition := &renditions[i]
+		rendition.URL = s.imageRepository.GetURL(rendition.FileName)
+
+		candidate := fmt.Sprintf("%s %dw", rendition.URL, rendition.Width)
+		if srcSet[rendition.Format] != "" {
+			candidate = srcSet[rendition.Format] + ", " + candidate
+		}
+		srcSet[rendition.Format] = candidate
+	}
+	return srcSet
+}
+
+// releaseImage drops a reference to a stored image. The image and its renditions are removed
+// with the last reference, in a transaction once it commits.
+func (s *productServiceImpl) releaseImage(ctx context.Context, imageName string) error {
+	if imageName == "" {
+		return nil
+	}
+
+	remaining, err := s.productImageRepository.ReleaseImageBlob(ctx, imageName)
+	if err != nil || remaining > 0 {
+		return err
+	}
+
+	if err := s.productImageRepository.DeleteImageRenditions(ctx, imageName); err != nil {
+		return err
+	}
+	if s.imageChanges != nil {
+		s.imageChanges.removed = append(s.imageChanges.removed, imageName)
+		return nil
+	}
+	s.removeImages(ctx, []string{imageName})
+	return nil
+}
+
+// getGallery returns the gallery of a product. Products created before galleries existed only have
+// a primary image, which becomes the first image of the gallery.
+func (s *productServiceImpl) getGallery(ctx context.Context, product *entity.Product) ([]entity.ProductImage, error) {
+	images, err := s.productImageRepository.GetProductImages(ctx, product.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(images) == 0 && product.Image != "" {
+		image := entity.ProductImage{
+			ProductID: product.ID,
+			FileName:  product.Image,
+			IsPrimary: true,
+		}
+		if err := s.productImageRepository.AddProductImage(ctx, &image); err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+// setPrimaryImage makes the image the primary one of the gallery. The image of the product is set with
+// the version, by setGalleryVersion.
+func (s *productServiceImpl) setPrimaryImage(ctx context.Context, image *entity.ProductImage) error {
+	if err := s.productImageRepository.SetPrimaryProductImage(ctx, image.ProductID, image.ID); err != nil {
+		return err
+	}
+	image.IsPrimary = true
+	return nil
+}
+
+// setGalleryVersion records a change of the gallery of a product, made from the version read: the image
+// of the product is set to the primary image of the gallery, and the version is incremented. A product
+// changed since it was read is a version conflict.
+func (s *productServiceImpl) setGalleryVersion(ctx context.Context, product *entity.Product, primaryImage string) AppError {
+	if err := s.productRepository.SetProductImage(ctx, product.ID, product.Version, primaryImage); err != nil {
+		return newVersionedUpdateError(err)
+	}
+	return *NewSuccessError()
+}
+
+// AddProductImageService adds an image at the end of the gallery of a product.
+// The first image of a gallery always becomes the primary image.
+func (s *productServiceImpl) AddProductImageService(ctx context.Context, request *model.ProductImageRequest, token string) (*entity.ProductImage, AppError) {
+	validate := newValidator()
+	if err := validate.Struct(request); err != nil {
+		return nil, *NewValidationError(err)
+	}
+
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return nil, *NewInvalidTokenError()
+	}
+
+	product, appError := s.getClientProduct(ctx, request.ProductID, token, user)
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	if !matchesVersion(request.Version, product.Version) {
+		return nil, *NewVersionConflictError()
+	}
+
+	images, err := s.getGallery(ctx, product)
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	image := &entity.ProductImage{
+		ProductID: product.ID,
+		AltText:   request.AltText,
+		Position:  len(images),
+	}
+	appError = s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		fileName, appError := tx.resolveImage(ctx, request.Image, request.ImageID, user)
+		if appError.Code != SuccessError {
+			return appError
+		}
+
+		image.FileName = fileName
+		if err := tx.productImageRepository.AddProductImage(ctx, image); err != nil {
+			return *NewUpdateQueryDBError()
+		}
+
+		primaryImage := product.Image
+		if request.IsPrimary || len(images) == 0 {
+			if err := tx.setPrimaryImage(ctx, image); err != nil {
+				return *NewUpdateQueryDBError()
+			}
+			primaryImage = image.FileName
+		}
+		if appError := tx.setGalleryVersion(ctx, product, primaryImage); appError.Code != SuccessError {
+			return appError
+		}
+
+		tx.publishMenuEvents(newProductEvent(model.MenuProductUpdated, uint(user.ClientId), product))
+		return *NewSuccessError()
+	})
+	if appError.Code != SuccessError {
+		return nil, appError
+	}
+	request.Version = product.Version + 1
+
+	renditions, err := s.productImageRepository.GetImageRenditions(ctx, []string{image.FileName})
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	image.URL = s.imageRepository.GetURL(image.FileName)
+	image.Renditions = renditions
+	image.SrcSet = s.setRenditionURLs(image.Renditions)
+	return image, *NewSuccessError()
+}
+
+// EditProductImageService updates the alt text of a gallery image and can make it the primary image.
+func (s *productServiceImpl) EditProductImageService(ctx context.Context, request *model.EditProductImageRequest, token string) AppError {
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return *NewInvalidTokenError()
+	}
+
+	product, appError := s.getClientProduct(ctx, request.ProductID, token, user)
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	if !matchesVersion(request.Version, product.Version) {
+		return *NewVersionConflictError()
+	}
+
+	if _, err := s.getGallery(ctx, product); err != nil {
+		return *NewQueryDBError()
+	}
+
+	image, err := s.productImageRepository.GetProductImageByID(ctx, product.ID, request.ImageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return *NewProductImageNotFoundError()
+		}
+		return *NewQueryDBError()
+	}
+
+	appError = s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		image.AltText = request.AltText
+		if err := tx.productImageRepository.EditProductImage(ctx, image); err != nil {
+			return *NewUpdateQueryDBError()
+		}
+
+		primaryImage := product.Image
+		if request.IsPrimary && !image.IsPrimary {
+			if err := tx.setPrimaryImage(ctx, image); err != nil {
+				return *NewUpdateQueryDBError()
+			}
+			primaryImage = image.FileName
+		}
+		if appError := tx.setGalleryVersion(ctx, product, primaryImage); appError.Code != SuccessError {
+			return appError
+		}
+
+		tx.publishMenuEvents(newProductEvent(model.MenuProductUpdated, uint(user.ClientId), product))
+		return *NewSuccessError()
+	})
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	request.Version = product.Version + 1
+	return *NewSuccessError()
+}
+
+// RemoveProductImageService removes an image from the gallery of a product.
+// When the primary image is removed, the next image of the gallery becomes primary.
+func (s *productServiceImpl) RemoveProductImageService(ctx context.Context, request *model.RemoveProductImageRequest, token string) AppError {
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return *NewInvalidTokenError()
+	}
+
+	product, appError := s.getClientProduct(ctx, request.ProductID, token, user)
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	if !matchesVersion(request.Version, product.Version) {
+		return *NewVersionConflictError()
+	}
+
+	if _, err := s.getGallery(ctx, product); err != nil {
+		return *NewQueryDBError()
+	}
+
+	image, err := s.productImageRepository.GetProductImageByID(ctx, product.ID, request.ImageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return *NewProductImageNotFoundError()
+		}
+		return *NewQueryDBError()
+	}
+
+	appError = s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		if err := tx.productImageRepository.DeleteProductImage(ctx, image.ID); err != nil {
+			return *NewUpdateQueryDBError()
+		}
+		tx.publishMenuEvents(newProductEvent(model.MenuProductUpdated, uint(user.ClientId), product))
+
+		if err := tx.releaseImage(ctx, image.FileName); err != nil {
+			return *NewUpdateQueryDBError()
+		}
+
+		primaryImage := product.Image
+		if image.IsPrimary {
+			images, err := tx.productImageRepository.GetProductImages(ctx, product.ID)
+			if err != nil {
+				return *NewQueryDBError()
+			}
+
+			primaryImage = ""
+			if len(images) > 0 {
+				if err := tx.setPrimaryImage(ctx, &images[0]); err != nil {
+					return *NewUpdateQueryDBError()
+				}
+				primaryImage = images[0].FileName
+			}
+		}
+
+		return tx.setGalleryVersion(ctx, product, primaryImage)
+	})
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	request.Version = product.Version + 1
+	return *NewSuccessError()
+}
+
+// ReorderProductImagesService changes the order of the gallery. Every image of the product must be listed once.
+func (s *productServiceImpl) ReorderProductImagesService(ctx context.Context, request *model.ReorderProductImagesRequest, token string) AppError {
+	validate := newValidator()
+	if err := validate.Struct(request); err != nil {
+		return *NewValidationError(err)
+	}
+
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return *NewInvalidTokenError()
+	}
+
+	product, appError := s.getClientProduct(ctx, request.ProductID, token, user)
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	if !matchesVersion(request.Version, product.Version) {
+		return *NewVersionConflictError()
+	}
+
+	images, err := s.getGallery(ctx, product)
+	if err != nil {
+		return *NewQueryDBError()
+	}
+
+	remaining := map[uint]bool{}
+	for _, image := range images {
+		remaining[image.ID] = true
+	}
+	for _, imageID := range request.ImageIDs {
+		if !remaining[imageID] {
+			return *NewInvalidRequestError("image_ids")
+		}
+		delete(remaining, imageID)
+	}
+	if len(remaining) > 0 {
+		return *NewInvalidRequestError("image_ids")
+	}
+
+	appError = s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		if err := tx.productImageRepository.ReorderProductImages(ctx, product.ID, request.ImageIDs); err != nil {
+			return *NewUpdateQueryDBError()
+		}
+		if appError := tx.setGalleryVersion(ctx, product, product.Image); appError.Code != SuccessError {
+			return appError
+		}
+
+		tx.publishMenuEvents(newProductEvent(model.MenuProductUpdated, uint(user.ClientId), product))
+		return *NewSuccessError()
+	})
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	request.Version = product.Version + 1
+	return *NewSuccessError()
+}
@@ -3,54 +3,101 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"image"
+	"io"
 	"maqhaa/library/helper"
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
 	exRepo "maqhaa/product_service/external/repository"
 	"maqhaa/product_service/internal/app/entity"
 	"maqhaa/product_service/internal/app/model"
 	"maqhaa/product_service/internal/app/repository"
-	"net/http"
-	"strings"
-	"time"
+	"maqhaa/product_service/internal/config"
 
-	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 // ProductService handles business logic related to products.
 type ProductService interface {
-	GetProductGroupsByCategory(ctx context.Context, token string) ([]entity.ProductCategory, AppError)
-	GetProductByID(ctx context.Context, ID uint, token string) (*entity.Product, AppError)
+	GetProductGroupsByCategory(ctx context.Context, token string, locale string) ([]entity.ProductCategory, AppError)
+	GetProductByID(ctx context.Context, ID uint, token string, locale string) (*entity.Product, AppError)
+	GetProductsByIDs(ctx context.Context, request *model.GetProductsRequest, token string, locale string) ([]entity.Product, []uint, AppError)
+	GetProductCategoryByID(ctx context.Context, ID uint, token string, locale string) (*entity.ProductCategory, AppError)
 	AddProductCategoryService(ctx context.Context, request *model.ProductCategoryRequest, token string) AppError
 	EditProductCategoryService(ctx context.Context, request *model.ProductCategoryRequest, token string) AppError
-	DeleteProductCategoryService(ctx context.Context, ID uint, token string) AppError
+	PatchProductCategoryService(ctx context.Context, request *model.ProductCategoryPatchRequest, token string) AppError
+	DeleteProductCategoryService(ctx context.Context, ID uint, version uint, token string) AppError
 	AddProductService(ctx context.Context, request *model.ProductRequest, token string) AppError
 	EditProductService(ctx context.Context, request *model.ProductRequest, token string) AppError
-	DeleteProductService(ctx context.Context, ID uint, token string) AppError
+	PatchProductService(ctx context.Context, request *model.ProductPatchRequest, token string) AppError
+	DeleteProductService(ctx context.Context, ID uint, version uint, token string) AppError
+	GetProductTranslationsService(ctx context.Context, productID uint, token string) ([]entity.ProductTranslation, AppError)
+	SaveProductTranslationService(ctx context.Context, request *model.ProductTranslationRequest, token string) AppError
+	DeleteProductTranslationService(ctx context.Context, productID uint, locale string, token string) AppError
+	GetCategoryTranslationsService(ctx context.Context, categoryID uint, token string) ([]entity.ProductCategoryTranslation, AppError)
+	SaveCategoryTranslationService(ctx context.Context, request *model.CategoryTranslationRequest, token string) AppError
+	DeleteCategoryTranslationService(ctx context.Context, categoryID uint, locale string, token string) AppError
+	AddProductImageService(ctx context.Context, request *model.ProductImageRequest, token string) (*entity.ProductImage, AppError)
+	EditProductImageService(ctx context.Context, request *model.EditProductImageRequest, token string) AppError
+	RemoveProductImageService(ctx context.Context, request *model.RemoveProductImageRequest, token string) AppError
+	ReorderProductImagesService(ctx context.Context, request *model.ReorderProductImagesRequest, token string) AppError
+	UploadImageService(ctx context.Context, file io.Reader, token string) (*entity.UploadedImage, AppError)
+	ImportCatalogueService(ctx context.Context, request *model.CatalogueImportRequest, token string) (*model.CatalogueImportReport, AppError)
+	ImportClientCatalogue(ctx context.Context, request *model.CatalogueImportRequest) (*model.CatalogueImportReport, AppError)
+	ExportCatalogueService(ctx context.Context, request *model.CatalogueExportRequest, token string) ([]model.CatalogueRow, AppError)
+	ExportClientCatalogue(ctx context.Context, request *model.CatalogueExportRequest) ([]model.CatalogueRow, AppError)
+	BulkUpdateProductsService(ctx context.Context, request *model.ProductBulkRequest, token string) (*model.ProductBulkReport, AppError)
+	CloneProductService(ctx context.Context, request *model.CloneProductRequest, token string) (*entity.Product, AppError)
+	CloneCategoryService(ctx context.Context, request *model.CloneCategoryRequest, token string) (*entity.ProductCategory, AppError)
+	CloneCatalogueService(ctx context.Context, request *model.CloneCatalogueRequest, token string) (*model.CloneCatalogueReport, AppError)
+	CloneClientCatalogue(ctx context.Context, request *model.CloneCatalogueRequest) (*model.CloneCatalogueReport, AppError)
+	WatchMenu(ctx context.Context, request *model.WatchMenuRequest, send func(event *model.MenuEvent) error) AppError
 }
 
 // productServiceImpl implements the ProductService interface
 type productServiceImpl struct {
-	productRepository repository.ProductRepository
-	userRepository    exRepo.UserRepository
-	imageRepository   repository.ImagesRepository
+	productRepository       repository.ProductRepository
+	userRepository          exRepo.UserRepository
+	imageRepository         repository.ImagesRepository
+	translationRepository   repository.TranslationRepository
+	productImageRepository  repository.ProductImageRepository
+	uploadedImageRepository repository.UploadedImageRepository
+	unitOfWork              repository.UnitOfWork
+	imageValidation         config.ImageValidationConfig
+	menuEvents              MenuEvents
+	webhooks                WebhookService
+	// imageChanges and pendingMenuEvents are set on the copies of the service running in a transaction,
+	// see inTransaction.
+	imageChanges      *imageChanges
+	pendingMenuEvents *[]model.MenuEvent
 }
 
 // NewProductService creates a new ProductService instance.
-func NewProductService(productRepository repository.ProductRepository, userRepository exRepo.UserRepository, imageRepository repository.ImagesRepository) ProductService {
+func NewProductService(productRepository repository.ProductRepository, userRepository exRepo.UserRepository, imageRepository repository.ImagesRepository, translationRepository repository.TranslationRepository, productImageRepository repository.ProductImageRepository, uploadedImageRepository repository.UploadedImageRepository, unitOfWork repository.UnitOfWork, imageValidation config.ImageValidationConfig, menuEvents MenuEvents, webhooks WebhookService) ProductService {
 	return &productServiceImpl{
-		productRepository: productRepository,
-		userRepository:    userRepository,
-		imageRepository:   imageRepository,
+		productRepository:       productRepository,
+		userRepository:          userRepository,
+		imageRepository:         imageRepository,
+		translationRepository:   translationRepository,
+		productImageRepository:  productImageRepository,
+		uploadedImageRepository: uploadedImageRepository,
+		unitOfWork:              unitOfWork,
+		imageValidation:         imageValidation,
+		menuEvents:              menuEvents,
+		webhooks:                webhooks,
 	}
 }
 
 // GetProductGroupsByCategory fetches product groups (categories with associated products),
-// grouped by category and filtered by token.
-func (s *productServiceImpl) GetProductGroupsByCategory(ctx context.Context, token string) ([]entity.ProductCategory, AppError) {
+// grouped by category and filtered by token. Names and descriptions are returned in the requested locale
+// when a translation exists, otherwise in the client's default locale.
+func (s *productServiceImpl) GetProductGroupsByCategory(ctx context.Context, token string, locale string) ([]entity.ProductCategory, AppError) {
 	if token == "" {
 		return nil, *NewInvalidTokenError()
 	}
@@ -72,10 +119,57 @@ func (s *productServiceImpl) GetProductGroupsByCategory(ctx context.Context, tok
 	if len(result) == 0 {
 		return nil, *NewInvalidTokenError()
 	}
+
+	if err := s.localizeCategories(ctx, token, locale, result); err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	products := []*entity.Product{}
+	for i := range result {
+		for j := range result[i].Products {
+			products = append(products, &result[i].Products[j])
+		}
+	}
+
+	details, err := s.getImageDetails(ctx, products)
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+	for _, product := range products {
+		s.setImageURLs(product, details)
+	}
 	return result, *NewSuccessError()
 }
 
-func (s *productServiceImpl) GetProductByID(ctx context.Context, ID uint, token string) (*entity.Product, AppError) {
+// GetProductCategoryByID returns a category of the client of the token, with its name in the locale.
+func (s *productServiceImpl) GetProductCategoryByID(ctx context.Context, ID uint, token string, locale string) (*entity.ProductCategory, AppError) {
+	client, err := s.productRepository.GetClientByToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, *NewInvalidTokenError()
+		}
+		return nil, *NewQueryDBError()
+	}
+
+	category, err := s.productRepository.GetProductCategoryByID(ctx, ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, *NewDateCategoryNotFoundError()
+		}
+		return nil, *NewQueryDBError()
+	}
+	if category.ClientID != client.ID {
+		return nil, *NewDateCategoryNotFoundError()
+	}
+
+	categories := []entity.ProductCategory{*category}
+	if err := s.localizeCategories(ctx, token, locale, categories); err != nil {
+		return nil, *NewQueryDBError()
+	}
+	return &categories[0], *NewSuccessError()
+}
+
+func (s *productServiceImpl) GetProductByID(ctx context.Context, ID uint, token string, locale string) (*entity.Product, AppError) {
 	product, err := s.productRepository.GetProductByID(ctx, ID, token)
 
 	if err != nil {
@@ -89,14 +183,75 @@ func (s *productServiceImpl) GetProductByID(ctx context.Context, ID uint, token
 		return nil, *NewProductNotFoundError()
 	}
 
+	if err := s.localizeProducts(ctx, token, locale, []*entity.Product{product}); err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	details, err := s.getImageDetails(ctx, []*entity.Product{product})
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+	s.setImageURLs(product, details)
+
 	return product, *NewSuccessError()
 }
 
+// GetProductsByIDs fetches the active products of the request, in the order of the request, with the IDs
+// of the products not found or inactive. Repeated IDs are only fetched once.
+func (s *productServiceImpl) GetProductsByIDs(ctx context.Context, request *model.GetProductsRequest, token string, locale string) ([]entity.Product, []uint, AppError) {
+	validate := newValidator()
+	if err := validate.Struct(request); err != nil {
+		return nil, nil, *NewValidationError(err)
+	}
+
+	found, err := s.productRepository.GetProductsByIDs(ctx, request.ProductIDs, token)
+	if err != nil {
+		return nil, nil, *NewQueryDBError()
+	}
+	byID := make(map[uint]*entity.Product, len(found))
+	for i := range found {
+		byID[found[i].ID] = &found[i]
+	}
+
+	products := []entity.Product{}
+	missing := []uint{}
+	seen := map[uint]bool{}
+	for _, ID := range request.ProductIDs {
+		if seen[ID] {
+			continue
+		}
+		seen[ID] = true
+		if product, ok := byID[ID]; ok && product.IsActive {
+			products = append(products, *product)
+		} else {
+			missing = append(missing, ID)
+		}
+	}
+
+	pointers := make([]*entity.Product, len(products))
+	for i := range products {
+		pointers[i] = &products[i]
+	}
+	if err := s.localizeProducts(ctx, token, locale, pointers); err != nil {
+		return nil, nil, *NewQueryDBError()
+	}
+
+	details, err := s.getImageDetails(ctx, pointers)
+	if err != nil {
+		return nil, nil, *NewQueryDBError()
+	}
+	for _, product := range pointers {
+		s.setImageURLs(product, details)
+	}
+
+	return products, missing, *NewSuccessError()
+}
+
 func (s *productServiceImpl) AddProductCategoryService(ctx context.Context, request *model.ProductCategoryRequest, token string) AppError {
 
-	validate := validator.New()
+	validate := newValidator()
 	if err := validate.Struct(request); err != nil {
-		return *NewInvalidRequestError(err.Error())
+		return *NewValidationError(err)
 	}
 	user, err := s.userRepository.GetUser(ctx, token)
 
@@ -116,37 +271,48 @@ func (s *productServiceImpl) AddProductCategoryService(ctx context.Context, requ
 		ClientID: uint(user.ClientId),
 		Name:     request.Category,
 	}
-	err = s.productRepository.AddProductCategory(ctx, category)
-
-	if err != nil {
-		return *NewUpdateQueryDBError()
+	appError := s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		if err := tx.productRepository.AddProductCategory(ctx, category); err != nil {
+			return *NewUpdateQueryDBError()
+		}
+		tx.publishMenuEvents(newCategoryEvent(model.MenuCategoryCreated, category))
+		return *NewSuccessError()
+	})
+	if appError.Code != SuccessError {
+		return appError
 	}
 
+	request.ID = category.ID
+	request.Version = category.Version
 	return *NewSuccessError()
 }
 
 // EditProductCategoryService edits an existing ProductCategory.
 func (s *productServiceImpl) EditProductCategoryService(ctx context.Context, request *model.ProductCategoryRequest, token string) AppError {
-	validate := validator.New()
+	validate := newValidator()
 	if err := validate.Struct(request); err != nil {
-		return *NewInvalidRequestError(err.Error())
+		return *NewValidationError(err)
 	}
-	user, err := s.userRepository.GetUser(ctx, token)
 
-	if err != nil {
-		return *NewInvalidTokenError()
-	}
+	patch := &model.ProductCategoryPatchRequest{ID: request.ID, Version: request.Version, Category: &request.Category}
+	appError := s.PatchProductCategoryService(ctx, patch, token)
+	request.Version = patch.Version
+	return appError
+}
 
-	if !user.IsLogin {
-		return *NewInvalidTokenError()
+// PatchProductCategoryService changes the fields present in the patch and keeps the others.
+func (s *productServiceImpl) PatchProductCategoryService(ctx context.Context, request *model.ProductCategoryPatchRequest, token string) AppError {
+	validate := newValidator()
+	if err := validate.Struct(request); err != nil {
+		return *NewValidationError(err)
 	}
 
-	if !user.IsAdmin {
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
 		return *NewInvalidTokenError()
 	}
 
 	category, err := s.productRepository.GetProductCategoryByID(ctx, request.ID)
-
 	if err != nil {
 		return *NewInvalidTokenError()
 	}
@@ -155,19 +321,33 @@ func (s *productServiceImpl) EditProductCategoryService(ctx context.Context, req
 		return *NewInvalidTokenError()
 	}
 
-	category.Name = request.Category
+	if !matchesVersion(request.Version, category.Version) {
+		return *NewVersionConflictError()
+	}
 
-	err = s.productRepository.EditProductCategory(ctx, category)
+	if request.Category == nil {
+		request.Version = category.Version
+		return *NewSuccessError()
+	}
+	category.Name = *request.Category
 
-	if err != nil {
-		return *NewUpdateQueryDBError()
+	appError := s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		if err := tx.productRepository.EditProductCategory(ctx, category); err != nil {
+			return newVersionedUpdateError(err)
+		}
+		tx.publishMenuEvents(newCategoryEvent(model.MenuCategoryUpdated, category))
+		return *NewSuccessError()
+	})
+	if appError.Code != SuccessError {
+		return appError
 	}
 
+	request.Version = category.Version
 	return *NewSuccessError()
 }
 
-// DeleteProductCategoryService deletes a ProductCategory.
-func (s *productServiceImpl) DeleteProductCategoryService(ctx context.Context, ID uint, token string) AppError {
+// DeleteProductCategoryService deletes a ProductCategory at the version, 0 for any version.
+func (s *productServiceImpl) DeleteProductCategoryService(ctx context.Context, ID uint, version uint, token string) AppError {
 
 	user, err := s.userRepository.GetUser(ctx, token)
 
@@ -193,20 +373,24 @@ func (s *productServiceImpl) DeleteProductCategoryService(ctx context.Context, I
 		return *NewInvalidTokenError()
 	}
 
-	err = s.productRepository.DeactivateProductCategory(ctx, ID)
-
-	if err != nil {
-		return *NewUpdateQueryDBError()
+	if !matchesVersion(version, category.Version) {
+		return *NewVersionConflictError()
 	}
 
-	return *NewSuccessError()
+	return s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		if err := tx.productRepository.DeactivateProductCategory(ctx, ID, category.Version); err != nil {
+			return newVersionedUpdateError(err)
+		}
+		tx.publishMenuEvents(newCategoryEvent(model.MenuCategoryDeactivated, category))
+		return *NewSuccessError()
+	})
 }
 
 func (s *productServiceImpl) AddProductService(ctx context.Context, request *model.ProductRequest, token string) AppError {
 
-	validate := validator.New()
+	validate := newValidator()
 	if err := validate.Struct(request); err != nil {
-		return *NewInvalidRequestError(err.Error())
+		return *NewValidationError(err)
 	}
 	user, err := s.userRepository.GetUser(ctx, token)
 
@@ -232,22 +416,45 @@ func (s *productServiceImpl) AddProductService(ctx context.Context, request *mod
 		return *NewInvalidTokenError()
 	}
 
-	productImage, err := SaveImage(request.Image, s.imageRepository)
+	return s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		productImage, appError := tx.resolveImage(ctx, request.Image, request.ImageID, user)
+		if appError.Code != SuccessError {
+			return appError
+		}
 
-	if err != nil {
-		return *NewInvalidRequestError(err.Error())
-	}
+		product := &entity.Product{
+			Name:        request.Name,
+			Description: request.Description,
+			Price:       request.Price,
+			Image:       productImage,
+			CategoryID:  request.CategoryID,
+		}
+		if appError := tx.addProduct(ctx, product); appError.Code != SuccessError {
+			return appError
+		}
+		tx.publishMenuEvents(newProductEvent(model.MenuProductCreated, category.ClientID, product))
+
+		request.ID = product.ID
+		request.Version = product.Version
+		return *NewSuccessError()
+	})
+}
 
-	product := &entity.Product{
-		Name:        request.Name,
-		Description: request.Description,
-		Price:       request.Price,
-		Image:       productImage,
-		CategoryID:  request.CategoryID,
+// addProduct saves a new product and makes its image, if any, the primary image of its gallery.
+func (s *productServiceImpl) addProduct(ctx context.Context, product *entity.Product) AppError {
+	if _, err := s.productRepository.AddProduct(ctx, product); err != nil {
+		return *NewUpdateQueryDBError()
 	}
 
-	_, err = s.productRepository.AddProduct(ctx, product)
+	if product.Image == "" {
+		return *NewSuccessError()
+	}
 
+	err := s.productImageRepository.AddProductImage(ctx, &entity.ProductImage{
+		ProductID: product.ID,
+		FileName:  product.Image,
+		IsPrimary: true,
+	})
 	if err != nil {
 		return *NewUpdateQueryDBError()
 	}
@@ -255,70 +462,114 @@ func (s *productServiceImpl) AddProductService(ctx context.Context, request *mod
 	return *NewSuccessError()
 }
 
+// EditProductService replaces the fields of a product. The image is kept when the request has none.
 func (s *productServiceImpl) EditProductService(ctx context.Context, request *model.ProductRequest, token string) AppError {
-
-	validate := validator.New()
-	if err := validate.Struct(request); err != nil {
-		return *NewInvalidRequestError(err.Error())
+	validate := newValidator()
+	if err := validate.StructExcept(request, "Image"); err != nil {
+		return *NewValidationError(err)
 	}
-	user, err := s.userRepository.GetUser(ctx, token)
 
-	if err != nil {
-		return *NewInvalidTokenError()
+	patch := &model.ProductPatchRequest{
+		ID:          request.ID,
+		Version:     request.Version,
+		CategoryID:  &request.CategoryID,
+		Name:        &request.Name,
+		Description: &request.Description,
+		Price:       &request.Price,
 	}
-
-	if !user.IsLogin {
-		return *NewInvalidTokenError()
+	if request.Image != "" {
+		patch.Image = &request.Image
 	}
-
-	if !user.IsAdmin {
-		return *NewInvalidTokenError()
+	if request.ImageID != 0 {
+		patch.ImageID = &request.ImageID
 	}
+	appError := s.PatchProductService(ctx, patch, token)
+	request.Version = patch.Version
+	return appError
+}
 
-	category, err := s.productRepository.GetProductCategoryByID(ctx, request.CategoryID)
-
-	if err != nil {
-		return *NewQueryDBError()
+// PatchProductService changes the fields present in the patch and keeps the others.
+// A new image replaces the primary image of the gallery; the old one is released once the product is saved.
+func (s *productServiceImpl) PatchProductService(ctx context.Context, request *model.ProductPatchRequest, token string) AppError {
+	validate := newValidator()
+	if err := validate.Struct(request); err != nil {
+		return *NewValidationError(err)
 	}
 
-	if category.ClientID != uint(user.ClientId) {
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
 		return *NewInvalidTokenError()
 	}
 
-	product, err := s.productRepository.GetProductByID(ctx, request.ID, token)
-	if err != nil {
-		return *NewInvalidTokenError()
+	product, appError := s.getClientProduct(ctx, request.ID, token, user)
+	if appError.Code != SuccessError {
+		return appError
 	}
 
-	productImage, err := SaveImage(request.Image, s.imageRepository)
-	if err != nil {
-		return *NewInvalidRequestError(err.Error())
+	if !matchesVersion(request.Version, product.Version) {
+		return *NewVersionConflictError()
 	}
 
-	err = s.imageRepository.RemoveImage(product.Image)
-	if err != nil {
-		fmt.Println(err.Error())
+	if request.CategoryID != nil && *request.CategoryID != product.CategoryID {
+		if _, appError := s.getClientCategory(ctx, *request.CategoryID, user); appError.Code != SuccessError {
+			return appError
+		}
 	}
 
-	updateProduct := &entity.Product{
-		ID:          product.ID,
-		Name:        request.Name,
-		Description: request.Description,
-		Price:       request.Price,
-		Image:       productImage,
-		CategoryID:  product.CategoryID,
-	}
+	var version uint
+	appError = s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		updateProduct := *product
+		updateProduct.Images = nil
+		if request.CategoryID != nil {
+			updateProduct.CategoryID = *request.CategoryID
+		}
+		if request.Name != nil {
+			updateProduct.Name = *request.Name
+		}
+		if request.Description != nil {
+			updateProduct.Description = *request.Description
+		}
+		if request.Price != nil {
+			updateProduct.Price = *request.Price
+		}
 
-	err = s.productRepository.EditProduct(ctx, updateProduct)
+		replaceImage := request.Image != nil || request.ImageID != nil
+		if replaceImage {
+			var base64Image string
+			var imageID uint
+			if request.Image != nil {
+				base64Image = *request.Image
+			}
+			if request.ImageID != nil {
+				imageID = *request.ImageID
+			}
+
+			productImage, appError := tx.resolveImage(ctx, base64Image, imageID, user)
+			if appError.Code != SuccessError {
+				return appError
+			}
+			updateProduct.Image = productImage
+		}
 
-	if err != nil {
-		return *NewUpdateQueryDBError()
-	}
+		if err := tx.productRepository.EditProduct(ctx, &updateProduct); err != nil {
+			return newVersionedUpdateError(err)
+		}
+		version = updateProduct.Version
+		tx.publishMenuEvents(newProductEvent(model.MenuProductUpdated, uint(user.ClientId), &updateProduct))
 
-	return *NewSuccessError()
+		if !replaceImage {
+			return *NewSuccessError()
+		}
+		return tx.replacePrimaryImage(ctx, product, updateProduct.Image)
+	})
+	if appError.Code == SuccessError {
+		request.Version = version
+	}
+	return appError
 }
 
-func (s *productServiceImpl) DeleteProductService(ctx context.Context, ID uint, token string) AppError {
+// DeleteProductService deactivates a product at the version, 0 for any version.
+func (s *productServiceImpl) DeleteProductService(ctx context.Context, ID uint, version uint, token string) AppError {
 
 	user, err := s.userRepository.GetUser(ctx, token)
 
@@ -349,40 +600,76 @@ func (s *productServiceImpl) DeleteProductService(ctx context.Context, ID uint,
 		return *NewInvalidTokenError()
 	}
 
-	err = s.productRepository.DeactivateProduct(ctx, ID)
-
-	if err != nil {
-		return *NewUpdateQueryDBError()
+	if !matchesVersion(version, product.Version) {
+		return *NewVersionConflictError()
 	}
 
-	return *NewSuccessError()
+	return s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		if err := tx.productRepository.DeactivateProduct(ctx, ID, product.Version); err != nil {
+			return newVersionedUpdateError(err)
+		}
+		tx.publishMenuEvents(newProductEvent(model.MenuProductDeactivated, category.ClientID, product))
+		return *NewSuccessError()
+	})
 }
 
-func SaveImage(base64Image string, imagesRepo repository.ImagesRepository) (string, error) {
-	imageData, err := base64.StdEncoding.DecodeString(base64Image)
+// replacePrimaryImage puts the new image of a saved product in place of the primary image of its gallery.
+// The previous image is only removed once the product no longer references it.
+func (s *productServiceImpl) replacePrimaryImage(ctx context.Context, previous *entity.Product, imageName string) AppError {
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+
+	images, err := s.getGallery(ctx, previous)
 	if err != nil {
-		return "", err
+		return *NewQueryDBError()
 	}
-	mimeType := http.DetectContentType(imageData)
-	mimeTypePrefix := strings.Split(mimeType, "/")[1]
 
-	id := "PR"
-	ms := time.Now().UnixNano() / int64(time.Millisecond)
-	imageName := fmt.Sprintf("%s-%d.%s", id, ms, mimeTypePrefix)
+	primary := entity.ProductImage{ProductID: previous.ID, IsPrimary: true}
+	for _, image := range images {
+		if image.IsPrimary {
+			primary = image
+			break
+		}
+	}
+	primary.FileName = imageName
 
-	img, format, err := image.Decode(bytes.NewReader(imageData))
-	if err != nil {
-		return "", err
+	if primary.ID == 0 {
+		err = s.productImageRepository.AddProductImage(ctx, &primary)
+	} else {
+		err = s.productImageRepository.EditProductImage(ctx, &primary)
 	}
-	compressedImage, err := helper.CompressImage(img, format)
 	if err != nil {
-		return "", err
+		return *NewUpdateQueryDBError()
+	}
+
+	if err := s.releaseImage(ctx, previous.Image); err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error RemoveImage %s", err.Error())
 	}
+	return *NewSuccessError()
+}
+
+// matchesVersion reports whether a request made from the expected version, 0 for any version,
+// may change a product or category at the current version.
+func matchesVersion(expected uint, current uint) bool {
+	return expected == 0 || expected == current
+}
 
-	err = imagesRepo.SaveImage(compressedImage, imageName)
+// newVersionedUpdateError returns the AppError of a failed version checked update.
+func newVersionedUpdateError(err error) AppError {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return *NewVersionConflictError()
+	}
+	return *NewUpdateQueryDBError()
+}
+
+// compressImage compresses a validated image and names the result after its SHA-256 hash,
+// so identical images share one stored file.
+func compressImage(img image.Image, format string) (string, []byte, error) {
+	compressedImage, err := helper.CompressImage(img, format)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return imageName, nil
+	hash := sha256.Sum256(compressedImage)
+	imageName := fmt.Sprintf("%s.%s", hex.EncodeToString(hash[:]), format)
+	return imageName, compressedImage, nil
 }
@@ -0,0 +1,92 @@
+// internal/service/transaction.go
+
+package service
+
+import (
+	"context"
+	"errors"
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// imageChanges collects the image files touched by a transaction, which cannot be rolled back
+// with the database: the files stored for the first time and the files no longer referenced.
+type imageChanges struct {
+	stored  []string
+	removed []string
+}
+
+// inTransaction runs fn with a copy of the service whose repositories write in one database transaction.
+// When fn fails, or the transaction does not commit, the image files stored by fn are removed again;
+// the image files released by fn are only removed, and the menu events of fn only delivered, once the
+// transaction commits. The webhooks of the events are queued in the transaction.
+func (s *productServiceImpl) inTransaction(ctx context.Context, fn func(tx *productServiceImpl) AppError) AppError {
+	changes := &imageChanges{}
+	menuEvents := []model.MenuEvent{}
+	appError := *NewSuccessError()
+
+	err := s.unitOfWork.Do(ctx, func(repositories *repository.Repositories) error {
+		tx := *s
+		tx.productRepository = repositories.Product
+		tx.translationRepository = repositories.Translation
+		tx.productImageRepository = repositories.ProductImage
+		tx.uploadedImageRepository = repositories.UploadedImage
+		tx.imageChanges = changes
+		tx.pendingMenuEvents = &menuEvents
+
+		appError = fn(&tx)
+		if appError.Code != SuccessError {
+			return errors.New(appError.Message)
+		}
+
+		if s.webhooks != nil && len(menuEvents) > 0 {
+			return s.webhooks.EnqueueWebhooks(ctx, repositories.Webhook, menuEvents...)
+		}
+		return nil
+	})
+
+	if err != nil {
+		s.removeImages(ctx, changes.stored)
+		if appError.Code == SuccessError {
+			appError = *NewUpdateQueryDBError()
+		}
+		return appError
+	}
+
+	s.removeImages(ctx, changes.removed)
+	s.notifyMenuEvents(menuEvents)
+	return appError
+}
+
+// removeImages removes the files of images stored by a rolled back transaction, or released by a
+// committed one. Image names are content hashes, so another request may store the same image again
+// in the meantime; images with a blob again are kept.
+func (s *productServiceImpl) removeImages(ctx context.Context, imageNames []string) {
+	if len(imageNames) == 0 {
+		return
+	}
+
+	logID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	blobs, err := s.productImageRepository.GetImageBlobs(ctx, imageNames)
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error GetImageBlobs %s", err.Error())
+		return
+	}
+
+	kept := map[string]bool{}
+	for _, blob := range blobs {
+		kept[blob.Name] = true
+	}
+	for _, imageName := range imageNames {
+		if kept[imageName] {
+			continue
+		}
+		if err := s.imageRepository.RemoveImage(imageName); err != nil {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error RemoveImage %s", err.Error())
+		}
+	}
+}
@@ -0,0 +1,342 @@
+// internal/service/translation_service.go
+
+package service
+
+import (
+	"context"
+	"errors"
+	exModel "maqhaa/product_service/external/model"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+
+	"gorm.io/gorm"
+)
+
+// resolveLocales returns the locales to look translations up in, in order of preference:
+// the requested locale followed by the default locale of the client owning the token.
+func (s *productServiceImpl) resolveLocales(ctx context.Context, token string, locale string) []string {
+	locales := []string{}
+	if locale = NormalizeLocale(locale); locale != "" {
+		locales = append(locales, locale)
+	}
+
+	client, err := s.productRepository.GetClientByToken(ctx, token)
+	if err != nil {
+		return locales
+	}
+
+	if defaultLocale := NormalizeLocale(client.DefaultLocale); defaultLocale != "" && defaultLocale != locale {
+		locales = append(locales, defaultLocale)
+	}
+	return locales
+}
+
+// localizeCategories replaces the names of the categories and their products with the best matching translation.
+func (s *productServiceImpl) localizeCategories(ctx context.Context, token string, locale string, categories []entity.ProductCategory) error {
+	locales := s.resolveLocales(ctx, token, locale)
+	if len(locales) == 0 {
+		return nil
+	}
+
+	categoryIDs := []uint{}
+	products := []*entity.Product{}
+	for i := range categories {
+		categoryIDs = append(categoryIDs, categories[i].ID)
+		for j := range categories[i].Products {
+			products = append(products, &categories[i].Products[j])
+		}
+	}
+
+	translations, err := s.translationRepository.GetCategoryTranslationsByLocales(ctx, categoryIDs, locales)
+	if err != nil {
+		return err
+	}
+
+	byCategory := map[uint]map[string]entity.ProductCategoryTranslation{}
+	for _, translation := range translations {
+		if byCategory[translation.CategoryID] == nil {
+			byCategory[translation.CategoryID] = map[string]entity.ProductCategoryTranslation{}
+		}
+		byCategory[translation.CategoryID][translation.Locale] = translation
+	}
+
+	for i := range categories {
+		for _, l := range locales {
+			if translation, ok := byCategory[categories[i].ID][l]; ok {
+				categories[i].Name = translation.Name
+				break
+			}
+		}
+	}
+
+	return s.applyProductTranslations(ctx, locales, products)
+}
+
+// localizeProducts replaces the names and descriptions of the products with the best matching translation.
+func (s *productServiceImpl) localizeProducts(ctx context.Context, token string, locale string, products []*entity.Product) error {
+	locales := s.resolveLocales(ctx, token, locale)
+	if len(locales) == 0 {
+		return nil
+	}
+	return s.applyProductTranslations(ctx, locales, products)
+}
+
+func (s *productServiceImpl) applyProductTranslations(ctx context.Context, locales []string, products []*entity.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	productIDs := make([]uint, 0, len(products))
+	for _, product := range products {
+		productIDs = append(productIDs, product.ID)
+	}
+
+	translations, err := s.translationRepository.GetProductTranslationsByLocales(ctx, productIDs, locales)
+	if err != nil {
+		return err
+	}
+
+	byProduct := map[uint]map[string]entity.ProductTranslation{}
+	for _, translation := range translations {
+		if byProduct[translation.ProductID] == nil {
+			byProduct[translation.ProductID] = map[string]entity.ProductTranslation{}
+		}
+		byProduct[translation.ProductID][translation.Locale] = translation
+	}
+
+	for _, product := range products {
+		for _, l := range locales {
+			if translation, ok := byProduct[product.ID][l]; ok {
+				product.Name = translation.Name
+				if translation.Description != "" {
+					product.Description = translation.Description
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// getAdminUser returns the user owning the token when it is logged in and an admin, otherwise nil.
+func (s *productServiceImpl) getAdminUser(ctx context.Context, token string) *exModel.UserData {
+	user, err := s.userRepository.GetUser(ctx, token)
+	if err != nil {
+		return nil
+	}
+
+	if !user.IsLogin || !user.IsAdmin {
+		return nil
+	}
+	return user
+}
+
+// getClientProduct returns the product when it belongs to the client of the user.
+func (s *productServiceImpl) getClientProduct(ctx context.Context, productID uint, token string, user *exModel.UserData) (*entity.Product, AppError) {
+	product, err := s.productRepository.GetProductByID(ctx, productID, token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, *NewProductNotFoundError()
+		}
+		return nil, *NewQueryDBError()
+	}
+
+	category, err := s.productRepository.GetProductCategoryByID(ctx, product.CategoryID)
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	if category.ClientID != uint(user.ClientId) {
+		return nil, *NewInvalidTokenError()
+	}
+	return product, *NewSuccessError()
+}
+
+// getClientCategory returns the category when it belongs to the client of the user.
+func (s *productServiceImpl) getClientCategory(ctx context.Context, categoryID uint, user *exModel.UserData) (*entity.ProductCategory, AppError) {
+	category, err := s.productRepository.GetProductCategoryByID(ctx, categoryID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, *NewDateCategoryNotFoundError()
+		}
+		return nil, *NewQueryDBError()
+	}
+
+	if category.ClientID != uint(user.ClientId) {
+		return nil, *NewInvalidTokenError()
+	}
+	return category, *NewSuccessError()
+}
+
+// GetProductTranslationsService lists every translation of a product.
+func (s *productServiceImpl) GetProductTranslationsService(ctx context.Context, productID uint, token string) ([]entity.ProductTranslation, AppError) {
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return nil, *NewInvalidTokenError()
+	}
+
+	if _, appError := s.getClientProduct(ctx, productID, token, user); appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	translations, err := s.translationRepository.GetProductTranslations(ctx, productID)
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	return translations, *NewSuccessError()
+}
+
+// SaveProductTranslationService creates or replaces the translation of a product for one locale.
+func (s *productServiceImpl) SaveProductTranslationService(ctx context.Context, request *model.ProductTranslationRequest, token string) AppError {
+	validate := newValidator()
+	if err := validate.Struct(request); err != nil {
+		return *NewValidationError(err)
+	}
+
+	locale := NormalizeLocale(request.Locale)
+	if locale == "" {
+		return *NewInvalidRequestError("locale")
+	}
+
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return *NewInvalidTokenError()
+	}
+
+	product, appError := s.getClientProduct(ctx, request.ProductID, token, user)
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	translation := &entity.ProductTranslation{
+		ProductID:   request.ProductID,
+		Locale:      locale,
+		Name:        request.Name,
+		Description: request.Description,
+	}
+	return s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		if err := tx.translationRepository.SaveProductTranslation(ctx, translation); err != nil {
+			return *NewUpdateQueryDBError()
+		}
+
+		tx.publishMenuEvents(newProductEvent(model.MenuProductUpdated, uint(user.ClientId), product))
+		return *NewSuccessError()
+	})
+}
+
+// DeleteProductTranslationService removes the translation of a product for one locale.
+func (s *productServiceImpl) DeleteProductTranslationService(ctx context.Context, productID uint, locale string, token string) AppError {
+	locale = NormalizeLocale(locale)
+	if locale == "" {
+		return *NewInvalidRequestError("locale")
+	}
+
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return *NewInvalidTokenError()
+	}
+
+	product, appError := s.getClientProduct(ctx, productID, token, user)
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	return s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		if err := tx.translationRepository.DeleteProductTranslation(ctx, productID, locale); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return *NewTranslationNotFoundError()
+			}
+			return *NewUpdateQueryDBError()
+		}
+
+		tx.publishMenuEvents(newProductEvent(model.MenuProductUpdated, uint(user.ClientId), product))
+		return *NewSuccessError()
+	})
+}
+
+// GetCategoryTranslationsService lists every translation of a category.
+func (s *productServiceImpl) GetCategoryTranslationsService(ctx context.Context, categoryID uint, token string) ([]entity.ProductCategoryTranslation, AppError) {
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return nil, *NewInvalidTokenError()
+	}
+
+	if _, appError := s.getClientCategory(ctx, categoryID, user); appError.Code != SuccessError {
+		return nil, appError
+	}
+
+	translations, err := s.translationRepository.GetCategoryTranslations(ctx, categoryID)
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	return translations, *NewSuccessError()
+}
+
+// SaveCategoryTranslationService creates or replaces the translation of a category for one locale.
+func (s *productServiceImpl) SaveCategoryTranslationService(ctx context.Context, request *model.CategoryTranslationRequest, token string) AppError {
+	validate := newValidator()
+	if err := validate.Struct(request); err != nil {
+		return *NewValidationError(err)
+	}
+
+	locale := NormalizeLocale(request.Locale)
+	if locale == "" {
+		return *NewInvalidRequestError("locale")
+	}
+
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return *NewInvalidTokenError()
+	}
+
+	category, appError := s.getClientCategory(ctx, request.CategoryID, user)
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	translation := &entity.ProductCategoryTranslation{
+		CategoryID: request.CategoryID,
+		Locale:     locale,
+		Name:       request.Name,
+	}
+	return s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		if err := tx.translationRepository.SaveCategoryTranslation(ctx, translation); err != nil {
+			return *NewUpdateQueryDBError()
+		}
+
+		tx.publishMenuEvents(newCategoryEvent(model.MenuCategoryUpdated, category))
+		return *NewSuccessError()
+	})
+}
+
+// DeleteCategoryTranslationService removes the translation of a category for one locale.
+func (s *productServiceImpl) DeleteCategoryTranslationService(ctx context.Context, categoryID uint, locale string, token string) AppError {
+	locale = NormalizeLocale(locale)
+	if locale == "" {
+		return *NewInvalidRequestError("locale")
+	}
+
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return *NewInvalidTokenError()
+	}
+
+	category, appError := s.getClientCategory(ctx, categoryID, user)
+	if appError.Code != SuccessError {
+		return appError
+	}
+
+	return s.inTransaction(ctx, func(tx *productServiceImpl) AppError {
+		if err := tx.translationRepository.DeleteCategoryTranslation(ctx, categoryID, locale); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return *NewTranslationNotFoundError()
+			}
+			return *NewUpdateQueryDBError()
+		}
+
+		tx.publishMenuEvents(newCategoryEvent(model.MenuCategoryUpdated, category))
+		return *NewSuccessError()
+	})
+}
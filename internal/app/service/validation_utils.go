@@ -0,0 +1,65 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"maqhaa/product_service/internal/app/model"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// newValidator creates a validator reporting fields by their JSON name.
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return validate
+}
+
+// NewValidationError converts the error of a struct validation into an invalid request error
+// carrying one entry per failed field.
+func NewValidationError(err error) *AppError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return NewInvalidRequestError(err.Error())
+	}
+
+	fieldErrors := make([]model.FieldError, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		fieldErrors = append(fieldErrors, model.FieldError{
+			Field:   fieldError.Field(),
+			Rule:    fieldError.Tag(),
+			Param:   fieldError.Param(),
+			Message: fieldErrorMessage(fieldError.Field(), fieldError.Tag(), fieldError.Param(), DefaultLanguage),
+		})
+	}
+
+	return newFieldErrors(fieldErrors)
+}
+
+// newFieldErrors creates an invalid request error carrying the field errors.
+func newFieldErrors(fieldErrors []model.FieldError) *AppError {
+	appError := NewAppError(InvalidRequestError, strings.TrimSpace(fmt.Sprintf(InvalidRequestMessage, "")))
+	appError.Errors = fieldErrors
+	return appError
+}
+
+// newFieldError creates the error of a field failing a validation rule.
+func newFieldError(field string, rule string, param string) model.FieldError {
+	return model.FieldError{
+		Field:   field,
+		Rule:    rule,
+		Param:   param,
+		Message: fieldErrorMessage(field, rule, param, DefaultLanguage),
+	}
+}
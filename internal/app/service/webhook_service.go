@@ -0,0 +1,494 @@
+// internal/service/webhook_service.go
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maqhaa/library/logging"
+	exModel "maqhaa/product_service/external/model"
+	exRepo "maqhaa/product_service/external/repository"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/repository"
+	"maqhaa/product_service/internal/config"
+	"maqhaa/product_service/internal/safehttp"
+	mathRand "math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Headers of the webhook requests. The signature is "t=<timestamp>,v1=<signature>", see SignWebhook.
+const (
+	WebhookIDHeader        = "X-Webhook-Id"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	// webhookDeadLetters is the number of dead deliveries listed, most recent first.
+	webhookDeadLetters = 100
+	// webhookErrorLength bounds the error kept for the last attempt of a delivery.
+	webhookErrorLength = 1024
+	// webhookLease is how long deliveries being sent are kept from the other instances, on top of the timeout.
+	webhookLease = time.Minute
+)
+
+// WebhookService manages the webhooks of clients and delivers the menu events they subscribed to.
+// A delivery is attempted until the URL responds with a 2xx status; redirects are not followed.
+type WebhookService interface {
+	AddWebhookService(ctx context.Context, request *model.WebhookRequest, token string) (*entity.WebhookSubscription, AppError)
+	GetWebhooksService(ctx context.Context, token string) ([]entity.WebhookSubscription, AppError)
+	DeleteWebhookService(ctx context.Context, webhookID uint, token string) AppError
+	GetDeadWebhookDeliveriesService(ctx context.Context, token string) ([]entity.WebhookDelivery, AppError)
+	// RedeliverWebhookService sends a delivery again, with all its attempts, as soon as possible.
+	RedeliverWebhookService(ctx context.Context, deliveryID uint, token string) (*entity.WebhookDelivery, AppError)
+	// EnqueueWebhooks queues the deliveries of menu events to the webhooks subscribed to them, through the
+	// repository of the transaction of the writes of the events, so they are committed or lost together.
+	EnqueueWebhooks(ctx context.Context, webhookRepository repository.WebhookRepository, events ...model.MenuEvent) error
+	// WakeWebhookDelivery starts a delivery as soon as deliveries queued by EnqueueWebhooks are committed.
+	WakeWebhookDelivery()
+	DeliverWebhooks(ctx context.Context) (int, error)
+	RunWebhookDelivery(ctx context.Context, interval time.Duration)
+}
+
+type webhookServiceImpl struct {
+	webhookRepository repository.WebhookRepository
+	unitOfWork        repository.UnitOfWork
+	userRepository    exRepo.UserRepository
+	config            config.WebhookConfig
+	client            *http.Client
+	// wake starts a delivery as soon as deliveries are queued.
+	wake chan struct{}
+}
+
+// NewWebhookService creates a new WebhookService instance. Limits missing from the configuration get
+// the defaults of config-prod.yaml.
+func NewWebhookService(webhookRepository repository.WebhookRepository, unitOfWork repository.UnitOfWork, userRepository exRepo.UserRepository, webhookConfig config.WebhookConfig) WebhookService {
+	if webhookConfig.BatchSize <= 0 {
+		webhookConfig.BatchSize = 20
+	}
+	if webhookConfig.Timeout <= 0 {
+		webhookConfig.Timeout = 10 * time.Second
+	}
+	if webhookConfig.MaxAttempts <= 0 {
+		webhookConfig.MaxAttempts = 8
+	}
+	if webhookConfig.InitialBackoff <= 0 {
+		webhookConfig.InitialBackoff = 30 * time.Second
+	}
+	if webhookConfig.MaxBackoff < webhookConfig.InitialBackoff {
+		webhookConfig.MaxBackoff = webhookConfig.InitialBackoff
+	}
+
+	return &webhookServiceImpl{
+		webhookRepository: webhookRepository,
+		unitOfWork:        unitOfWork,
+		userRepository:    userRepository,
+		config:            webhookConfig,
+		client:            safehttp.NewClient(webhookConfig.Timeout, webhookConfig.AllowedHosts...),
+		wake:              make(chan struct{}, 1),
+	}
+}
+
+// SignWebhook returns the signature of a webhook request sent at timestamp, in Unix seconds: the hex
+// HMAC-SHA256, keyed by the secret of the webhook, of the timestamp, a dot and the body. Receivers
+// compute it again to check the request, and reject old timestamps to stop replays.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *webhookServiceImpl) AddWebhookService(ctx context.Context, request *model.WebhookRequest, token string) (*entity.WebhookSubscription, AppError) {
+	validate := newValidator()
+	if err := validate.Struct(request); err != nil {
+		return nil, *NewValidationError(err)
+	}
+	if !s.allowsURL(request.URL) {
+		return nil, *newFieldErrors([]model.FieldError{newFieldError("url", "https_url", "")})
+	}
+
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return nil, *NewInvalidTokenError()
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			return nil, *NewGeneralSystemError()
+		}
+		secret = hex.EncodeToString(random)
+	}
+
+	subscription := &entity.WebhookSubscription{
+		ClientID:   uint(user.ClientId),
+		URL:        request.URL,
+		EventTypes: request.EventTypes,
+		Secret:     secret,
+	}
+	if err := s.webhookRepository.AddWebhookSubscription(ctx, subscription); err != nil {
+		return nil, *NewUpdateQueryDBError()
+	}
+	return subscription, *NewSuccessError()
+}
+
+// GetWebhooksService lists the webhooks of the client of the token, without their secrets.
+func (s *webhookServiceImpl) GetWebhooksService(ctx context.Context, token string) ([]entity.WebhookSubscription, AppError) {
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return nil, *NewInvalidTokenError()
+	}
+
+	subscriptions, err := s.webhookRepository.GetWebhookSubscriptions(ctx, uint(user.ClientId))
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	return subscriptions, *NewSuccessError()
+}
+
+// DeleteWebhookService removes a webhook of the client of the token. Its pending deliveries become dead letters.
+func (s *webhookServiceImpl) DeleteWebhookService(ctx context.Context, webhookID uint, token string) AppError {
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return *NewInvalidTokenError()
+	}
+
+	deleted, err := s.webhookRepository.DeleteWebhookSubscription(ctx, uint(user.ClientId), webhookID)
+	if err != nil {
+		return *NewUpdateQueryDBError()
+	}
+	if !deleted {
+		return *NewWebhookNotFoundError()
+	}
+	return *NewSuccessError()
+}
+
+// GetDeadWebhookDeliveriesService lists the most recent deliveries of the client of the token that failed every attempt.
+func (s *webhookServiceImpl) GetDeadWebhookDeliveriesService(ctx context.Context, token string) ([]entity.WebhookDelivery, AppError) {
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return nil, *NewInvalidTokenError()
+	}
+
+	deliveries, err := s.webhookRepository.GetDeadWebhookDeliveries(ctx, uint(user.ClientId), webhookDeadLetters)
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+	return deliveries, *NewSuccessError()
+}
+
+func (s *webhookServiceImpl) RedeliverWebhookService(ctx context.Context, deliveryID uint, token string) (*entity.WebhookDelivery, AppError) {
+	user := s.getAdminUser(ctx, token)
+	if user == nil {
+		return nil, *NewInvalidTokenError()
+	}
+
+	delivery, err := s.webhookRepository.GetWebhookDeliveryByID(ctx, uint(user.ClientId), deliveryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, *NewWebhookDeliveryNotFoundError()
+	}
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	_, err = s.webhookRepository.GetWebhookSubscriptionByID(ctx, delivery.SubscriptionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, *NewWebhookNotFoundError()
+	}
+	if err != nil {
+		return nil, *NewQueryDBError()
+	}
+
+	delivery.Status = entity.WebhookPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.LastError = ""
+	delivery.LastStatusCode = 0
+	delivery.DeliveredAt = nil
+	if err := s.webhookRepository.SaveWebhookDelivery(ctx, delivery); err != nil {
+		return nil, *NewUpdateQueryDBError()
+	}
+
+	s.wakeDelivery()
+	return delivery, *NewSuccessError()
+}
+
+func (s *webhookServiceImpl) EnqueueWebhooks(ctx context.Context, webhookRepository repository.WebhookRepository, events ...model.MenuEvent) error {
+	subscriptions := map[uint][]entity.WebhookSubscription{}
+	deliveries := []entity.WebhookDelivery{}
+	now := time.Now()
+
+	for _, event := range events {
+		clientSubscriptions, ok := subscriptions[event.ClientID]
+		if !ok {
+			var err error
+			clientSubscriptions, err = webhookRepository.GetWebhookSubscriptions(ctx, event.ClientID)
+			if err != nil {
+				return err
+			}
+			subscriptions[event.ClientID] = clientSubscriptions
+		}
+
+		var eventID string
+		var payload []byte
+		for _, subscription := range clientSubscriptions {
+			if !subscribedTo(subscription, event.Type) {
+				continue
+			}
+			if payload == nil {
+				eventID = uuid.NewString()
+				payload, _ = json.Marshal(model.WebhookEvent{
+					ID:         eventID,
+					Type:       event.Type,
+					ClientID:   event.ClientID,
+					ProductID:  event.ProductID,
+					CategoryID: event.CategoryID,
+					CreatedAt:  now,
+				})
+			}
+
+			deliveries = append(deliveries, entity.WebhookDelivery{
+				SubscriptionID: subscription.ID,
+				ClientID:       event.ClientID,
+				EventID:        eventID,
+				EventType:      event.Type,
+				Payload:        string(payload),
+				Status:         entity.WebhookPending,
+				NextAttemptAt:  now,
+			})
+		}
+	}
+
+	return webhookRepository.AddWebhookDeliveries(ctx, deliveries)
+}
+
+func (s *webhookServiceImpl) WakeWebhookDelivery() {
+	s.wakeDelivery()
+}
+
+// DeliverWebhooks sends a batch of due deliveries, at the same time, and returns how many were delivered.
+// The deliveries are leased while they are sent, so other instances only send them again when their
+// attempts could not be saved.
+func (s *webhookServiceImpl) DeliverWebhooks(ctx context.Context) (int, error) {
+	var deliveries []entity.WebhookDelivery
+	now := time.Now()
+
+	err := s.unitOfWork.Do(ctx, func(repositories *repository.Repositories) error {
+		due, err := repositories.Webhook.LockDueWebhookDeliveries(ctx, now, s.config.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		IDs := make([]uint, 0, len(due))
+		for _, delivery := range due {
+			IDs = append(IDs, delivery.ID)
+		}
+		deliveries = due
+		return repositories.Webhook.PostponeWebhookDeliveries(ctx, IDs, now.Add(s.config.Timeout+webhookLease))
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var wait sync.WaitGroup
+	delivered := make([]bool, len(deliveries))
+	for i := range deliveries {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			delivered[i] = s.deliver(ctx, &deliveries[i])
+		}(i)
+	}
+	wait.Wait()
+
+	count := 0
+	for _, ok := range delivered {
+		if ok {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// RunWebhookDelivery delivers the due webhooks every interval, and as soon as deliveries are queued,
+// until the context is done.
+func (s *webhookServiceImpl) RunWebhookDelivery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+
+		if _, err := s.DeliverWebhooks(ctx); err != nil {
+			logging.Log.Errorf("Error DeliverWebhooks %s", err.Error())
+		}
+	}
+}
+
+// deliver attempts a delivery and saves the outcome: delivered, retried after a backoff, or dead after
+// the last attempt. It returns whether the webhook was delivered.
+func (s *webhookServiceImpl) deliver(ctx context.Context, delivery *entity.WebhookDelivery) bool {
+	subscription, err := s.webhookRepository.GetWebhookSubscriptionByID(ctx, delivery.SubscriptionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		delivery.Status = entity.WebhookDead
+		delivery.LastError = "webhook removed"
+		s.saveDelivery(ctx, delivery)
+		return false
+	}
+	if err != nil {
+		// the delivery is attempted again once its lease expires
+		return false
+	}
+
+	statusCode, err := s.send(ctx, subscription, delivery)
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	switch {
+	case err == nil:
+		delivery.Status = entity.WebhookDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= s.config.MaxAttempts:
+		delivery.Status = entity.WebhookDead
+		delivery.LastError = truncate(err.Error(), webhookErrorLength)
+	default:
+		delivery.NextAttemptAt = now.Add(s.backoff(delivery.Attempts))
+		delivery.LastError = truncate(err.Error(), webhookErrorLength)
+	}
+
+	s.saveDelivery(ctx, delivery)
+	return err == nil
+}
+
+// send posts the payload of a delivery, signed with the secret of the webhook, and returns the status
+// code of the response, or 0 when none was received.
+func (s *webhookServiceImpl) send(ctx context.Context, subscription *entity.WebhookSubscription, delivery *entity.WebhookDelivery) (int, error) {
+	if !s.allowsURL(subscription.URL) {
+		return 0, fmt.Errorf("%s: not an https URL", subscription.URL)
+	}
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "product_service-webhooks")
+	req.Header.Set(WebhookIDHeader, delivery.EventID)
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookSignatureHeader, fmt.Sprintf("t=%d,v1=%s", timestamp, SignWebhook(subscription.Secret, timestamp, body)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// allowsURL reports whether webhooks may be sent to a URL: an https URL, or any URL of an allowed host.
+// The address of the host is checked by the client when connecting.
+func (s *webhookServiceImpl) allowsURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "https" {
+		return true
+	}
+	for _, host := range s.config.AllowedHosts {
+		if strings.EqualFold(host, u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the attempt following attempts failed ones: the initial backoff doubled
+// after every failure, up to the maximum, with up to 10% of jitter so failed deliveries spread out.
+func (s *webhookServiceImpl) backoff(attempts int) time.Duration {
+	delay := s.config.MaxBackoff
+	if attempts-1 < 32 {
+		if doubled := s.config.InitialBackoff << (attempts - 1); doubled > 0 && doubled < delay {
+			delay = doubled
+		}
+	}
+	return delay + time.Duration(mathRand.Int63n(int64(delay)/10+1))
+}
+
+func (s *webhookServiceImpl) saveDelivery(ctx context.Context, delivery *entity.WebhookDelivery) {
+	if err := s.webhookRepository.SaveWebhookDelivery(ctx, delivery); err != nil {
+		logging.Log.Errorf("Error saving webhook delivery %d: %s", delivery.ID, err.Error())
+	}
+}
+
+func (s *webhookServiceImpl) wakeDelivery() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// getAdminUser returns the user of the token when an admin is logged in with it, or nil.
+func (s *webhookServiceImpl) getAdminUser(ctx context.Context, token string) *exModel.UserData {
+	user, err := s.userRepository.GetUser(ctx, token)
+	if err != nil || !user.IsLogin || !user.IsAdmin {
+		return nil
+	}
+	return user
+}
+
+// subscribedTo returns whether a webhook is subscribed to the events of a type.
+func subscribedTo(subscription entity.WebhookSubscription, eventType string) bool {
+	for _, subscribed := range subscription.EventTypes {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate shortens a text to at most length bytes.
+func truncate(text string, length int) string {
+	if len(text) <= length {
+		return text
+	}
+	return text[:length]
+}
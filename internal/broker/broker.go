@@ -0,0 +1,51 @@
+// internal/broker/broker.go
+
+// Package broker publishes messages to the message brokers the other services consume the domain events
+// of the catalogue from. Delivery is at least once: a message may be published again after a failure, so
+// consumers recognise repeated messages by their ID.
+package broker
+
+import (
+	"context"
+	"fmt"
+	"maqhaa/product_service/internal/config"
+	"time"
+)
+
+// defaultTimeout bounds a publish when the configuration sets no timeout.
+const defaultTimeout = 5 * time.Second
+
+// Message is a message published to a broker. Subject names the kind of message, e.g. the type of a
+// domain event, and messages with the same Key are kept in order by brokers partitioning their topics.
+type Message struct {
+	ID      string
+	Subject string
+	Key     string
+	Body    []byte
+}
+
+// Broker publishes messages to a message broker.
+type Broker interface {
+	// Publish returns once the broker acknowledged the message, or with the reason it did not.
+	Publish(ctx context.Context, message Message) error
+	Close() error
+}
+
+// NewBroker creates the Broker selected by the driver of the configuration.
+func NewBroker(cfg config.BrokerConfig) (Broker, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	switch cfg.Driver {
+	case "nats":
+		return NewNATSBroker(cfg.URL, cfg.Topic, timeout)
+	case "kafka":
+		return NewKafkaBroker(cfg.URL, cfg.Topic, timeout)
+	case "memory":
+		return NewMemoryBroker(), nil
+	default:
+		return nil, fmt.Errorf("unsupported broker driver: %s", cfg.Driver)
+	}
+}
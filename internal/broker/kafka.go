@@ -0,0 +1,101 @@
+// internal/broker/kafka.go
+
+package broker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// kafkaRecords is the body of a produce request of the Kafka REST proxy, with binary keys and values.
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// kafkaOffsets is the response to a produce request, with an error for every record not written.
+type kafkaOffsets struct {
+	Offsets []struct {
+		Partition int    `json:"partition"`
+		Offset    int64  `json:"offset"`
+		ErrorCode int    `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// kafkaBroker publishes messages to a Kafka topic through the Kafka REST proxy. Messages are keyed by
+// their Key, so the messages of a key go to the same partition, and the partition keeps them in order.
+type kafkaBroker struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewKafkaBroker creates a Broker publishing to the topic through the Kafka REST proxy at proxyURL.
+func NewKafkaBroker(proxyURL string, topic string, timeout time.Duration) (Broker, error) {
+	if _, err := url.ParseRequestURI(proxyURL); err != nil {
+		return nil, fmt.Errorf("invalid Kafka REST proxy URL: %v", err)
+	}
+	if topic == "" {
+		return nil, fmt.Errorf("missing Kafka topic")
+	}
+
+	return &kafkaBroker{
+		endpoint: strings.TrimRight(proxyURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (b *kafkaBroker) Publish(ctx context.Context, message Message) error {
+	body, err := json.Marshal(kafkaRecords{Records: []kafkaRecord{{Key: []byte(message.Key), Value: message.Body}}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.binary.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("kafka REST proxy responded %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var offsets kafkaOffsets
+	if err := json.Unmarshal(data, &offsets); err != nil {
+		return fmt.Errorf("invalid Kafka REST proxy response: %v", err)
+	}
+	for _, offset := range offsets.Offsets {
+		if offset.ErrorCode != 0 || offset.Error != "" {
+			return fmt.Errorf("kafka rejected message %s: %d %s", message.ID, offset.ErrorCode, offset.Error)
+		}
+	}
+	return nil
+}
+
+func (b *kafkaBroker) Close() error {
+	b.client.CloseIdleConnections()
+	return nil
+}
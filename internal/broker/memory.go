@@ -0,0 +1,51 @@
+// internal/broker/memory.go
+
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBroker keeps the messages published in memory, for tests and for running without a broker.
+type MemoryBroker struct {
+	mutex    sync.Mutex
+	messages []Message
+	err      error
+}
+
+// NewMemoryBroker creates a new MemoryBroker instance.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, message Message) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.err != nil {
+		return b.err
+	}
+	b.messages = append(b.messages, message)
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
+
+// Messages returns the messages published so far, in the order they were published.
+func (b *MemoryBroker) Messages() []Message {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return append([]Message{}, b.messages...)
+}
+
+// SetError makes the following publishes fail with err, or succeed again when err is nil.
+func (b *MemoryBroker) SetError(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.err = err
+}
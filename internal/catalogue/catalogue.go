@@ -0,0 +1,150 @@
+// internal/catalogue/catalogue.go
+
+// Package catalogue reads and writes the catalogue files used to import and export the categories and
+// products of a client. A CSV or XLSX catalogue has one product per row, under a header row naming the
+// columns; a JSON catalogue is an array of products with the columns as keys.
+package catalogue
+
+import (
+	"fmt"
+	"io"
+	"maqhaa/product_service/internal/app/model"
+	"path/filepath"
+	"strings"
+)
+
+// Formats of the catalogue files.
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	FormatJSON = "json"
+)
+
+// Columns are the columns of a catalogue, in the order they are written. When reading, columns may come
+// in any order and unknown columns are ignored, but category, name and price are required.
+var Columns = []string{"category", "name", "description", "price", "image", "active"}
+
+var requiredColumns = []string{"category", "name", "price"}
+
+// FormatOf returns the format of a catalogue file from the extension of its name, or "" when unknown.
+func FormatOf(fileName string) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return FormatCSV
+	case ".xlsx":
+		return FormatXLSX
+	case ".json":
+		return FormatJSON
+	}
+	return ""
+}
+
+// ContentType returns the media type of the catalogue files in the format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatJSON:
+		return "application/json"
+	}
+	return "application/octet-stream"
+}
+
+// ReadRows reads the rows of a catalogue file in the format.
+func ReadRows(r io.Reader, format string) ([]model.CatalogueRow, error) {
+	switch format {
+	case FormatCSV:
+		return ReadCSV(r)
+	case FormatXLSX:
+		return ReadXLSX(r)
+	case FormatJSON:
+		return ReadJSON(r)
+	}
+	return nil, fmt.Errorf("unsupported catalogue format %q", format)
+}
+
+// WriteRows writes the rows as a catalogue file in the format.
+func WriteRows(w io.Writer, rows []model.CatalogueRow, format string) error {
+	switch format {
+	case FormatCSV:
+		return WriteCSV(w, rows)
+	case FormatXLSX:
+		return WriteXLSX(w, rows)
+	case FormatJSON:
+		return WriteJSON(w, rows)
+	}
+	return fmt.Errorf("unsupported catalogue format %q", format)
+}
+
+// rowsFromRecords converts the records of a sheet, the header first, into rows.
+// lines are the lines of the records in the file; blank records are skipped.
+func rowsFromRecords(records [][]string, lines []int) ([]model.CatalogueRow, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header")
+	}
+
+	index := map[string]int{}
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+	for _, name := range requiredColumns {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("missing column %s", name)
+		}
+	}
+
+	rows := []model.CatalogueRow{}
+	for n, record := range records[1:] {
+		if isBlank(record) {
+			continue
+		}
+
+		cell := func(column string) string {
+			i, ok := index[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return record[i]
+		}
+		rows = append(rows, trimRow(model.CatalogueRow{
+			Line:        lines[n+1],
+			Category:    cell("category"),
+			Name:        cell("name"),
+			Description: cell("description"),
+			Price:       cell("price"),
+			Image:       cell("image"),
+			Active:      cell("active"),
+		}))
+	}
+	return rows, nil
+}
+
+// trimRow trims the spaces around the cells of a row and lowercases its active flag.
+func trimRow(row model.CatalogueRow) model.CatalogueRow {
+	row.Category = strings.TrimSpace(row.Category)
+	row.Name = strings.TrimSpace(row.Name)
+	row.Description = strings.TrimSpace(row.Description)
+	row.Price = strings.TrimSpace(row.Price)
+	row.Image = strings.TrimSpace(row.Image)
+	row.Active = strings.ToLower(strings.TrimSpace(row.Active))
+	return row
+}
+
+// record returns the cells of a row in the order of Columns.
+func record(row model.CatalogueRow) []string {
+	return []string{row.Category, row.Name, row.Description, row.Price, row.Image, row.Active}
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+// internal/catalogue/csv.go
+
+package catalogue
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"maqhaa/product_service/internal/app/model"
+)
+
+// utf8BOM starts the CSV files saved by spreadsheet applications.
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// ReadCSV reads a catalogue from a CSV file. Fields are separated by commas, or by semicolons
+// when the header has semicolons but no commas, as written by spreadsheets in some locales.
+func ReadCSV(r io.Reader) ([]model.CatalogueRow, error) {
+	buffered := bufio.NewReader(r)
+	if prefix, err := buffered.Peek(len(utf8BOM)); err == nil && bytes.Equal(prefix, utf8BOM) {
+		buffered.Discard(len(utf8BOM))
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.FieldsPerRecord = -1
+	start, _ := buffered.Peek(buffered.Size())
+	if header := firstLine(start); !bytes.ContainsRune(header, ',') && bytes.ContainsRune(header, ';') {
+		reader.Comma = ';'
+	}
+
+	records := [][]string{}
+	lines := []int{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	return rowsFromRecords(records, lines)
+}
+
+// WriteCSV writes the rows as a comma separated CSV file, starting with the header.
+func WriteCSV(w io.Writer, rows []model.CatalogueRow) error {
+	writer := csv.NewWriter(w)
+	writer.Write(Columns)
+	for _, row := range rows {
+		writer.Write(record(row))
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func firstLine(data []byte) []byte {
+	if end := bytes.IndexByte(data, '\n'); end >= 0 {
+		return data[:end]
+	}
+	return data
+}
@@ -0,0 +1,105 @@
+// internal/catalogue/json.go
+
+package catalogue
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maqhaa/product_service/internal/app/model"
+	"strconv"
+)
+
+// jsonProduct is a product of a JSON catalogue.
+type jsonProduct struct {
+	Category    string     `json:"category"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Price       jsonScalar `json:"price"`
+	Image       string     `json:"image,omitempty"`
+	Active      jsonScalar `json:"active"`
+}
+
+// jsonScalar is a string, number or boolean read as written, so an invalid value fails the
+// validation of its row rather than the whole file.
+type jsonScalar struct {
+	value string
+	raw   json.RawMessage
+}
+
+func (s *jsonScalar) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		s.value = ""
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &s.value)
+	}
+	if len(data) > 0 && (data[0] == '{' || data[0] == '[') {
+		return fmt.Errorf("invalid value %s", data)
+	}
+	s.value = string(data)
+	return nil
+}
+
+func (s jsonScalar) MarshalJSON() ([]byte, error) {
+	if s.raw != nil {
+		return s.raw, nil
+	}
+	return json.Marshal(s.value)
+}
+
+// ReadJSON reads a catalogue from a JSON array of products. The line of a product is its position
+// in the array, counting from 1.
+func ReadJSON(r io.Reader) ([]model.CatalogueRow, error) {
+	var products []jsonProduct
+	if err := json.NewDecoder(r).Decode(&products); err != nil {
+		return nil, err
+	}
+
+	rows := make([]model.CatalogueRow, 0, len(products))
+	for i, product := range products {
+		record := []string{product.Category, product.Name, product.Description, product.Price.value, product.Image, product.Active.value}
+		if isBlank(record) {
+			continue
+		}
+		rows = append(rows, trimRow(model.CatalogueRow{
+			Line:        i + 1,
+			Category:    product.Category,
+			Name:        product.Name,
+			Description: product.Description,
+			Price:       product.Price.value,
+			Image:       product.Image,
+			Active:      product.Active.value,
+		}))
+	}
+	return rows, nil
+}
+
+// WriteJSON writes the rows as an indented JSON array. Prices are written as numbers and the
+// active flags as booleans when they are valid.
+func WriteJSON(w io.Writer, rows []model.CatalogueRow) error {
+	products := make([]jsonProduct, len(rows))
+	for i, row := range rows {
+		products[i] = jsonProduct{
+			Category:    row.Category,
+			Name:        row.Name,
+			Description: row.Description,
+			Price:       jsonScalar{value: row.Price},
+			Image:       row.Image,
+			Active:      jsonScalar{value: row.Active},
+		}
+		if _, err := strconv.ParseFloat(row.Price, 64); err == nil && json.Valid([]byte(row.Price)) {
+			products[i].Price.raw = json.RawMessage(row.Price)
+		}
+		if active, err := strconv.ParseBool(row.Active); err == nil {
+			products[i].Active.raw = json.RawMessage(strconv.FormatBool(active))
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(products)
+}
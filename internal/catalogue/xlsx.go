@@ -0,0 +1,77 @@
+// internal/catalogue/xlsx.go
+
+package catalogue
+
+import (
+	"fmt"
+	"io"
+	"maqhaa/product_service/internal/app/model"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ReadXLSX reads a catalogue from the first sheet of an XLSX workbook.
+// Cells are read unformatted, so prices are not altered by the number format of the sheet.
+func ReadXLSX(r io.Reader) ([]model.CatalogueRow, error) {
+	workbook, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer workbook.Close()
+
+	sheets := workbook.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("missing sheet")
+	}
+
+	records, err := workbook.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]int, len(records))
+	for i := range records {
+		lines[i] = i + 1
+	}
+	return rowsFromRecords(records, lines)
+}
+
+// WriteXLSX writes the rows to the first sheet of an XLSX workbook, starting with the header.
+// Prices are written as numbers, so they can be summed and sorted in a spreadsheet.
+func WriteXLSX(w io.Writer, rows []model.CatalogueRow) error {
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+
+	writer, err := workbook.NewStreamWriter(workbook.GetSheetName(0))
+	if err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(Columns))
+	for i, column := range Columns {
+		header[i] = column
+	}
+	if err := writer.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		cells := make([]interface{}, len(Columns))
+		for j, value := range record(row) {
+			cells[j] = value
+		}
+		if price, err := strconv.ParseFloat(row.Price, 64); err == nil {
+			cells[3] = price
+		}
+
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := writer.SetRow(cell, cells); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return workbook.Write(w)
+}
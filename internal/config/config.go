@@ -4,6 +4,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -18,6 +19,106 @@ type DatabaseConfig struct {
 	Debug    bool
 }
 
+// ImageRendition describes a resized copy generated for every uploaded image.
+// Format is one of jpeg, png or webp. Images narrower than Width are not enlarged.
+type ImageRendition struct {
+	Name   string
+	Width  uint
+	Format string
+}
+
+// ImageStorageConfig selects where images are stored. Driver is "local" (the default), which keeps
+// images in ImagePath, or "s3", which keeps them in Bucket of an S3-compatible service.
+// With a SignedURLExpiry, image URLs of the s3 driver are presigned for that long.
+type ImageStorageConfig struct {
+	Driver          string
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	UseSSL          bool
+	SignedURLExpiry time.Duration
+}
+
+// ImageValidationConfig limits the images accepted for products. A zero limit, or no AllowedFormats, disables the check.
+// AllowedFormats are format names as reported by image.Decode, e.g. jpeg or png.
+type ImageValidationConfig struct {
+	MaxBytes       int64
+	MinWidth       int
+	MinHeight      int
+	MaxWidth       int
+	MaxHeight      int
+	AllowedFormats []string
+}
+
+// ImageGCConfig schedules the removal of stored images no longer referenced by the database.
+// The collector runs every Interval, or never when Interval is zero, and keeps images younger than GracePeriod.
+type ImageGCConfig struct {
+	Interval    time.Duration
+	GracePeriod time.Duration
+}
+
+// IdempotencyConfig sets how long the responses to create requests sent with an Idempotency-Key are kept for
+// replay. Expired keys are removed every TTL; a zero TTL disables idempotency keys. A request holds its key
+// under a Lease, renewed every third of it while the request is in progress; a retry takes over a key whose
+// lease expired without a response, after the server stopped while handling its request. Lease defaults
+// to one minute.
+type IdempotencyConfig struct {
+	TTL   time.Duration
+	Lease time.Duration
+}
+
+// MenuEventsConfig schedules the delivery of menu changes to watchers. The changes are read from the outbox
+// every Interval, and as soon as this instance commits one; a change is held back while an earlier one is
+// not committed, for up to GapTimeout. A watcher resuming after a reconnect gets up to History changes it
+// missed, or a new snapshot when it missed more, and up to Buffer changes are queued for each watcher;
+// a watcher falling further behind is stopped and has to resume. Event streams over HTTP send a heartbeat
+// every Heartbeat, or none when zero.
+type MenuEventsConfig struct {
+	Interval   time.Duration
+	GapTimeout time.Duration
+	History    int
+	Buffer     int
+	Heartbeat  time.Duration
+}
+
+// BrokerConfig selects the message broker the domain events are published to. Driver is "nats", which
+// publishes to the JetStream stream capturing the subject Topic.<event type> of the NATS server at URL,
+// "kafka", which publishes to the topic Topic through the Kafka REST proxy at URL, or "memory", which keeps
+// the events in the process.
+type BrokerConfig struct {
+	Driver  string
+	URL     string
+	Topic   string
+	Timeout time.Duration
+}
+
+// OutboxConfig schedules the relay publishing the domain events of the outbox to the broker. The relay
+// runs every Interval, or never when Interval is zero or no broker driver is set, publishing up to
+// BatchSize events at a time. Published events are removed after Retention, or kept when zero.
+type OutboxConfig struct {
+	Interval  time.Duration
+	BatchSize int
+	Retention time.Duration
+	Broker    BrokerConfig
+}
+
+// WebhookConfig schedules the delivery of webhooks. Due deliveries are sent every Interval, and as soon as
+// they are queued, up to BatchSize at a time, each request bounded by Timeout; nothing is sent when Interval
+// is zero. A failed delivery is retried after InitialBackoff, doubled after every failure up to MaxBackoff,
+// and becomes a dead letter after MaxAttempts attempts. Webhooks must use https and public addresses, except
+// for the hosts of AllowedHosts, which are trusted.
+type WebhookConfig struct {
+	Interval       time.Duration
+	BatchSize      int
+	Timeout        time.Duration
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	AllowedHosts   []string
+}
+
 // Config holds the application configuration.
 type Config struct {
 	Database           DatabaseConfig
@@ -26,9 +127,18 @@ type Config struct {
 			Host string
 		}
 	}
-	AppPort   string
-	GrpcPort  string
-	ImagePath string
+	AppPort         string
+	GrpcPort        string
+	ImagePath       string
+	ImageBaseURL    string
+	ImageRenditions []ImageRendition
+	ImageStorage    ImageStorageConfig
+	ImageGC         ImageGCConfig
+	ImageValidation ImageValidationConfig
+	Idempotency     IdempotencyConfig
+	MenuEvents      MenuEventsConfig
+	Outbox          OutboxConfig
+	Webhook         WebhookConfig
 }
 
 // LoadConfig loads configuration from a specified file path, environment variables, and/or config files.
@@ -4,6 +4,7 @@ package database
 
 import (
 	"fmt"
+	"maqhaa/product_service/internal/app/entity"
 	"maqhaa/product_service/internal/config"
 
 	"gorm.io/driver/mysql"
@@ -28,3 +29,43 @@ func NewDB(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 
 	return db, nil
 }
+
+// Migrate creates or updates the tables that are owned by the product service.
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(
+		&entity.ProductTranslation{},
+		&entity.ProductCategoryTranslation{},
+		&entity.ProductImage{},
+		&entity.UploadedImage{},
+		&entity.ImageRendition{},
+		&entity.ImageBlob{},
+		&entity.IdempotencyKey{},
+		&entity.ProductTag{},
+		&entity.OutboxEvent{},
+		&entity.WebhookSubscription{},
+		&entity.WebhookDelivery{},
+	); err != nil {
+		return fmt.Errorf("error migrating database: %v", err)
+	}
+
+	// client, product and product_category are shared with the other services,
+	// only the columns the product service relies on are added.
+	columns := []struct {
+		table  interface{}
+		column string
+	}{
+		{&entity.Client{}, "DefaultLocale"},
+		{&entity.Product{}, "Version"},
+		{&entity.ProductCategory{}, "Version"},
+	}
+	for _, c := range columns {
+		if db.Migrator().HasColumn(c.table, c.column) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(c.table, c.column); err != nil {
+			return fmt.Errorf("error migrating database: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+	pb "maqhaa/product_service/internal/interface/grpc/model"
+)
+
+// ListCategories lists the categories of the client of the token, with their products when requested.
+func (h *ProductHandler) ListCategories(ctx context.Context, req *pb.ListCategoriesRequest) (*pb.ListCategoriesResponse, error) {
+	categories, appError := h.productService.GetProductGroupsByCategory(ctx, req.Token, requestLocale(ctx, req.Locale))
+
+	response := &pb.ListCategoriesResponse{
+		Code:    int32(appError.Code),
+		Message: service.LocalizeMessage(appError.Code, appError.Message, metadataLocale(ctx)),
+	}
+	if appError.Code != service.SuccessError {
+		return response, nil
+	}
+
+	response.Data = make([]*pb.CategoryData, 0, len(categories))
+	for i := range categories {
+		if !req.IncludeProducts {
+			categories[i].Products = nil
+		}
+		response.Data = append(response.Data, toCategoryData(&categories[i]))
+	}
+	return response, nil
+}
+
+// CreateCategory adds a category and returns it.
+func (h *ProductHandler) CreateCategory(ctx context.Context, req *pb.CreateCategoryRequest) (*pb.CategoryResponse, error) {
+	request := &model.ProductCategoryRequest{Category: req.Name}
+
+	appError := h.productService.AddProductCategoryService(ctx, request, req.Token)
+	if appError.Code != service.SuccessError {
+		return newCategoryResponse(ctx, appError, nil), nil
+	}
+
+	category, appError := h.productService.GetProductCategoryByID(ctx, request.ID, req.Token, metadataLocale(ctx))
+	return newCategoryResponse(ctx, appError, category), nil
+}
+
+// UpdateCategory renames a category at the version of the request and returns it.
+func (h *ProductHandler) UpdateCategory(ctx context.Context, req *pb.UpdateCategoryRequest) (*pb.CategoryResponse, error) {
+	if req.Version == 0 {
+		return newCategoryResponse(ctx, *service.NewVersionRequiredError(), nil), nil
+	}
+
+	request := &model.ProductCategoryRequest{ID: uint(req.Id), Version: uint(req.Version), Category: req.Name}
+
+	appError := h.productService.EditProductCategoryService(ctx, request, req.Token)
+	if appError.Code != service.SuccessError {
+		return newCategoryResponse(ctx, appError, nil), nil
+	}
+
+	category, appError := h.productService.GetProductCategoryByID(ctx, request.ID, req.Token, metadataLocale(ctx))
+	return newCategoryResponse(ctx, appError, category), nil
+}
+
+// DeactivateCategory deactivates a category at the version of the request.
+func (h *ProductHandler) DeactivateCategory(ctx context.Context, req *pb.DeactivateRequest) (*pb.DeactivateResponse, error) {
+	appError := *service.NewVersionRequiredError()
+	if req.Version != 0 {
+		appError = h.productService.DeleteProductCategoryService(ctx, uint(req.Id), uint(req.Version), req.Token)
+	}
+
+	return &pb.DeactivateResponse{
+		Code:    int32(appError.Code),
+		Message: service.LocalizeMessage(appError.Code, appError.Message, metadataLocale(ctx)),
+	}, nil
+}
+
+// newCategoryResponse returns the response of a category write, with the category when it succeeded.
+func newCategoryResponse(ctx context.Context, appError service.AppError, category *entity.ProductCategory) *pb.CategoryResponse {
+	locale := metadataLocale(ctx)
+	response := &pb.CategoryResponse{
+		Code:    int32(appError.Code),
+		Message: service.LocalizeMessage(appError.Code, appError.Message, locale),
+		Errors:  toFieldErrorData(service.LocalizeFieldErrors(appError.Errors, locale)),
+	}
+	if appError.Code == service.SuccessError && category != nil {
+		response.Data = toCategoryData(category)
+	}
+	return response
+}
+
+// toCategoryData converts a category entity, with the products it was loaded with, into its gRPC representation.
+func toCategoryData(category *entity.ProductCategory) *pb.CategoryData {
+	products := make([]*pb.ProductData, 0, len(category.Products))
+	for i := range category.Products {
+		products = append(products, toProductData(&category.Products[i]))
+	}
+
+	return &pb.CategoryData{
+		Id:        uint32(category.ID),
+		Name:      category.Name,
+		IsActive:  category.IsActive,
+		CreatedAt: category.CreatedAt.Format("2006-01-02 15:04:05"),
+		Version:   uint32(category.Version),
+		Products:  products,
+	}
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+	pb "maqhaa/product_service/internal/interface/grpc/model"
+)
+
+// WatchMenu streams the changes of the menu of the client of the token until the call is cancelled.
+// Errors, including a watcher falling behind, are sent as a last response with their code.
+func (h *ProductHandler) WatchMenu(req *pb.WatchMenuRequest, stream pb.Product_WatchMenuServer) error {
+	ctx := stream.Context()
+	request := &model.WatchMenuRequest{
+		Token:         req.Token,
+		Locale:        requestLocale(ctx, req.Locale),
+		AfterSequence: req.AfterSequence,
+	}
+
+	var sendError error
+	appError := h.productService.WatchMenu(ctx, request, func(event *model.MenuEvent) error {
+		sendError = stream.Send(&pb.WatchMenuResponse{
+			Code:    int32(service.SuccessError),
+			Message: service.SuccessMessage,
+			Event:   toMenuEvent(event),
+		})
+		return sendError
+	})
+	if sendError != nil {
+		return sendError
+	}
+	if appError.Code == service.SuccessError {
+		return nil
+	}
+
+	return stream.Send(&pb.WatchMenuResponse{
+		Code:    int32(appError.Code),
+		Message: service.LocalizeMessage(appError.Code, appError.Message, metadataLocale(ctx)),
+	})
+}
+
+// toMenuEvent converts a menu event into its gRPC representation.
+func toMenuEvent(event *model.MenuEvent) *pb.MenuEvent {
+	data := &pb.MenuEvent{
+		Sequence:   event.Sequence,
+		Type:       event.Type,
+		CreatedAt:  event.CreatedAt.Format("2006-01-02 15:04:05"),
+		ProductId:  uint32(event.ProductID),
+		CategoryId: uint32(event.CategoryID),
+	}
+	if event.Product != nil {
+		data.Product = toProductData(event.Product)
+	}
+	if event.Category != nil {
+		data.Category = toCategoryData(event.Category)
+	}
+	for i := range event.Categories {
+		data.Categories = append(data.Categories, toCategoryData(&event.Categories[i]))
+	}
+	return data
+}
@@ -2,8 +2,13 @@ package handler
 
 import (
 	"context"
+	"encoding/base64"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
 	"maqhaa/product_service/internal/app/service"
 	pb "maqhaa/product_service/internal/interface/grpc/model" // Update with your actual package name
+
+	"google.golang.org/grpc/metadata"
 )
 
 type ProductHandler struct {
@@ -16,13 +21,15 @@ func NewProductGRPCHandler(productService service.ProductService) *ProductHandle
 	}
 }
 func (h *ProductHandler) GetProduct(ctx context.Context, req *pb.GetProductRequest) (*pb.GetProductResponse, error) {
-	product, appError := h.productService.GetProductByID(ctx, uint(req.ProductId), req.Token)
+	locale := requestLocale(ctx, req.Locale)
+
+	product, appError := h.productService.GetProductByID(ctx, uint(req.ProductId), req.Token, locale)
 	var response *pb.GetProductResponse
 
 	if appError.Code != service.SuccessError {
 		response = &pb.GetProductResponse{
 			Code:    int32(appError.Code),
-			Message: appError.Message,
+			Message: service.LocalizeMessage(appError.Code, appError.Message, metadataLocale(ctx)),
 			Data:    nil,
 		}
 		return response, nil
@@ -30,17 +37,274 @@ func (h *ProductHandler) GetProduct(ctx context.Context, req *pb.GetProductReque
 
 	response = &pb.GetProductResponse{
 		Code:    int32(appError.Code),
-		Message: appError.Message,
-		Data: &pb.ProductData{
-			Id:          uint32(product.ID),
-			CategoryId:  uint32(product.CategoryID),
-			Name:        product.Name,
-			Price:       float32(product.Price),
-			Description: product.Description,
-			Image:       product.Image,
-			IsActive:    product.IsActive,
-			CreatedAt:   product.CreatedAt.Format("2006-01-02 15:04:05"),
-		},
+		Message: service.LocalizeMessage(appError.Code, appError.Message, metadataLocale(ctx)),
+		Data:    toProductData(product),
+	}
+	return response, nil
+}
+
+// GetProducts fetches several products in one call, with the IDs of the products not found or inactive.
+func (h *ProductHandler) GetProducts(ctx context.Context, req *pb.GetProductsRequest) (*pb.GetProductsResponse, error) {
+	request := &model.GetProductsRequest{ProductIDs: toUintIDs(req.ProductIds)}
+	products, missing, appError := h.productService.GetProductsByIDs(ctx, request, req.Token, requestLocale(ctx, req.Locale))
+
+	locale := metadataLocale(ctx)
+	response := &pb.GetProductsResponse{
+		Code:    int32(appError.Code),
+		Message: service.LocalizeMessage(appError.Code, appError.Message, locale),
+		Errors:  toFieldErrorData(service.LocalizeFieldErrors(appError.Errors, locale)),
+	}
+	if appError.Code != service.SuccessError {
+		return response, nil
+	}
+
+	response.Data = make([]*pb.ProductData, 0, len(products))
+	for i := range products {
+		response.Data = append(response.Data, toProductData(&products[i]))
+	}
+	response.MissingIds = make([]uint32, 0, len(missing))
+	for _, ID := range missing {
+		response.MissingIds = append(response.MissingIds, uint32(ID))
 	}
 	return response, nil
 }
+
+// ListProducts lists the products of the client of the token, or of one of its categories.
+func (h *ProductHandler) ListProducts(ctx context.Context, req *pb.ListProductsRequest) (*pb.ListProductsResponse, error) {
+	categories, appError := h.productService.GetProductGroupsByCategory(ctx, req.Token, requestLocale(ctx, req.Locale))
+
+	response := &pb.ListProductsResponse{
+		Code:    int32(appError.Code),
+		Message: service.LocalizeMessage(appError.Code, appError.Message, metadataLocale(ctx)),
+	}
+	if appError.Code != service.SuccessError {
+		return response, nil
+	}
+
+	response.Data = []*pb.ProductData{}
+	for _, category := range categories {
+		if req.CategoryId != 0 && uint32(category.ID) != req.CategoryId {
+			continue
+		}
+		for i := range category.Products {
+			response.Data = append(response.Data, toProductData(&category.Products[i]))
+		}
+	}
+	return response, nil
+}
+
+// CreateProduct adds a product and returns it.
+func (h *ProductHandler) CreateProduct(ctx context.Context, req *pb.CreateProductRequest) (*pb.ProductResponse, error) {
+	request := &model.ProductRequest{
+		CategoryID:  uint(req.CategoryId),
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		Image:       encodeImage(req.ImageData),
+		ImageID:     uint(req.ImageId),
+	}
+
+	appError := h.productService.AddProductService(ctx, request, req.Token)
+	if appError.Code != service.SuccessError {
+		return newProductResponse(ctx, appError, nil), nil
+	}
+
+	product, appError := h.productService.GetProductByID(ctx, request.ID, req.Token, metadataLocale(ctx))
+	return newProductResponse(ctx, appError, product), nil
+}
+
+// UpdateProduct replaces the fields of a product at the version of the request and returns it.
+func (h *ProductHandler) UpdateProduct(ctx context.Context, req *pb.UpdateProductRequest) (*pb.ProductResponse, error) {
+	if req.Version == 0 {
+		return newProductResponse(ctx, *service.NewVersionRequiredError(), nil), nil
+	}
+
+	request := &model.ProductRequest{
+		ID:          uint(req.Id),
+		Version:     uint(req.Version),
+		CategoryID:  uint(req.CategoryId),
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		Image:       encodeImage(req.ImageData),
+		ImageID:     uint(req.ImageId),
+	}
+
+	appError := h.productService.EditProductService(ctx, request, req.Token)
+	if appError.Code != service.SuccessError {
+		return newProductResponse(ctx, appError, nil), nil
+	}
+
+	product, appError := h.productService.GetProductByID(ctx, request.ID, req.Token, metadataLocale(ctx))
+	return newProductResponse(ctx, appError, product), nil
+}
+
+// DeactivateProduct deactivates a product at the version of the request.
+func (h *ProductHandler) DeactivateProduct(ctx context.Context, req *pb.DeactivateRequest) (*pb.DeactivateResponse, error) {
+	appError := *service.NewVersionRequiredError()
+	if req.Version != 0 {
+		appError = h.productService.DeleteProductService(ctx, uint(req.Id), uint(req.Version), req.Token)
+	}
+
+	return &pb.DeactivateResponse{
+		Code:    int32(appError.Code),
+		Message: service.LocalizeMessage(appError.Code, appError.Message, metadataLocale(ctx)),
+	}, nil
+}
+
+// BulkUpdateProducts applies the operations of the request to the selected products. The report is
+// returned also when a product fails and nothing is saved.
+func (h *ProductHandler) BulkUpdateProducts(ctx context.Context, req *pb.BulkUpdateProductsRequest) (*pb.BulkUpdateProductsResponse, error) {
+	request := &model.ProductBulkRequest{
+		ProductIDs:  toUintIDs(req.ProductIds),
+		CategoryIDs: toUintIDs(req.CategoryIds),
+		Operations:  make([]model.ProductBulkOperation, 0, len(req.Operations)),
+	}
+	for _, operation := range req.Operations {
+		request.Operations = append(request.Operations, model.ProductBulkOperation{
+			Type:       operation.Type,
+			Price:      operation.Price,
+			Percent:    operation.Percent,
+			CategoryID: uint(operation.CategoryId),
+			Tag:        operation.Tag,
+		})
+	}
+
+	report, appError := h.productService.BulkUpdateProductsService(ctx, request, req.Token)
+	locale := metadataLocale(ctx)
+
+	response := &pb.BulkUpdateProductsResponse{
+		Code:    int32(appError.Code),
+		Message: service.LocalizeMessage(appError.Code, appError.Message, locale),
+		Errors:  toFieldErrorData(service.LocalizeFieldErrors(appError.Errors, locale)),
+	}
+	if report == nil {
+		return response, nil
+	}
+
+	response.Data = &pb.BulkUpdateProductsData{
+		Applied:   report.Applied,
+		Updated:   int32(report.Updated),
+		Unchanged: int32(report.Unchanged),
+		Failed:    int32(report.Failed),
+		Results:   make([]*pb.BulkProductResult, 0, len(report.Results)),
+	}
+	for _, result := range report.Results {
+		data := &pb.BulkProductResult{
+			ProductId: uint32(result.ProductID),
+			Status:    result.Status,
+			Message:   result.Message,
+			Errors:    toFieldErrorData(service.LocalizeFieldErrors(result.Errors, locale)),
+		}
+		if result.Product != nil {
+			data.Product = toProductData(result.Product)
+		}
+		response.Data.Results = append(response.Data.Results, data)
+	}
+	return response, nil
+}
+
+// toProductData converts a product entity into its gRPC representation.
+func toProductData(product *entity.Product) *pb.ProductData {
+	images := make([]*pb.ProductImageData, 0, len(product.Images))
+	for _, image := range product.Images {
+		images = append(images, &pb.ProductImageData{
+			Id:            uint32(image.ID),
+			Url:           image.URL,
+			AltText:       image.AltText,
+			Position:      int32(image.Position),
+			IsPrimary:     image.IsPrimary,
+			Srcset:        image.SrcSet,
+			Blurhash:      image.BlurHash,
+			DominantColor: image.DominantColor,
+		})
+	}
+
+	tags := make([]string, 0, len(product.Tags))
+	for _, tag := range product.Tags {
+		tags = append(tags, tag.Tag)
+	}
+
+	return &pb.ProductData{
+		Id:                 uint32(product.ID),
+		CategoryId:         uint32(product.CategoryID),
+		Name:               product.Name,
+		Price:              float32(product.Price),
+		Description:        product.Description,
+		Image:              product.Image,
+		IsActive:           product.IsActive,
+		CreatedAt:          product.CreatedAt.Format("2006-01-02 15:04:05"),
+		ImageUrl:           product.ImageURL,
+		Images:             images,
+		ImageSrcset:        product.ImageSrcSet,
+		ImageBlurhash:      product.ImageBlurHash,
+		ImageDominantColor: product.ImageDominantColor,
+		Version:            uint32(product.Version),
+		Tags:               tags,
+	}
+}
+
+// newProductResponse returns the response of a product write, with the product when it succeeded.
+func newProductResponse(ctx context.Context, appError service.AppError, product *entity.Product) *pb.ProductResponse {
+	locale := metadataLocale(ctx)
+	response := &pb.ProductResponse{
+		Code:    int32(appError.Code),
+		Message: service.LocalizeMessage(appError.Code, appError.Message, locale),
+		Errors:  toFieldErrorData(service.LocalizeFieldErrors(appError.Errors, locale)),
+	}
+	if appError.Code == service.SuccessError && product != nil {
+		response.Data = toProductData(product)
+	}
+	return response
+}
+
+// encodeImage encodes the content of an image as the base64 the product service reads, "" when empty.
+func encodeImage(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// toFieldErrorData converts validation errors into their gRPC representation.
+func toFieldErrorData(fieldErrors []model.FieldError) []*pb.FieldErrorData {
+	data := make([]*pb.FieldErrorData, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		data = append(data, &pb.FieldErrorData{
+			Field:   fieldError.Field,
+			Rule:    fieldError.Rule,
+			Param:   fieldError.Param,
+			Message: fieldError.Message,
+		})
+	}
+	return data
+}
+
+func toUintIDs(ids []uint32) []uint {
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, uint(id))
+	}
+	return result
+}
+
+// requestLocale returns the locale of a request, falling back to the accept-language metadata.
+func requestLocale(ctx context.Context, locale string) string {
+	if locale != "" {
+		return locale
+	}
+	return metadataLocale(ctx)
+}
+
+// metadataLocale returns the most preferred locale of the accept-language metadata.
+func metadataLocale(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if values := md.Get("accept-language"); len(values) > 0 {
+		return service.ParseAcceptLanguage(values[0])
+	}
+	return ""
+}
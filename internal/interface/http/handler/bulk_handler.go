@@ -0,0 +1,47 @@
+// internal/handler/bulk_handler.go
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+// BulkUpdateProductsHandler handles the POST request applying a list of operations to many products at once.
+// The report of every product is returned, also when a product fails and nothing is saved.
+func (h *ProductHandler) BulkUpdateProductsHandler(w http.ResponseWriter, r *http.Request) {
+	var request *model.ProductBulkRequest
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request == nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	report, appError := h.productService.BulkUpdateProductsService(r.Context(), request, token)
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, report).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
+}
@@ -0,0 +1,163 @@
+// internal/handler/catalogue_handler.go
+
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+	"maqhaa/product_service/internal/catalogue"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// catalogueFormField is the name of the multipart field holding the catalogue file.
+	catalogueFormField = "file"
+	// maxCatalogueFileSize is the largest catalogue file accepted, in bytes.
+	maxCatalogueFileSize = 20 << 20
+)
+
+// ImportCatalogueHandler handles the POST multipart/form-data request importing a CSV, XLSX or JSON catalogue.
+// The format is taken from the format query parameter or the extension of the file name. With
+// dry_run=true the report of the changes is returned without saving them; images are only read
+// from http(s) URLs or stored images.
+func (h *ProductHandler) ImportCatalogueHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCatalogueFileSize+1<<20)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload multipart")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Infof("Invalid request payload multipart %s", err.Error())
+
+			appError = *service.NewInvalidFormatError()
+			response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+			sendJSONResponse(w, r, response, appError.Code)
+			return
+		}
+
+		if part.FormName() != catalogueFormField {
+			part.Close()
+			continue
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = catalogue.FormatOf(part.FileName())
+		}
+		rows, err := catalogue.ReadRows(part, format)
+		part.Close()
+		if err != nil {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Infof("Invalid catalogue %s", err.Error())
+
+			appError = *service.NewInvalidRequestError(err.Error())
+			response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+			sendJSONResponse(w, r, response, appError.Code)
+			return
+		}
+
+		request := &model.CatalogueImportRequest{Rows: rows, DryRun: dryRun}
+		report, appError := h.productService.ImportCatalogueService(r.Context(), request, token)
+
+		response := model.NewHTTPResponse(appError.Code, appError.Message, report).WithErrors(appError.Errors)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload file")
+
+	appError = *service.NewInvalidRequestError(catalogueFormField)
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// ExportCatalogueHandler handles the GET request exporting the catalogue of the client as a file download.
+// The format query parameter is csv (the default), xlsx or json; with include_inactive=true the inactive
+// categories and products are exported too.
+func (h *ProductHandler) ExportCatalogueHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = catalogue.FormatCSV
+	}
+	if format != catalogue.FormatCSV && format != catalogue.FormatXLSX && format != catalogue.FormatJSON {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Infof("Invalid catalogue format %s", format)
+
+		appError = *service.NewInvalidRequestError("format")
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	includeInactive, _ := strconv.ParseBool(r.URL.Query().Get("include_inactive"))
+
+	request := &model.CatalogueExportRequest{IncludeInactive: includeInactive}
+	rows, appError := h.productService.ExportCatalogueService(r.Context(), request, token)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	// The file is written to a buffer first, so a failure can still be answered with an error.
+	var file bytes.Buffer
+	if err := catalogue.WriteRows(&file, rows, format); err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error writing catalogue %s", err.Error())
+
+		appError = *service.NewGeneralSystemError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	w.Header().Set("Content-Type", catalogue.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="catalogue.%s"`, format))
+	w.Header().Set("Content-Length", strconv.Itoa(file.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(file.Bytes())
+}
@@ -0,0 +1,154 @@
+// internal/handler/clone_handler.go
+
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// CloneProductHandler handles the POST request copying a product. The body is optional and may set the
+// name and category of the copy. The copy is returned with its new ID.
+func (h *ProductHandler) CloneProductHandler(w http.ResponseWriter, r *http.Request) {
+	request := &model.CloneProductRequest{}
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	if err := decodeOptionalJSON(r, request); err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	productID, err := strconv.Atoi(vars["productID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload productID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	request.ProductID = uint(productID)
+
+	product, appError := h.productService.CloneProductService(r.Context(), request, token)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	setETag(w, product.Version)
+	response := model.NewHTTPResponse(appError.Code, appError.Message, product)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// CloneCategoryHandler handles the POST request copying a category with its products. The body is optional
+// and may set the name of the copy. The copy is returned with its products.
+func (h *ProductHandler) CloneCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	request := &model.CloneCategoryRequest{}
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	if err := decodeOptionalJSON(r, request); err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	categoryID, err := strconv.Atoi(vars["categoryID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload categoryID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	request.CategoryID = uint(categoryID)
+
+	category, appError := h.productService.CloneCategoryService(r.Context(), request, token)
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, category).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// CloneCatalogueHandler handles the POST request copying the whole catalogue of the client of the token
+// into the client of the target_token of the body. Both must be admin tokens.
+func (h *ProductHandler) CloneCatalogueHandler(w http.ResponseWriter, r *http.Request) {
+	var request *model.CloneCatalogueRequest
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request == nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	report, appError := h.productService.CloneCatalogueService(r.Context(), request, token)
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, report).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// decodeOptionalJSON decodes the JSON body of the request into v, leaving v unchanged when the body is empty.
+func decodeOptionalJSON(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+// internal/handler/etag.go
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"maqhaa/product_service/internal/app/service"
+)
+
+// ifMatchVersion returns the version in the If-Match header of a request changing a product or category:
+// the ETag of the version the change was made from, or * for any version, returned as 0.
+// Weak ETags are accepted as proxies compressing the responses may have weakened them.
+func ifMatchVersion(r *http.Request) (uint, service.AppError) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		return 0, *service.NewVersionRequiredError()
+	}
+	if ifMatch == "*" {
+		return 0, *service.NewSuccessError()
+	}
+
+	tag := strings.TrimPrefix(ifMatch, "W/")
+	if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		return 0, *service.NewInvalidFormatError()
+	}
+	version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 32)
+	if err != nil || version == 0 {
+		return 0, *service.NewInvalidFormatError()
+	}
+	return uint(version), *service.NewSuccessError()
+}
+
+// setETag sets the ETag of a response to the version of the product or category.
+func setETag(w http.ResponseWriter, version uint) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, version))
+}
@@ -0,0 +1,117 @@
+// internal/handler/idempotency_handler.go
+
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxIdempotencyKeyLength is the longest Idempotency-Key header accepted.
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyHandler replays the responses to create requests retried with the same Idempotency-Key header.
+type IdempotencyHandler struct {
+	idempotencyService service.IdempotencyService
+}
+
+// NewIdempotencyHandler creates a new IdempotencyHandler instance.
+func NewIdempotencyHandler(idempotencyService service.IdempotencyService) *IdempotencyHandler {
+	return &IdempotencyHandler{
+		idempotencyService: idempotencyService,
+	}
+}
+
+// Idempotent wraps a create handler so a request retried with the same Idempotency-Key gets the first
+// response again, marked with an Idempotent-Replayed header, instead of being processed twice.
+// Requests without the header are processed as usual.
+func (h *IdempotencyHandler) Idempotent(next func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+		key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+		token := r.Header.Get("Token")
+		if key == "" || token == "" {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil || len(key) > maxIdempotencyKeyLength {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+
+			appError := *service.NewInvalidFormatError()
+			response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+			sendJSONResponse(w, r, response, appError.Code)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		idempotencyKey, appError := h.idempotencyService.BeginRequest(r.Context(), token, key, requestHash(r, body))
+		if appError.Code != service.SuccessError {
+			response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+			sendJSONResponse(w, r, response, appError.Code)
+			return
+		}
+
+		if idempotencyKey.StatusCode != 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(idempotencyKey.StatusCode)
+			io.WriteString(w, idempotencyKey.Response)
+			return
+		}
+
+		// the key is held while the handler runs, and released when it panics so the request can be retried
+		stop := h.idempotencyService.HoldRequest(r.Context(), idempotencyKey)
+		recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		completed := false
+		defer func() {
+			stop()
+			if completed {
+				h.idempotencyService.CompleteRequest(r.Context(), idempotencyKey, recorder.statusCode, recorder.body.String())
+			} else {
+				h.idempotencyService.CompleteRequest(r.Context(), idempotencyKey, http.StatusInternalServerError, "")
+			}
+		}()
+
+		next(recorder, r)
+		completed = true
+	}
+}
+
+// requestHash identifies the method, path and body of a request.
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.Path+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder writes a response through and keeps a copy of its status code and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
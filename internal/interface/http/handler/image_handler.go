@@ -0,0 +1,129 @@
+// internal/handler/image_handler.go
+
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// imageCacheControl lets clients and proxies keep uploaded images for a year: they are named after
+	// the hash of their content, so the content behind a name never changes.
+	imageCacheControl = "public, max-age=31536000, immutable"
+	// variantCacheControl lets clients and proxies keep renditions and resized copies for a day before
+	// revalidating them: they are named after the image they come from, and are generated again when
+	// the renditions are configured differently or the cache is pruned.
+	variantCacheControl = "public, max-age=86400"
+)
+
+type imageHandler struct {
+	imageService service.ImageService
+}
+
+type ImageHandler interface {
+	ServeImageHandler(w http.ResponseWriter, r *http.Request)
+}
+
+func NewImageHandler(imageService service.ImageService) ImageHandler {
+	return &imageHandler{
+		imageService: imageService,
+	}
+}
+
+// ServeImageHandler handles the GET request for a stored image. The w and h query parameters
+// resize the image to fit in those bounds and format converts it to jpeg, png or webp.
+// Conditional and range requests are supported.
+func (h *imageHandler) ServeImageHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	request := &model.ImageRequest{
+		Name:   mux.Vars(r)["name"],
+		Format: r.URL.Query().Get("format"),
+	}
+
+	for param, value := range map[string]*uint{"w": &request.Width, "h": &request.Height} {
+		if r.URL.Query().Get(param) == "" {
+			continue
+		}
+		size, err := strconv.ParseUint(r.URL.Query().Get(param), 10, 32)
+		if err != nil {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Infof("Invalid request payload %s", param)
+
+			appError = *service.NewInvalidFormatError()
+			response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+			sendJSONResponse(w, r, response, appError.Code)
+			return
+		}
+		*value = uint(size)
+	}
+
+	file, appError := h.imageService.GetImageService(r.Context(), request)
+	if appError.Code == service.ImageNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Errorf("Error ServeImageHandler %s", err.Error())
+
+		appError = *service.NewGeneralSystemError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	uploaded := info.Name() == request.Name && isContentHashName(request.Name)
+	w.Header().Set("ETag", imageETag(info, uploaded))
+	if uploaded {
+		w.Header().Set("Cache-Control", imageCacheControl)
+	} else {
+		w.Header().Set("Cache-Control", variantCacheControl)
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// imageETag returns a strong ETag for a served file without reading it. The name of an uploaded image
+// identifies its content; other files are identified by their size and modification time as well,
+// which change when they are generated again.
+func imageETag(info os.FileInfo, uploaded bool) string {
+	key := info.Name()
+	if !uploaded {
+		key = fmt.Sprintf("%s/%d/%d", info.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	hash := sha256.Sum256([]byte(key))
+	return `"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+// isContentHashName reports whether name is the name of an uploaded image: the hex SHA-256 of its
+// content followed by its extension.
+func isContentHashName(name string) bool {
+	hash := strings.TrimSuffix(name, filepath.Ext(name))
+	if len(hash) != 2*sha256.Size {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
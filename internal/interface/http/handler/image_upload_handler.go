@@ -0,0 +1,81 @@
+// internal/handler/image_upload_handler.go
+
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+// imageFormField is the name of the multipart field holding the uploaded image.
+const imageFormField = "image"
+
+// UploadImageHandler handles the POST multipart/form-data request to upload an image.
+// The image is streamed to the service instead of being buffered as base64 JSON, and the
+// returned ID can be sent as image_id when adding or editing products.
+func (h *ProductHandler) UploadImageHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	// Leave room for the multipart boundaries and headers around the image.
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxImageUploadSize+1<<20)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload multipart")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Infof("Invalid request payload multipart %s", err.Error())
+
+			appError = *service.NewInvalidFormatError()
+			response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+			sendJSONResponse(w, r, response, appError.Code)
+			return
+		}
+
+		if part.FormName() != imageFormField {
+			part.Close()
+			continue
+		}
+
+		image, appError := h.productService.UploadImageService(r.Context(), part, token)
+		part.Close()
+
+		response := model.NewHTTPResponse(appError.Code, appError.Message, image).WithErrors(appError.Errors)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload image")
+
+	appError = *service.NewInvalidRequestError(imageFormField)
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+	sendJSONResponse(w, r, response, appError.Code)
+}
@@ -0,0 +1,176 @@
+// internal/handler/menu_events_handler.go
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// menuEventWriteTimeout bounds the write of an event; a client not reading its stream is disconnected.
+	menuEventWriteTimeout = 10 * time.Second
+	// menuEventRetry is the delay, in milliseconds, browsers wait before reconnecting a closed stream.
+	menuEventRetry = 3000
+)
+
+// MenuEventsHandler streams the changes of menus as server-sent events, for clients that cannot use gRPC.
+type MenuEventsHandler struct {
+	productService service.ProductService
+	heartbeat      time.Duration
+}
+
+// NewMenuEventsHandler creates a new MenuEventsHandler instance sending a heartbeat every heartbeat, or
+// none when zero.
+func NewMenuEventsHandler(productService service.ProductService, heartbeat time.Duration) *MenuEventsHandler {
+	return &MenuEventsHandler{
+		productService: productService,
+		heartbeat:      heartbeat,
+	}
+}
+
+// WatchMenuHandler handles the GET request streaming the changes of the menu of the client of the token as
+// server-sent events, named after the type of the event and identified by its sequence, the ID of the change
+// in the outbox. The token is read from the Token header or, since EventSource cannot send headers, from the
+// token query parameter. The stream resumes after the Last-Event-ID header or the last_event_id query
+// parameter, on any instance, and starts with a snapshot event when the ID is missing, unknown or too old.
+// A client falling behind the events gets an error event and resumes by reconnecting.
+func (h *MenuEventsHandler) WatchMenuHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var afterSequence uint64
+	if lastEventID != "" {
+		sequence, err := strconv.ParseUint(lastEventID, 10, 64)
+		// an ID this service did not send is unknown, and the stream starts over with a snapshot
+		if err != nil {
+			logging.Log.WithFields(logrus.Fields{"request_id": logID}).Infof("Invalid Last-Event-ID %s", lastEventID)
+			sequence = 0
+		}
+		afterSequence = sequence
+	}
+
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = service.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	}
+
+	request := &model.WatchMenuRequest{Token: token, Locale: locale, AfterSequence: afterSequence}
+	ctx, cancel := context.WithCancel(r.Context())
+	stream := &eventStream{w: w, controller: http.NewResponseController(w)}
+	var heartbeats sync.WaitGroup
+	started := false
+
+	appError = h.productService.WatchMenu(ctx, request, func(event *model.MenuEvent) error {
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			w.Header().Set("X-Accel-Buffering", "no")
+			w.WriteHeader(http.StatusOK)
+			if err := stream.write("retry: %d\n\n", menuEventRetry); err != nil {
+				return err
+			}
+
+			if h.heartbeat > 0 {
+				heartbeats.Add(1)
+				go func() {
+					defer heartbeats.Done()
+					stream.sendHeartbeats(ctx, cancel, h.heartbeat)
+				}()
+			}
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return stream.write("id: %d\nevent: %s\ndata: %s\n\n", event.Sequence, event.Type, data)
+	})
+	cancel()
+	heartbeats.Wait()
+
+	if appError.Code == service.SuccessError {
+		return
+	}
+	if !started {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+	if r.Context().Err() != nil {
+		return
+	}
+
+	logging.Log.WithFields(logrus.Fields{"request_id": logID}).Infof("Menu event stream ended: %s", appError.Message)
+	response := model.NewHTTPResponse(appError.Code, service.LocalizeMessage(appError.Code, appError.Message, locale), nil)
+	data, _ := json.Marshal(response)
+	stream.write("event: error\ndata: %s\n\n", data)
+}
+
+// eventStream writes server-sent events to a response, one write at a time.
+type eventStream struct {
+	mutex      sync.Mutex
+	w          http.ResponseWriter
+	controller *http.ResponseController
+}
+
+// write writes and flushes an event, failing when the client does not read it within menuEventWriteTimeout.
+func (s *eventStream) write(format string, args ...interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// without support for deadlines, a write to a client not reading blocks until the connection closes
+	s.controller.SetWriteDeadline(time.Now().Add(menuEventWriteTimeout))
+	if _, err := fmt.Fprintf(s.w, format, args...); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+// sendHeartbeats writes a comment every interval until ctx is done, so proxies keep the connection open
+// and broken connections are noticed. The stream is cancelled when a heartbeat cannot be written.
+func (s *eventStream) sendHeartbeats(ctx context.Context, cancel context.CancelFunc, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.write(": heartbeat\n\n"); err != nil {
+				cancel()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// internal/handler/merge_patch.go
+
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// decodeMergePatch decodes a JSON merge patch (RFC 7396) into patch, whose pointer fields stay nil
+// for the members left out. A null member removes the value: the string fields listed in nullable
+// are reset to empty, null is rejected for every other field.
+func decodeMergePatch(body io.Reader, patch interface{}, nullable ...string) error {
+	var members map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&members); err != nil {
+		return err
+	}
+	if members == nil {
+		return fmt.Errorf("merge patch must be a JSON object")
+	}
+
+	for name, value := range members {
+		if !bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			continue
+		}
+		if !contains(nullable, name) {
+			return fmt.Errorf("%s cannot be removed", name)
+		}
+		members[name] = json.RawMessage(`""`)
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, patch)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
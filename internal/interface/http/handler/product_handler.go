@@ -35,15 +35,16 @@ func (h *ProductHandler) GetProductGroupsByCategoryHandler(w http.ResponseWriter
 	if token == "" {
 		appError = *service.NewInvalidTokenError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 
-	categories, appError := h.productService.GetProductGroupsByCategory(r.Context(), token)
+	locale := service.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	categories, appError := h.productService.GetProductGroupsByCategory(r.Context(), token, locale)
 
 	// Respond with the fetched categories
 	response := model.NewHTTPResponse(appError.Code, appError.Message, categories)
-	sendJSONResponse(w, response, appError.Code)
+	sendJSONResponse(w, r, response, appError.Code)
 }
 
 func (h *ProductHandler) AddCategoryHandler(w http.ResponseWriter, r *http.Request) {
@@ -56,7 +57,7 @@ func (h *ProductHandler) AddCategoryHandler(w http.ResponseWriter, r *http.Reque
 	if token == "" {
 		appError = *service.NewInvalidTokenError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 
@@ -66,15 +67,15 @@ func (h *ProductHandler) AddCategoryHandler(w http.ResponseWriter, r *http.Reque
 
 		appError = *service.NewInvalidFormatError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 
 	appError = h.productService.AddProductCategoryService(r.Context(), request, token)
 
 	// Respond with the fetched categories
-	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-	sendJSONResponse(w, response, appError.Code)
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
 }
 
 func (h *ProductHandler) EditCategoryHandler(w http.ResponseWriter, r *http.Request) {
@@ -87,7 +88,14 @@ func (h *ProductHandler) EditCategoryHandler(w http.ResponseWriter, r *http.Requ
 	if token == "" {
 		appError = *service.NewInvalidTokenError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	version, appError := ifMatchVersion(r)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 
@@ -97,7 +105,7 @@ func (h *ProductHandler) EditCategoryHandler(w http.ResponseWriter, r *http.Requ
 
 		appError = *service.NewInvalidFormatError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 	vars := mux.Vars(r)
@@ -107,17 +115,75 @@ func (h *ProductHandler) EditCategoryHandler(w http.ResponseWriter, r *http.Requ
 
 		appError = *service.NewInvalidFormatError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 
 	request.ID = uint(categoryID)
+	request.Version = version
 
 	appError = h.productService.EditProductCategoryService(r.Context(), request, token)
+	if appError.Code == service.SuccessError {
+		setETag(w, request.Version)
+	}
 
 	// Respond with the fetched categories
-	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-	sendJSONResponse(w, response, appError.Code)
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// PatchCategoryHandler handles the PATCH request applying a JSON merge patch to a category.
+func (h *ProductHandler) PatchCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var request model.ProductCategoryPatchRequest
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	version, appError := ifMatchVersion(r)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	if err := decodeMergePatch(r.Body, &request); err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Infof("Invalid request payload %s", err.Error())
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	categoryID, err := strconv.Atoi(vars["categoryID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload categoryID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	request.ID = uint(categoryID)
+	request.Version = version
+
+	appError = h.productService.PatchProductCategoryService(r.Context(), &request, token)
+	if appError.Code == service.SuccessError {
+		setETag(w, request.Version)
+	}
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
 }
 
 func (h *ProductHandler) DeactiveCategoryHandler(w http.ResponseWriter, r *http.Request) {
@@ -129,7 +195,14 @@ func (h *ProductHandler) DeactiveCategoryHandler(w http.ResponseWriter, r *http.
 	if token == "" {
 		appError = *service.NewInvalidTokenError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	version, appError := ifMatchVersion(r)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 
@@ -140,15 +213,15 @@ func (h *ProductHandler) DeactiveCategoryHandler(w http.ResponseWriter, r *http.
 
 		appError = *service.NewInvalidFormatError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 
-	appError = h.productService.DeleteProductCategoryService(r.Context(), uint(categoryID), token)
+	appError = h.productService.DeleteProductCategoryService(r.Context(), uint(categoryID), version, token)
 
 	// Respond with the fetched categories
-	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-	sendJSONResponse(w, response, appError.Code)
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
 }
 
 func (h *ProductHandler) AddProductHandler(w http.ResponseWriter, r *http.Request) {
@@ -161,7 +234,7 @@ func (h *ProductHandler) AddProductHandler(w http.ResponseWriter, r *http.Reques
 	if token == "" {
 		appError = *service.NewInvalidTokenError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 
@@ -171,15 +244,15 @@ func (h *ProductHandler) AddProductHandler(w http.ResponseWriter, r *http.Reques
 
 		appError = *service.NewInvalidFormatError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 
 	appError = h.productService.AddProductService(r.Context(), request, token)
 
 	// Respond with the fetched categories
-	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-	sendJSONResponse(w, response, appError.Code)
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
 }
 
 func (h *ProductHandler) EditProductHandler(w http.ResponseWriter, r *http.Request) {
@@ -192,7 +265,14 @@ func (h *ProductHandler) EditProductHandler(w http.ResponseWriter, r *http.Reque
 	if token == "" {
 		appError = *service.NewInvalidTokenError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	version, appError := ifMatchVersion(r)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 
@@ -202,7 +282,7 @@ func (h *ProductHandler) EditProductHandler(w http.ResponseWriter, r *http.Reque
 
 		appError = *service.NewInvalidFormatError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 
@@ -213,17 +293,76 @@ func (h *ProductHandler) EditProductHandler(w http.ResponseWriter, r *http.Reque
 
 		appError = *service.NewInvalidFormatError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 
 	request.ID = uint(productID)
+	request.Version = version
 
 	appError = h.productService.EditProductService(r.Context(), request, token)
+	if appError.Code == service.SuccessError {
+		setETag(w, request.Version)
+	}
 
 	// Respond with the fetched categories
-	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-	sendJSONResponse(w, response, appError.Code)
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// PatchProductHandler handles the PATCH request applying a JSON merge patch to a product.
+// Fields left out of the patch, including the image, keep their value.
+func (h *ProductHandler) PatchProductHandler(w http.ResponseWriter, r *http.Request) {
+	var request model.ProductPatchRequest
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	version, appError := ifMatchVersion(r)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	if err := decodeMergePatch(r.Body, &request, "description"); err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Infof("Invalid request payload %s", err.Error())
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	productID, err := strconv.Atoi(vars["productID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload productID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	request.ID = uint(productID)
+	request.Version = version
+
+	appError = h.productService.PatchProductService(r.Context(), &request, token)
+	if appError.Code == service.SuccessError {
+		setETag(w, request.Version)
+	}
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
 }
 
 func (h *ProductHandler) DeactiveProductHandler(w http.ResponseWriter, r *http.Request) {
@@ -235,7 +374,14 @@ func (h *ProductHandler) DeactiveProductHandler(w http.ResponseWriter, r *http.R
 	if token == "" {
 		appError = *service.NewInvalidTokenError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	version, appError := ifMatchVersion(r)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 
@@ -246,13 +392,13 @@ func (h *ProductHandler) DeactiveProductHandler(w http.ResponseWriter, r *http.R
 
 		appError = *service.NewInvalidFormatError()
 		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-		sendJSONResponse(w, response, appError.Code)
+		sendJSONResponse(w, r, response, appError.Code)
 		return
 	}
 
-	appError = h.productService.DeleteProductService(r.Context(), uint(productID), token)
+	appError = h.productService.DeleteProductService(r.Context(), uint(productID), version, token)
 
 	// Respond with the fetched categories
-	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
-	sendJSONResponse(w, response, appError.Code)
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
 }
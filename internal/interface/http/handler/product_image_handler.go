@@ -0,0 +1,245 @@
+// internal/handler/product_image_handler.go
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// AddProductImageHandler handles the POST request to add an image to the gallery of a product.
+func (h *ProductHandler) AddProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	var request *model.ProductImageRequest
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	version, appError := ifMatchVersion(r)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request == nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	productID, err := strconv.Atoi(vars["productID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload productID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	request.ProductID = uint(productID)
+	request.Version = version
+
+	image, appError := h.productService.AddProductImageService(r.Context(), request, token)
+	if appError.Code == service.SuccessError {
+		setETag(w, request.Version)
+	}
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, image).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// EditProductImageHandler handles the PUT request to change the alt text or primary flag of a gallery image.
+func (h *ProductHandler) EditProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	var request *model.EditProductImageRequest
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	version, appError := ifMatchVersion(r)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request == nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	productID, err := strconv.Atoi(vars["productID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload productID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	imageID, err := strconv.Atoi(vars["imageID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload imageID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	request.ProductID = uint(productID)
+	request.ImageID = uint(imageID)
+	request.Version = version
+
+	appError = h.productService.EditProductImageService(r.Context(), request, token)
+	if appError.Code == service.SuccessError {
+		setETag(w, request.Version)
+	}
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// DeleteProductImageHandler handles the DELETE request to remove an image from the gallery of a product.
+func (h *ProductHandler) DeleteProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	version, appError := ifMatchVersion(r)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	productID, err := strconv.Atoi(vars["productID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload productID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	imageID, err := strconv.Atoi(vars["imageID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload imageID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	request := &model.RemoveProductImageRequest{ProductID: uint(productID), ImageID: uint(imageID), Version: version}
+	appError = h.productService.RemoveProductImageService(r.Context(), request, token)
+	if appError.Code == service.SuccessError {
+		setETag(w, request.Version)
+	}
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// ReorderProductImagesHandler handles the PUT request to change the order of the gallery of a product.
+func (h *ProductHandler) ReorderProductImagesHandler(w http.ResponseWriter, r *http.Request) {
+	var request *model.ReorderProductImagesRequest
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	version, appError := ifMatchVersion(r)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request == nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	productID, err := strconv.Atoi(vars["productID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload productID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	request.ProductID = uint(productID)
+	request.Version = version
+
+	appError = h.productService.ReorderProductImagesService(r.Context(), request, token)
+	if appError.Code == service.SuccessError {
+		setETag(w, request.Version)
+	}
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
+}
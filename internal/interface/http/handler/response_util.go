@@ -5,10 +5,14 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
 )
 
 // sendJSONResponse sends a JSON-encoded HTTP response.
-func sendJSONResponse(w http.ResponseWriter, response interface{}, errorCode int) {
+// Messages of a model.HTTPResponse are translated into the language of the Accept-Language header.
+func sendJSONResponse(w http.ResponseWriter, r *http.Request, response interface{}, errorCode int) {
 	statusCode := http.StatusOK
 	if errorCode > 0 && errorCode < 100 {
 		statusCode = http.StatusInternalServerError
@@ -30,6 +34,20 @@ func sendJSONResponse(w http.ResponseWriter, response interface{}, errorCode int
 		statusCode = http.StatusInternalServerError
 	}
 
+	if errorCode == service.VersionRequired {
+		statusCode = http.StatusPreconditionRequired
+	}
+
+	if errorCode == service.VersionConflict || errorCode == service.IdempotencyKeyConflict || errorCode == service.RequestInProgress {
+		statusCode = http.StatusConflict
+	}
+
+	if httpResponse, ok := response.(*model.HTTPResponse); ok {
+		locale := service.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+		httpResponse.Message = service.LocalizeMessage(httpResponse.Code, httpResponse.Message, locale)
+		httpResponse.Errors = service.LocalizeFieldErrors(httpResponse.Errors, locale)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(response)
@@ -0,0 +1,231 @@
+// internal/handler/translation_handler.go
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// GetProductTranslationsHandler handles the GET request to list the translations of a product.
+func (h *ProductHandler) GetProductTranslationsHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	productID, err := strconv.Atoi(vars["productID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload productID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	translations, appError := h.productService.GetProductTranslationsService(r.Context(), uint(productID), token)
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, translations)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// SaveProductTranslationHandler handles the PUT request to create or replace a product translation.
+func (h *ProductHandler) SaveProductTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	var request *model.ProductTranslationRequest
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request == nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	productID, err := strconv.Atoi(vars["productID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload productID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	request.ProductID = uint(productID)
+	request.Locale = vars["locale"]
+
+	appError = h.productService.SaveProductTranslationService(r.Context(), request, token)
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// DeleteProductTranslationHandler handles the DELETE request to remove a product translation.
+func (h *ProductHandler) DeleteProductTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	productID, err := strconv.Atoi(vars["productID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload productID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	appError = h.productService.DeleteProductTranslationService(r.Context(), uint(productID), vars["locale"], token)
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// GetCategoryTranslationsHandler handles the GET request to list the translations of a category.
+func (h *ProductHandler) GetCategoryTranslationsHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	categoryID, err := strconv.Atoi(vars["categoryID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload categoryID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	translations, appError := h.productService.GetCategoryTranslationsService(r.Context(), uint(categoryID), token)
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, translations)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// SaveCategoryTranslationHandler handles the PUT request to create or replace a category translation.
+func (h *ProductHandler) SaveCategoryTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	var request *model.CategoryTranslationRequest
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request == nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	categoryID, err := strconv.Atoi(vars["categoryID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload categoryID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	request.CategoryID = uint(categoryID)
+	request.Locale = vars["locale"]
+
+	appError = h.productService.SaveCategoryTranslationService(r.Context(), request, token)
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// DeleteCategoryTranslationHandler handles the DELETE request to remove a category translation.
+func (h *ProductHandler) DeleteCategoryTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	categoryID, err := strconv.Atoi(vars["categoryID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload categoryID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	appError = h.productService.DeleteCategoryTranslationService(r.Context(), uint(categoryID), vars["locale"], token)
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+	sendJSONResponse(w, r, response, appError.Code)
+}
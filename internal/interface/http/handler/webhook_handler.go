@@ -0,0 +1,182 @@
+// internal/handler/webhook_handler.go
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// WebhookHandler handles the webhooks of clients and their failed deliveries.
+type WebhookHandler struct {
+	webhookService service.WebhookService
+}
+
+// NewWebhookHandler creates a new WebhookHandler instance.
+func NewWebhookHandler(webhookService service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// AddWebhookHandler handles the POST request subscribing a URL to menu events. The webhook is returned
+// with its secret, which is not returned again.
+func (h *WebhookHandler) AddWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	request := &model.WebhookRequest{}
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	subscription, appError := h.webhookService.AddWebhookService(r.Context(), request, token)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil).WithErrors(appError.Errors)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, subscription)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// GetWebhooksHandler handles the GET request listing the webhooks of the client.
+func (h *WebhookHandler) GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	subscriptions, appError := h.webhookService.GetWebhooksService(r.Context(), token)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, subscriptions)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// DeleteWebhookHandler handles the DELETE request removing a webhook.
+func (h *WebhookHandler) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	webhookID, err := strconv.Atoi(vars["webhookID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload webhookID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	appError = h.webhookService.DeleteWebhookService(r.Context(), uint(webhookID), token)
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// GetDeadWebhookDeliveriesHandler handles the GET request listing the dead letters of the client: the most
+// recent deliveries that failed every attempt, with the error of the last one.
+func (h *WebhookHandler) GetDeadWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	deliveries, appError := h.webhookService.GetDeadWebhookDeliveriesService(r.Context(), token)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, deliveries)
+	sendJSONResponse(w, r, response, appError.Code)
+}
+
+// RedeliverWebhookHandler handles the POST request sending a delivery again, typically a dead letter.
+// The delivery is returned as queued.
+func (h *WebhookHandler) RedeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var appError service.AppError
+	logID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+
+	token := r.Header.Get("Token")
+
+	if token == "" {
+		appError = *service.NewInvalidTokenError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	vars := mux.Vars(r)
+	deliveryID, err := strconv.Atoi(vars["deliveryID"])
+	if err != nil {
+		logging.Log.WithFields(logrus.Fields{"request_id": logID}).Info("Invalid request payload deliveryID")
+
+		appError = *service.NewInvalidFormatError()
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	delivery, appError := h.webhookService.RedeliverWebhookService(r.Context(), uint(deliveryID), token)
+	if appError.Code != service.SuccessError {
+		response := model.NewHTTPResponse(appError.Code, appError.Message, nil)
+		sendJSONResponse(w, r, response, appError.Code)
+		return
+	}
+
+	response := model.NewHTTPResponse(appError.Code, appError.Message, delivery)
+	sendJSONResponse(w, r, response, appError.Code)
+}
@@ -28,6 +28,9 @@ func (*muxRouter) POST(uri string, f func(w http.ResponseWriter, r *http.Request
 func (*muxRouter) PUT(uri string, f func(w http.ResponseWriter, r *http.Request)) {
 	muxDispatcher.HandleFunc(uri, f).Methods("PUT")
 }
+func (*muxRouter) PATCH(uri string, f func(w http.ResponseWriter, r *http.Request)) {
+	muxDispatcher.HandleFunc(uri, f).Methods("PATCH")
+}
 func (*muxRouter) DELETE(uri string, f func(w http.ResponseWriter, r *http.Request)) {
 	muxDispatcher.HandleFunc(uri, f).Methods("DELETE")
 }
@@ -0,0 +1,141 @@
+// internal/placeholder/blurhash.go
+
+// Package placeholder computes the low quality previews shown by clients while an image loads:
+// a BlurHash (https://blurha.sh) and the dominant colour of the image.
+//
+// Both are computed from a small thumbnail of the image, which keeps them cheap for large photos
+// without visibly changing the result.
+package placeholder
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"strings"
+
+	"github.com/nfnt/resize"
+)
+
+const (
+	// thumbnailSize bounds the thumbnail the placeholders are computed from.
+	thumbnailSize = 32
+
+	// BlurHashXComponents and BlurHashYComponents are the number of horizontal and vertical
+	// components of the hashes computed by BlurHash, suited to landscape product photos.
+	BlurHashXComponents = 4
+	BlurHashYComponents = 3
+)
+
+const base83Characters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// BlurHash returns the BlurHash of an image with BlurHashXComponents x BlurHashYComponents components.
+func BlurHash(img image.Image) string {
+	return encodeBlurHash(thumbnail(img), BlurHashXComponents, BlurHashYComponents)
+}
+
+// encodeBlurHash encodes the image with xComponents x yComponents components, each between 1 and 9.
+func encodeBlurHash(img *image.NRGBA, xComponents int, yComponents int) string {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if width == 0 || height == 0 {
+		return ""
+	}
+
+	// Convert the pixels once, the basis functions visit every pixel for every component.
+	linear := make([][3]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := img.PixOffset(x, y)
+			linear[y*width+x] = [3]float64{sRGBToLinear(img.Pix[p]), sRGBToLinear(img.Pix[p+1]), sRGBToLinear(img.Pix[p+2])}
+		}
+	}
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				basisY := math.Cos(math.Pi * float64(j) * float64(y) / float64(height))
+				for x := 0; x < width; x++ {
+					basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(width)) * basisY
+					pixel := linear[y*width+x]
+					factor[0] += basis * pixel[0]
+					factor[1] += basis * pixel[1]
+					factor[2] += basis * pixel[2]
+				}
+			}
+
+			scale := normalisation / float64(width*height)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	var hash strings.Builder
+	hash.WriteString(encodeBase83((xComponents-1)+(yComponents-1)*9, 1))
+
+	dc, ac := factors[0], factors[1:]
+	maximumValue := 1.0
+	if len(ac) > 0 {
+		actualMaximum := 0.0
+		for _, factor := range ac {
+			actualMaximum = math.Max(actualMaximum, math.Max(math.Abs(factor[0]), math.Max(math.Abs(factor[1]), math.Abs(factor[2]))))
+		}
+		quantisedMaximum := int(math.Max(0, math.Min(82, math.Floor(actualMaximum*166-0.5))))
+		maximumValue = float64(quantisedMaximum+1) / 166
+		hash.WriteString(encodeBase83(quantisedMaximum, 1))
+	} else {
+		hash.WriteString(encodeBase83(0, 1))
+	}
+
+	hash.WriteString(encodeBase83(linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4))
+	for _, factor := range ac {
+		hash.WriteString(encodeBase83(quantiseAC(factor[0], maximumValue)*19*19+quantiseAC(factor[1], maximumValue)*19+quantiseAC(factor[2], maximumValue), 2))
+	}
+	return hash.String()
+}
+
+// thumbnail returns a copy of the image that fits in thumbnailSize x thumbnailSize.
+func thumbnail(img image.Image) *image.NRGBA {
+	small := resize.Thumbnail(thumbnailSize, thumbnailSize, img, resize.Bilinear)
+	bounds := small.Bounds()
+	result := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(result, result.Bounds(), small, bounds.Min, draw.Src)
+	return result
+}
+
+func quantiseAC(value float64, maximumValue float64) int {
+	return int(math.Max(0, math.Min(18, math.Floor(signPow(value/maximumValue, 0.5)*9+9.5))))
+}
+
+func encodeBase83(value int, length int) string {
+	result := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		result[i] = base83Characters[value%83]
+		value /= 83
+	}
+	return string(result)
+}
+
+func sRGBToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value float64, exponent float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exponent), value)
+}
@@ -0,0 +1,53 @@
+// internal/placeholder/color.go
+
+package placeholder
+
+import (
+	"fmt"
+	"image"
+)
+
+// colorBits is the number of bits per channel kept when grouping similar colours.
+const colorBits = 3
+
+// DominantColor returns the most common colour of an image as a CSS hex colour, e.g. #a0522d.
+// Pixels are grouped by the high bits of their channels and the colour returned is the average
+// of the largest group. Mostly transparent pixels are ignored; an image without opaque pixels is white.
+func DominantColor(img image.Image) string {
+	small := thumbnail(img)
+
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	buckets := map[int]*bucket{}
+	var dominant *bucket
+
+	for i := 0; i+3 < len(small.Pix); i += 4 {
+		r, g, b, a := int(small.Pix[i]), int(small.Pix[i+1]), int(small.Pix[i+2]), small.Pix[i+3]
+		if a < 0x80 {
+			continue
+		}
+
+		shift := 8 - colorBits
+		key := (r>>shift)<<(2*colorBits) | (g>>shift)<<colorBits | b>>shift
+		current, ok := buckets[key]
+		if !ok {
+			current = &bucket{}
+			buckets[key] = current
+		}
+		current.count++
+		current.r += r
+		current.g += g
+		current.b += b
+
+		if dominant == nil || current.count > dominant.count {
+			dominant = current
+		}
+	}
+
+	if dominant == nil {
+		return "#ffffff"
+	}
+	return fmt.Sprintf("#%02x%02x%02x", dominant.r/dominant.count, dominant.g/dominant.count, dominant.b/dominant.count)
+}
@@ -0,0 +1,17 @@
+// internal/webp/encode.go
+
+//go:build cgo
+
+package webp
+
+import (
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+// Encode writes the image m to w in lossy WebP format.
+func Encode(w io.Writer, m image.Image) error {
+	return webp.Encode(w, m, &webp.Options{Quality: Quality})
+}
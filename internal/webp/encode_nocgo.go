@@ -0,0 +1,15 @@
+// internal/webp/encode_nocgo.go
+
+//go:build !cgo
+
+package webp
+
+import (
+	"image"
+	"io"
+)
+
+// Encode reports ErrUnsupported: libwebp is only available with cgo.
+func Encode(w io.Writer, m image.Image) error {
+	return ErrUnsupported
+}
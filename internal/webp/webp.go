@@ -0,0 +1,14 @@
+// internal/webp/webp.go
+
+// Package webp encodes images in lossy WebP format with libwebp, which needs cgo. Built without cgo,
+// Encode reports ErrUnsupported.
+package webp
+
+import "errors"
+
+// Quality is the quality of the encoded images, from 0 to 100. WebP files at this quality are smaller
+// than the JPEG renditions at a similar visual quality.
+const Quality = 80
+
+// ErrUnsupported is returned by Encode when the service is built without cgo.
+var ErrUnsupported = errors.New("webp: encoding requires cgo")
@@ -0,0 +1,176 @@
+// bulk_handler_test.go
+
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	exModel "maqhaa/product_service/external/model"
+)
+
+// bulkResponse is the response of a bulk update, with the report as data.
+type bulkResponse struct {
+	Code int                     `json:"code"`
+	Data model.ProductBulkReport `json:"data"`
+}
+
+func bulkUpdateProducts(t *testing.T, token string, body string) (*httptest.ResponseRecorder, bulkResponse) {
+	req, err := http.NewRequest("POST", "/product/bulk", bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, uuid.New().String())
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(productHandler.BulkUpdateProductsHandler).ServeHTTP(rr, req)
+
+	var response bulkResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return rr, response
+}
+
+func TestBulkUpdateProducts_Positive(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	for _, category := range categories[:3] {
+		db.Create(category)
+	}
+	db.Create(&entity.ProductTag{ProductID: categories[0].Products[0].ID, Tag: "classic"})
+
+	// Clean up the testing environment
+	tables := []string{"product_tag", "product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	espresso, latte, greenTea := categories[0].Products[0], categories[0].Products[1], categories[1].Products[0]
+	body := `{
+		"category_ids": [` + uintString(categories[0].ID) + `],
+		"product_ids": [` + uintString(greenTea.ID) + `, ` + uintString(espresso.ID) + `],
+		"operations": [
+			{"type": "adjust_price", "percent": -10},
+			{"type": "add_tag", "tag": "Happy Hour"},
+			{"type": "remove_tag", "tag": "classic"},
+			{"type": "move_category", "category_id": ` + uintString(categories[2].ID) + `}
+		]
+	}`
+	rr, response := bulkUpdateProducts(t, token, body)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	report := response.Data
+	assert.True(t, report.Applied)
+	assert.Equal(t, 3, report.Updated)
+	assert.Equal(t, 0, report.Failed)
+	assert.Len(t, report.Results, 3)
+
+	var products []entity.Product
+	db.Preload("Tags").Where("id IN ?", []uint{espresso.ID, latte.ID, greenTea.ID}).Order("id asc").Find(&products)
+	assert.Len(t, products, 3)
+	for _, product := range products {
+		assert.Equal(t, categories[2].ID, product.CategoryID)
+		assert.Equal(t, uint(2), product.Version)
+		assert.Equal(t, []entity.ProductTag{{ProductID: product.ID, Tag: "happy hour"}}, stripTagTimes(product.Tags))
+	}
+	assert.Equal(t, 2.25, products[0].Price)
+	assert.Equal(t, 2.7, products[1].Price)
+	assert.Equal(t, 1.8, products[2].Price)
+
+	// applying the same tags again changes nothing
+	rr, response = bulkUpdateProducts(t, token, `{"product_ids": [`+uintString(espresso.ID)+`], "operations": [{"type": "add_tag", "tag": "happy hour"}, {"type": "activate"}]}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 1, response.Data.Unchanged)
+	assert.Equal(t, uint(2), response.Data.Results[0].Product.Version)
+}
+
+func TestBulkUpdateProducts_ProductNotFound(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	otherClient := SampleClient2()
+	otherClient.Token = "yyyyybbbbb"
+	db.Create(otherClient)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+	otherCategories := SampleCategories(otherClient.ID)
+	db.Create(otherCategories[1])
+
+	// Clean up the testing environment
+	tables := []string{"product_tag", "product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	// the product of another client is not found and nothing is changed
+	espresso, greenTea := categories[0].Products[0], otherCategories[1].Products[0]
+	body := `{"product_ids": [` + uintString(espresso.ID) + `, ` + uintString(greenTea.ID) + `], "operations": [{"type": "deactivate"}]}`
+	rr, response := bulkUpdateProducts(t, token, body)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+
+	report := response.Data
+	assert.False(t, report.Applied)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, model.BulkStatusFailed, report.Results[1].Status)
+	assert.Equal(t, greenTea.ID, report.Results[1].ProductID)
+
+	var product entity.Product
+	db.First(&product, espresso.ID)
+	assert.True(t, product.IsActive)
+	assert.Equal(t, uint(1), product.Version)
+
+	// nor can products be moved to the category of another client
+	body = `{"product_ids": [` + uintString(espresso.ID) + `], "operations": [{"type": "move_category", "category_id": ` + uintString(otherCategories[1].ID) + `}]}`
+	_, response = bulkUpdateProducts(t, token, body)
+	assert.Equal(t, service.InvalidToken, response.Code)
+}
+
+func TestBulkUpdateProducts_InvalidOperation(t *testing.T) {
+	rr, response := bulkUpdateProducts(t, "xxxxxaaaaa", `{"product_ids": [1], "operations": [{"type": "set_price", "price": -1}]}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+
+	var errorResponse model.HTTPResponse
+	json.Unmarshal(rr.Body.Bytes(), &errorResponse)
+	assert.Equal(t, "operations[0].price", errorResponse.Errors[0].Field)
+}
+
+func uintString(value uint) string {
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+func stripTagTimes(tags []entity.ProductTag) []entity.ProductTag {
+	for i := range tags {
+		tags[i].CreatedAt = time.Time{}
+	}
+	return tags
+}
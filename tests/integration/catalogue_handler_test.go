@@ -0,0 +1,347 @@
+// catalogue_handler_test.go
+
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+	"maqhaa/product_service/internal/catalogue"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	exModel "maqhaa/product_service/external/model"
+)
+
+// catalogueReport is the response of an import, with the report as data.
+type catalogueReport struct {
+	Code int                         `json:"code"`
+	Data model.CatalogueImportReport `json:"data"`
+}
+
+func importCatalogue(t *testing.T, token string, file string, query string) (*httptest.ResponseRecorder, catalogueReport) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "menu.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(file))
+	writer.Close()
+
+	req, err := http.NewRequest("POST", "/catalogue/import"+query, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, uuid.New().String())
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(productHandler.ImportCatalogueHandler).ServeHTTP(rr, req)
+
+	var response catalogueReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return rr, response
+}
+
+const sampleCatalogue = "category,name,description,price\n" +
+	"Coffee,Espresso,Strong coffee,2.5\n" +
+	"coffee,Latte,Coffee with milk,3.5\n" +
+	"Pastry,Croissant,Buttery,4\n"
+
+func TestImportCatalogue_DryRun(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+
+	// Clean up the testing environment
+	tables := []string{"product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	rr, response := importCatalogue(t, token, sampleCatalogue, "?dry_run=true")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	report := response.Data
+	assert.True(t, report.DryRun)
+	assert.False(t, report.Applied)
+	assert.Equal(t, []string{"Pastry"}, report.CreatedCategories)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Equal(t, model.CatalogueActionUnchanged, report.Rows[0].Action)
+	assert.Equal(t, model.CatalogueActionUpdate, report.Rows[1].Action)
+	assert.Equal(t, model.CatalogueActionCreate, report.Rows[2].Action)
+
+	// nothing is saved
+	var count int64
+	db.Model(&entity.ProductCategory{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	var latte entity.Product
+	db.Where("name = ?", "Latte").First(&latte)
+	assert.Equal(t, 3.0, latte.Price)
+}
+
+func TestImportCatalogue_Apply(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+
+	// Clean up the testing environment
+	tables := []string{"product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	rr, response := importCatalogue(t, token, sampleCatalogue, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, response.Data.Applied)
+
+	var latte entity.Product
+	db.Where("name = ?", "Latte").First(&latte)
+	assert.Equal(t, 3.5, latte.Price)
+	assert.Equal(t, uint(2), latte.Version)
+
+	var pastry entity.ProductCategory
+	result := db.Preload("Products").Where("client_id = ? AND name = ?", client.ID, "Pastry").First(&pastry)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	assert.Len(t, pastry.Products, 1)
+	assert.Equal(t, "Croissant", pastry.Products[0].Name)
+	assert.Equal(t, 4.0, pastry.Products[0].Price)
+	assert.True(t, pastry.Products[0].IsActive)
+
+	// importing the same catalogue again changes nothing
+	rr, response = importCatalogue(t, token, sampleCatalogue, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 3, response.Data.Unchanged)
+	assert.Empty(t, response.Data.CreatedCategories)
+}
+
+func TestImportCatalogue_Concurrent(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+
+	// Clean up the testing environment
+	tables := []string{"outbox_event", "product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	// imports of the same catalogue at the same time create its categories and products once
+	var wg sync.WaitGroup
+	responses := make([]catalogueReport, 2)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, responses[i] = importCatalogue(t, token, sampleCatalogue, "")
+		}(i)
+	}
+	wg.Wait()
+
+	for _, response := range responses {
+		assert.Equal(t, service.SuccessError, response.Code)
+	}
+
+	var count int64
+	db.Model(&entity.ProductCategory{}).Where("client_id = ? AND name = ?", client.ID, "Pastry").Count(&count)
+	assert.Equal(t, int64(1), count)
+	db.Model(&entity.Product{}).Where("name = ?", "Croissant").Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestImportCatalogue_InvalidRows(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	// Clean up the testing environment
+	tables := []string{"product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	requested := false
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer internal.Close()
+
+	file := "category,name,price,image\n" +
+		"Coffee,Espresso,2.5,\n" +
+		"Coffee,Latte,free,\n" +
+		"Coffee,espresso,3,\n" +
+		"Coffee,Mocha,3,/etc/passwd\n" +
+		"Coffee,Cappuccino,3," + internal.URL + "/cappuccino.jpeg\n"
+
+	rr, response := importCatalogue(t, token, file, "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+
+	report := response.Data
+	assert.False(t, report.Applied)
+	assert.Equal(t, 4, report.Failed)
+	assert.Equal(t, "price", report.Rows[1].Errors[0].Field)
+	assert.Equal(t, "numeric", report.Rows[1].Errors[0].Rule)
+	assert.Equal(t, "unique", report.Rows[2].Errors[0].Rule)
+	// local paths are not read through the API
+	assert.Equal(t, "image", report.Rows[3].Errors[0].Field)
+	// nor are the images of private addresses downloaded
+	assert.Equal(t, "image", report.Rows[4].Errors[0].Field)
+	assert.False(t, requested)
+
+	// the valid rows are not saved either
+	var count int64
+	db.Model(&entity.Product{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func exportCatalogue(t *testing.T, token string, query string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", "/catalogue/export"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, uuid.New().String())
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(productHandler.ExportCatalogueHandler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestExportCatalogue_CSV(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	categories[0].Products[1].IsActive = false
+	categories[1].IsActive = false
+	for _, category := range categories {
+		db.Create(category)
+	}
+
+	// Clean up the testing environment
+	tables := []string{"product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	rr := exportCatalogue(t, token, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="catalogue.csv"`, rr.Header().Get("Content-Disposition"))
+
+	chips := imagesRepository.GetURL("PR--1715681194302.jpeg")
+	assert.Equal(t, "category,name,description,price,image,active\n"+
+		"Coffee,Espresso,Strong coffee,2.5,,true\n"+
+		"Snacks,Chips,Crispy snacks,1.5,"+chips+",true\n", rr.Body.String())
+
+	rr = exportCatalogue(t, token, "?include_inactive=true")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rows, err := catalogue.ReadCSV(rr.Body)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 4)
+	assert.Equal(t, "Latte", rows[1].Name)
+	assert.Equal(t, "false", rows[1].Active)
+	assert.Equal(t, "Green Tea", rows[2].Name)
+}
+
+func TestExportCatalogue_RoundTrip(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	categories[0].Products[1].IsActive = false
+	for _, category := range categories[:2] {
+		db.Create(category)
+	}
+
+	// Clean up the testing environment
+	tables := []string{"product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	for _, format := range []string{catalogue.FormatJSON, catalogue.FormatXLSX} {
+		rr := exportCatalogue(t, token, "?include_inactive=1&format="+format)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, catalogue.ContentType(format), rr.Header().Get("Content-Type"))
+
+		// the exported catalogue imports back unchanged
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("file", "catalogue."+format)
+		part.Write(rr.Body.Bytes())
+		writer.Close()
+
+		req, _ := http.NewRequest("POST", "/catalogue/import?dry_run=true", &body)
+		req.Header.Set("Token", token)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, uuid.New().String()))
+		rr = httptest.NewRecorder()
+		http.HandlerFunc(productHandler.ImportCatalogueHandler).ServeHTTP(rr, req)
+
+		var response catalogueReport
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		assert.Equal(t, http.StatusOK, rr.Code, format)
+		assert.Equal(t, 3, response.Data.Unchanged, format)
+		assert.Equal(t, 0, response.Data.Created+response.Data.Updated, format)
+	}
+}
+
+func TestExportCatalogue_InvalidFormat(t *testing.T) {
+	rr := exportCatalogue(t, "xxxxxaaaaa", "?format=ods")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.True(t, strings.HasPrefix(rr.Header().Get("Content-Type"), "application/json"))
+
+	rr = exportCatalogue(t, "", "")
+	var response catalogueReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, service.InvalidToken, response.Code)
+}
@@ -0,0 +1,205 @@
+// clone_handler_test.go
+
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	exModel "maqhaa/product_service/external/model"
+)
+
+func cloneRequest(t *testing.T, method string, path string, route string, handler http.HandlerFunc, token string, body string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.HandleFunc(route, handler).Methods(method)
+
+	req, err := http.NewRequest(method, path, bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, uuid.New().String())
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCloneProduct_Positive(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+	db.Create(categories[2])
+
+	// Chips has a gallery of two images
+	chips := categories[2].Products[0]
+	db.Create(&entity.ImageBlob{Name: chips.Image, RefCount: 1})
+	db.Create(&entity.ImageBlob{Name: "side.jpeg", RefCount: 1})
+	db.Create(&[]entity.ProductImage{
+		{ProductID: chips.ID, FileName: chips.Image, IsPrimary: true},
+		{ProductID: chips.ID, FileName: "side.jpeg", AltText: "Side", Position: 1},
+	})
+	db.Create(&entity.ProductTranslation{ProductID: chips.ID, Locale: "id", Name: "Keripik", Description: "Camilan renyah"})
+	db.Create(&entity.ProductTag{ProductID: chips.ID, Tag: "vegan"})
+
+	// Clean up the testing environment
+	tables := []string{"product_tag", "product_translation", "image_blob", "product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	// the copy stays in the category of the product
+	path := "/product/" + strconv.Itoa(int(chips.ID)) + "/clone"
+	rr := cloneRequest(t, "POST", path, "/product/{productID}/clone", productHandler.CloneProductHandler, token, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"1"`, rr.Header().Get("ETag"))
+
+	var clone entity.Product
+	result := db.Preload("Images", func(db *gorm.DB) *gorm.DB { return db.Order("position asc") }).Preload("Tags").
+		Where("name = ?", "Chips (copy)").First(&clone)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	assert.Equal(t, chips.CategoryID, clone.CategoryID)
+	assert.Equal(t, chips.Price, clone.Price)
+	assert.Equal(t, chips.Image, clone.Image)
+	assert.Len(t, clone.Images, 2)
+	assert.Equal(t, "Side", clone.Images[1].AltText)
+	assert.Len(t, clone.Tags, 1)
+
+	var translation entity.ProductTranslation
+	db.Where("product_id = ? AND locale = ?", clone.ID, "id").First(&translation)
+	assert.Equal(t, "Keripik", translation.Name)
+
+	// the copy shares the stored images
+	var blobs []entity.ImageBlob
+	db.Order("name asc").Find(&blobs)
+	for _, blob := range blobs {
+		assert.Equal(t, 2, blob.RefCount, blob.Name)
+	}
+
+	// a copy into another category keeps the name
+	body := `{"category_id": ` + strconv.Itoa(int(categories[0].ID)) + `}`
+	rr = cloneRequest(t, "POST", path, "/product/{productID}/clone", productHandler.CloneProductHandler, token, body)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response struct {
+		Data entity.Product `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, "Chips", response.Data.Name)
+	assert.Equal(t, categories[0].ID, response.Data.CategoryID)
+}
+
+func TestCloneCategory_Positive(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	categories[0].Products[1].IsActive = false
+	db.Create(categories[0])
+	db.Create(&entity.ProductCategoryTranslation{CategoryID: categories[0].ID, Locale: "id", Name: "Kopi"})
+
+	// Clean up the testing environment
+	tables := []string{"product_tag", "product_category_translation", "product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	path := "/category/" + strconv.Itoa(int(categories[0].ID)) + "/clone"
+	rr := cloneRequest(t, "POST", path, "/category/{categoryID}/clone", productHandler.CloneCategoryHandler, token, `{"name": "Coffee Outlet 2"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var clone entity.ProductCategory
+	result := db.Preload("Products").Where("name = ?", "Coffee Outlet 2").First(&clone)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	assert.Equal(t, client.ID, clone.ClientID)
+	assert.Len(t, clone.Products, 2)
+	assert.False(t, clone.Products[1].IsActive)
+
+	var translation entity.ProductCategoryTranslation
+	db.Where("category_id = ? AND locale = ?", clone.ID, "id").First(&translation)
+	assert.Equal(t, "Kopi", translation.Name)
+}
+
+func TestCloneCatalogue_Positive(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	target := SampleClient2()
+	targetToken := "yyyyybbbbb"
+	target.Token = targetToken
+	db.Create(target)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+	userRepo.SetUserResponse(targetToken, &exModel.UserData{Id: 2, ClientId: uint32(target.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	for _, category := range categories {
+		db.Create(category)
+	}
+
+	// Clean up the testing environment
+	tables := []string{"product_tag", "image_blob", "product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	rr := cloneRequest(t, "POST", "/catalogue/clone", "/catalogue/clone", productHandler.CloneCatalogueHandler, token, `{"target_token": "`+targetToken+`"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response struct {
+		Code int                        `json:"code"`
+		Data model.CloneCatalogueReport `json:"data"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, service.SuccessError, response.Code)
+	assert.Equal(t, model.CloneCatalogueReport{Categories: 4, Products: 4, Images: 1}, response.Data)
+
+	var copied []entity.ProductCategory
+	db.Preload("Products").Where("client_id = ?", target.ID).Order("id asc").Find(&copied)
+	assert.Len(t, copied, 4)
+	assert.Equal(t, "Coffee", copied[0].Name)
+	assert.Len(t, copied[0].Products, 2)
+
+	// the image of Chips, stored before reference counting, is referenced by both products
+	var blob entity.ImageBlob
+	db.Where("name = ?", categories[2].Products[0].Image).First(&blob)
+	assert.Equal(t, 2, blob.RefCount)
+
+	// a catalogue cannot be cloned into its own client, nor with a user token
+	rr = cloneRequest(t, "POST", "/catalogue/clone", "/catalogue/clone", productHandler.CloneCatalogueHandler, token, `{"target_token": "`+token+`"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	userRepo.SetUserResponse(targetToken, &exModel.UserData{Id: 2, ClientId: uint32(target.ID), IsAdmin: false, IsLogin: true})
+	rr = cloneRequest(t, "POST", "/catalogue/clone", "/catalogue/clone", productHandler.CloneCatalogueHandler, token, `{"target_token": "`+targetToken+`"}`)
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, service.InvalidToken, response.Code)
+}
@@ -0,0 +1,183 @@
+// idempotency_handler_test.go
+
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	exModel "maqhaa/product_service/external/model"
+)
+
+func postCategory(t *testing.T, token string, key string, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "/category", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+	req.Header.Set("Idempotency-Key", key)
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, uuid.New().String())
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(idempotencyHandler.Idempotent(productHandler.AddCategoryHandler)).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAddCategory_IdempotencyKeyReplay(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	db.Create(client)
+	token := "xxxxxaaaaa"
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	// Clean up the testing environment
+	tables := []string{"idempotency_key", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	key := uuid.New().String()
+	first := postCategory(t, token, key, `{"category": "New Category"}`)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+	// the retry gets the first response and creates nothing
+	retry := postCategory(t, token, key, `{"category": "New Category"}`)
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+
+	var count int64
+	db.Model(&entity.ProductCategory{}).Where("client_id = ?", client.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// another key creates another category
+	other := postCategory(t, token, uuid.New().String(), `{"category": "New Category"}`)
+	assert.Equal(t, http.StatusOK, other.Code)
+	db.Model(&entity.ProductCategory{}).Where("client_id = ?", client.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestAddCategory_IdempotencyKeyConflict(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	db.Create(client)
+	token := "xxxxxaaaaa"
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	// Clean up the testing environment
+	tables := []string{"idempotency_key", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	key := uuid.New().String()
+	first := postCategory(t, token, key, `{"category": "New Category"}`)
+	assert.Equal(t, http.StatusOK, first.Code)
+
+	// the same key with another body is refused
+	rr := postCategory(t, token, key, `{"category": "Other Category"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	var response model.HTTPResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, service.IdempotencyKeyConflict, response.Code)
+
+	var count int64
+	db.Model(&entity.ProductCategory{}).Where("client_id = ?", client.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestAddCategory_IdempotencyKeyLease(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	db.Create(client)
+	token := "xxxxxaaaaa"
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	// Clean up the testing environment
+	tables := []string{"idempotency_key", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	key := uuid.New().String()
+	first := postCategory(t, token, key, `{"category": "New Category"}`)
+	assert.Equal(t, http.StatusOK, first.Code)
+
+	// a key whose request is still running holds a lease, and is not taken over
+	db.Model(&entity.IdempotencyKey{}).Where("client_id = ? AND `key` = ?", client.ID, key).
+		Updates(map[string]interface{}{"StatusCode": 0, "LeaseExpiresAt": time.Now().Add(time.Minute)})
+	rr := postCategory(t, token, key, `{"category": "New Category"}`)
+	var response model.HTTPResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, service.RequestInProgress, response.Code)
+
+	var stale entity.IdempotencyKey
+	db.Where("client_id = ? AND `key` = ?", client.ID, key).First(&stale)
+
+	// a key whose lease expired without a response, because its request never completed, is taken over
+	db.Model(&entity.IdempotencyKey{}).Where("client_id = ? AND `key` = ?", client.ID, key).
+		Update("LeaseExpiresAt", time.Now().Add(-time.Second))
+	rr = postCategory(t, token, key, `{"category": "New Category"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+
+	var count int64
+	db.Model(&entity.ProductCategory{}).Where("client_id = ?", client.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	// the request that lost the key completing late neither overwrites nor releases the new response
+	idempotencyService.CompleteRequest(context.Background(), &stale, http.StatusOK, "{}")
+	idempotencyService.CompleteRequest(context.Background(), &stale, http.StatusInternalServerError, "")
+
+	retry := postCategory(t, token, key, `{"category": "New Category"}`)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, rr.Body.String(), retry.Body.String())
+}
+
+func TestAddCategory_IdempotencyKeyExpired(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	db.Create(client)
+	token := "xxxxxaaaaa"
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	// Clean up the testing environment
+	tables := []string{"idempotency_key", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	// an expired key is processed again, even with another body
+	key := uuid.New().String()
+	db.Create(&entity.IdempotencyKey{ClientID: client.ID, Key: key, RequestHash: "expired", StatusCode: http.StatusOK, Response: "{}", ExpiresAt: time.Now().Add(-time.Minute)})
+
+	rr := postCategory(t, token, key, `{"category": "New Category"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+
+	var count int64
+	db.Model(&entity.ProductCategory{}).Where("client_id = ?", client.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// expired keys are purged, the key just used is kept
+	db.Create(&entity.IdempotencyKey{ClientID: client.ID, Key: uuid.New().String(), ExpiresAt: time.Now().Add(-time.Minute)})
+	purged, err := idempotencyService.PurgeExpiredKeys(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	db.Model(&entity.IdempotencyKey{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,181 @@
+// image_gc_test.go
+
+package handler_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/repository"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectImages_Orphans(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	db.Create(client)
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+	product := categories[0].Products[0]
+	images := []entity.ProductImage{
+		{ProductID: product.ID, FileName: "kept.png", IsPrimary: true},
+		{ProductID: product.ID, FileName: "missing.png", Position: 1},
+	}
+	db.Create(&images)
+
+	// Clean up the testing environment
+	tables := []string{"product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	imagePath := t.TempDir()
+	imagesRepository := repository.NewImagesRepository(imagePath, "", nil)
+	for _, name := range []string{"kept.png", "old-orphan.png", "new-orphan.png"} {
+		if err := imagesRepository.SaveImage([]byte(name), name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(imagePath, "old-orphan.png"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	gcService := service.NewImageGCService(imagesRepository, repository.NewProductImageRepository(db), repository.NewUploadedImageRepository(db), repository.NewUnitOfWork(db))
+
+	// a dry run only reports
+	report, err := gcService.CollectImages(context.Background(), 24*time.Hour, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"new-orphan.png", "old-orphan.png"}, report.Orphans)
+	assert.Contains(t, report.Missing, "missing.png")
+	assert.NotContains(t, report.Missing, "kept.png")
+	assert.Empty(t, report.Deleted)
+
+	// orphans younger than the grace period are kept
+	report, err = gcService.CollectImages(context.Background(), 24*time.Hour, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"old-orphan.png"}, report.Deleted)
+
+	stored, err := imagesRepository.ListImages()
+	assert.NoError(t, err)
+	names := []string{}
+	for _, image := range stored {
+		names = append(names, image.Name())
+	}
+	assert.Equal(t, []string{"kept.png", "new-orphan.png"}, names)
+}
+
+func TestCollectImages_KeepsRenditionsOfStoredImages(t *testing.T) {
+	// an image stored again, whose renditions are not recorded yet
+	db.Create(&entity.ImageBlob{Name: "stored.png", RefCount: 1})
+
+	// Clean up the testing environment
+	tables := []string{"image_blob"}
+	defer clearDB(tables)
+
+	imagePath := t.TempDir()
+	imagesRepository := repository.NewImagesRepository(imagePath, "", nil)
+	for _, name := range []string{"stored.png", "stored-thumbnail.webp", "removed-thumbnail.webp"} {
+		if err := imagesRepository.SaveImage([]byte(name), name); err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(-48 * time.Hour)
+		if err := os.Chtimes(filepath.Join(imagePath, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gcService := service.NewImageGCService(imagesRepository, repository.NewProductImageRepository(db), repository.NewUploadedImageRepository(db), repository.NewUnitOfWork(db))
+
+	// the references are checked again before removing, including the image a rendition was made of
+	report, err := gcService.CollectImages(context.Background(), 24*time.Hour, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"removed-thumbnail.webp", "stored-thumbnail.webp"}, report.Orphans)
+	assert.Equal(t, []string{"removed-thumbnail.webp"}, report.Deleted)
+}
+
+func TestCollectImages_ExpiresUploads(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	db.Create(client)
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+	product := categories[0].Products[0]
+
+	old := time.Now().Add(-48 * time.Hour)
+	// abandoned.png was uploaded two days ago and never attached, fresh.png was just uploaded,
+	// attached.png was uploaded two days ago and added to the gallery, which holds a reference too
+	uploads := []entity.UploadedImage{
+		{ClientID: client.ID, FileName: "abandoned.png", CreatedAt: old},
+		{ClientID: client.ID, FileName: "fresh.png"},
+		{ClientID: client.ID, FileName: "attached.png", CreatedAt: old},
+	}
+	db.Create(&uploads)
+	db.Create(&entity.ImageBlob{Name: "abandoned.png", RefCount: 1})
+	db.Create(&entity.ImageBlob{Name: "fresh.png", RefCount: 1})
+	db.Create(&entity.ImageBlob{Name: "attached.png", RefCount: 2})
+	db.Create(&entity.ImageRendition{ImageName: "abandoned.png", Name: "thumbnail", Format: "webp", FileName: "abandoned-thumbnail.webp"})
+	db.Create(&entity.ProductImage{ProductID: product.ID, FileName: "attached.png", IsPrimary: true})
+
+	// Clean up the testing environment
+	tables := []string{"image_rendition", "image_blob", "uploaded_image", "product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	imagePath := t.TempDir()
+	imagesRepository := repository.NewImagesRepository(imagePath, "", nil)
+	for _, name := range []string{"abandoned.png", "abandoned-thumbnail.webp", "fresh.png", "attached.png"} {
+		if err := imagesRepository.SaveImage([]byte(name), name); err != nil {
+			t.Fatal(err)
+		}
+		if name == "fresh.png" {
+			continue
+		}
+		if err := os.Chtimes(filepath.Join(imagePath, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gcService := service.NewImageGCService(imagesRepository, repository.NewProductImageRepository(db), repository.NewUploadedImageRepository(db), repository.NewUnitOfWork(db))
+
+	// a dry run expires nothing
+	report, err := gcService.CollectImages(context.Background(), 24*time.Hour, true)
+	assert.NoError(t, err)
+	assert.Empty(t, report.ExpiredUploads)
+	assert.Empty(t, report.Orphans)
+
+	report, err = gcService.CollectImages(context.Background(), 24*time.Hour, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abandoned.png", "attached.png"}, report.ExpiredUploads)
+	assert.Equal(t, []string{"abandoned-thumbnail.webp", "abandoned.png"}, report.Deleted)
+
+	// the fresh upload is kept, and the attached image keeps the reference of the gallery
+	var remaining []entity.UploadedImage
+	db.Find(&remaining)
+	assert.Len(t, remaining, 1)
+	assert.Equal(t, "fresh.png", remaining[0].FileName)
+
+	var blobs []entity.ImageBlob
+	db.Order("name asc").Find(&blobs)
+	assert.Len(t, blobs, 2)
+	assert.Equal(t, "attached.png", blobs[0].Name)
+	assert.Equal(t, 1, blobs[0].RefCount)
+	assert.Equal(t, "fresh.png", blobs[1].Name)
+
+	var renditions int64
+	db.Model(&entity.ImageRendition{}).Count(&renditions)
+	assert.Equal(t, int64(0), renditions)
+
+	stored, err := imagesRepository.ListImages()
+	assert.NoError(t, err)
+	names := []string{}
+	for _, image := range stored {
+		names = append(names, image.Name())
+	}
+	assert.Equal(t, []string{"attached.png", "fresh.png"}, names)
+}
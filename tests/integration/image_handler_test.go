@@ -0,0 +1,100 @@
+// image_handler_test.go
+
+package handler_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	httpHandler "maqhaa/product_service/internal/interface/http/handler"
+)
+
+func serveImageRequest(t *testing.T, url string, headers map[string]string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.HandleFunc("/images/{name}", httpHandler.NewImageHandler(service.NewImageService(imagesRepository)).ServeImageHandler).Methods("GET")
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, uuid.New().String())
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestServeImage_CachingHeaders(t *testing.T) {
+	imageData, err := base64.StdEncoding.DecodeString(SampleImagePNG())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(imageData)
+	imageName := hex.EncodeToString(hash[:]) + ".png"
+	if err := imagesRepository.SaveImage(imageData, imageName); err != nil {
+		t.Fatal(err)
+	}
+	defer imagesRepository.RemoveImage(imageName)
+
+	// uploaded images are named after their content, so they never change
+	rr := serveImageRequest(t, "/images/"+imageName, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+	assert.Equal(t, imageData, rr.Body.Bytes())
+	assert.Contains(t, rr.Header().Get("Cache-Control"), "immutable")
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// the ETag is strong and answers conditional requests
+	rr = serveImageRequest(t, "/images/"+imageName, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+
+	rr = serveImageRequest(t, "/images/"+imageName, map[string]string{"Range": "bytes=0-9"})
+	assert.Equal(t, http.StatusPartialContent, rr.Code)
+	assert.Equal(t, imageData[:10], rr.Body.Bytes())
+
+	// resized copies may be generated again, so they are revalidated
+	rr = serveImageRequest(t, "/images/"+imageName+"?w=10&format=webp", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/webp", rr.Header().Get("Content-Type"))
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"))
+	assert.Contains(t, rr.Header().Get("Cache-Control"), "max-age")
+	assert.NotContains(t, rr.Header().Get("Cache-Control"), "immutable")
+
+	// as are images not named after their content, such as renditions
+	otherName := fmt.Sprintf("PR-%d.png", time.Now().UnixNano())
+	if err := imagesRepository.SaveImage(imageData, otherName); err != nil {
+		t.Fatal(err)
+	}
+	defer imagesRepository.RemoveImage(otherName)
+
+	rr = serveImageRequest(t, "/images/"+otherName, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Header().Get("Cache-Control"), "immutable")
+}
+
+func TestServeImage_NotFound(t *testing.T) {
+	rr := serveImageRequest(t, "/images/missing.png", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = serveImageRequest(t, "/images/missing.png?w=abc", nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,101 @@
+// image_upload_handler_test.go
+
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	exModel "maqhaa/product_service/external/model"
+)
+
+func TestUploadImage_AddProductImage(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+	product := categories[0].Products[0]
+
+	// Clean up the testing environment
+	tables := []string{"image_blob", "image_rendition", "outbox_event", "uploaded_image", "product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	imageData, err := base64.StdEncoding.DecodeString(SampleImagePNG())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("image", "sample.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(imageData)
+	writer.Close()
+
+	req, err := http.NewRequest("POST", "/image", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, uuid.New().String())
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(productHandler.UploadImageHandler).ServeHTTP(rr, req)
+
+	var response struct {
+		Code int                  `json:"code"`
+		Data entity.UploadedImage `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, service.SuccessError, response.Code)
+	assert.NotZero(t, response.Data.ID)
+	assert.Equal(t, "image/png", response.Data.ContentType)
+	assert.NotEmpty(t, response.Data.Renditions)
+	assert.Contains(t, response.Data.SrcSet, "webp")
+	assert.Len(t, response.Data.BlurHash, 28)
+	assert.Regexp(t, "^#[0-9a-f]{6}$", response.Data.DominantColor)
+
+	var renditions []entity.ImageRendition
+	db.Where("image_name = ?", response.Data.FileName).Find(&renditions)
+	assert.Equal(t, len(response.Data.Renditions), len(renditions))
+
+	// reference the uploaded image instead of sending it again
+	url := fmt.Sprintf("/product/%d/image", product.ID)
+	added, _ := serveProductImageRequest(t, "POST", url, token, `*`, model.ProductImageRequest{ImageID: response.Data.ID})
+	assert.Equal(t, service.SuccessError, added.Code)
+
+	var image entity.ProductImage
+	db.Where("product_id = ?", product.ID).First(&image)
+	assert.Equal(t, response.Data.FileName, image.FileName)
+
+	// images of other clients or unknown images cannot be referenced
+	missing, _ := serveProductImageRequest(t, "POST", url, token, `*`, model.ProductImageRequest{ImageID: response.Data.ID + 1})
+	assert.Equal(t, service.ImageNotFound, missing.Code)
+}
@@ -0,0 +1,156 @@
+// menu_events_handler_test.go
+
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/stretchr/testify/assert"
+
+	exModel "maqhaa/product_service/external/model"
+)
+
+// serverSentEvent is an event read from a server-sent event stream.
+type serverSentEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// readServerSentEvent reads the next event of a stream, skipping comments and the retry field.
+func readServerSentEvent(t *testing.T, reader *bufio.Reader) serverSentEvent {
+	var event serverSentEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Error reading event stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "" && event.Event != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func watchMenu(t *testing.T, ctx context.Context, url string, header http.Header) *http.Response {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error watching the menu: %v", err)
+	}
+	return response
+}
+
+func TestWatchMenuHandler(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	for _, category := range categories[:2] {
+		db.Create(category)
+	}
+
+	// Clean up the testing environment
+	tables := []string{"product_tag", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	server := httptest.NewServer(http.HandlerFunc(menuEventsHandler.WatchMenuHandler))
+	defer server.Close()
+
+	espresso := categories[0].Products[0]
+
+	// the stream starts with a snapshot of the menu
+	ctx, cancel := context.WithCancel(context.Background())
+	response := watchMenu(t, ctx, server.URL+"?token="+token, nil)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(response.Body)
+	snapshot := readServerSentEvent(t, reader)
+	assert.Equal(t, model.MenuSnapshot, snapshot.Event)
+	var snapshotEvent model.MenuEvent
+	assert.NoError(t, json.Unmarshal([]byte(snapshot.Data), &snapshotEvent))
+	assert.Len(t, snapshotEvent.Categories, 2)
+	assert.Equal(t, snapshot.ID, fmt.Sprint(snapshotEvent.Sequence))
+
+	// then the changes of the menu
+	_, bulk := bulkUpdateProducts(t, token, fmt.Sprintf(`{"product_ids": [%d], "operations": [{"type": "set_price", "price": 30000}]}`, espresso.ID))
+	assert.Equal(t, service.SuccessError, bulk.Code)
+
+	changed := readServerSentEvent(t, reader)
+	assert.Equal(t, model.MenuProductUpdated, changed.Event)
+	var changedEvent model.MenuEvent
+	assert.NoError(t, json.Unmarshal([]byte(changed.Data), &changedEvent))
+	assert.Equal(t, espresso.ID, changedEvent.ProductID)
+	assert.Equal(t, 30000.0, changedEvent.Product.Price)
+	cancel()
+	response.Body.Close()
+
+	// a stream reconnecting with the Last-Event-ID gets the changes missed in the meantime
+	_, bulk = bulkUpdateProducts(t, token, fmt.Sprintf(`{"product_ids": [%d], "operations": [{"type": "deactivate"}]}`, espresso.ID))
+	assert.Equal(t, service.SuccessError, bulk.Code)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	response = watchMenu(t, ctx, server.URL, http.Header{"Token": {token}, "Last-Event-ID": {changed.ID}})
+	defer response.Body.Close()
+	missed := readServerSentEvent(t, bufio.NewReader(response.Body))
+	assert.Equal(t, model.MenuProductAvailability, missed.Event)
+
+	// an unknown Last-Event-ID starts over with a snapshot
+	for _, lastEventID := range []string{"abc", "18446744073709551615"} {
+		response := watchMenu(t, ctx, server.URL, http.Header{"Token": {token}, "Last-Event-ID": {lastEventID}})
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		restarted := readServerSentEvent(t, bufio.NewReader(response.Body))
+		response.Body.Close()
+		assert.Equal(t, model.MenuSnapshot, restarted.Event)
+	}
+}
+
+func TestWatchMenuHandler_Negative(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(menuEventsHandler.WatchMenuHandler))
+	defer server.Close()
+
+	// a token is required
+	response := watchMenu(t, context.Background(), server.URL, nil)
+	response.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	// errors before the stream starts are JSON responses
+	response = watchMenu(t, context.Background(), server.URL+"?token=unknown", nil)
+	var body model.HTTPResponse
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+	response.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Equal(t, service.InvalidToken, body.Code)
+}
@@ -0,0 +1,145 @@
+// outbox_test.go
+
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/repository"
+	"maqhaa/product_service/internal/app/service"
+	"maqhaa/product_service/internal/broker"
+
+	"github.com/stretchr/testify/assert"
+
+	exModel "maqhaa/product_service/external/model"
+)
+
+func outboxEvents(t *testing.T) []entity.OutboxEvent {
+	var events []entity.OutboxEvent
+	if err := db.Order("id asc").Find(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestOutbox_RecordsEventsInTheTransactionOfTheWrite(t *testing.T) {
+	// events of the other tests are not cleaned up
+	clearDB([]string{"outbox_event"})
+
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+
+	// Clean up the testing environment
+	tables := []string{"outbox_event", "product_tag", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	espresso := categories[0].Products[0]
+	_, bulk := bulkUpdateProducts(t, token, fmt.Sprintf(`{"product_ids": [%d], "operations": [{"type": "set_price", "price": 30000}]}`, espresso.ID))
+	assert.Equal(t, service.SuccessError, bulk.Code)
+	_, bulk = bulkUpdateProducts(t, token, fmt.Sprintf(`{"product_ids": [%d], "operations": [{"type": "deactivate"}]}`, espresso.ID))
+	assert.Equal(t, service.SuccessError, bulk.Code)
+
+	events := outboxEvents(t)
+	assert.Len(t, events, 2)
+	assert.Equal(t, entity.EventProductUpdated, events[0].Type)
+	assert.Equal(t, entity.EventProductDeactivated, events[1].Type)
+	// with the type of the change for the watchers of the menu
+	assert.Equal(t, model.MenuProductUpdated, events[0].MenuEventType)
+	assert.Equal(t, model.MenuProductAvailability, events[1].MenuEventType)
+	assert.Equal(t, entity.AggregateProduct, events[0].AggregateType)
+	assert.Equal(t, espresso.ID, events[0].AggregateID)
+	assert.Equal(t, client.ID, events[0].ClientID)
+	assert.Nil(t, events[0].PublishedAt)
+
+	var payload map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(events[0].Payload), &payload))
+	assert.Equal(t, 30000.0, payload["price"])
+
+	// a write rolled back leaves no event
+	ctx := context.Background()
+	failure := errors.New("failure")
+	err := repository.NewUnitOfWork(db).Do(ctx, func(repositories *repository.Repositories) error {
+		category := &entity.ProductCategory{ClientID: client.ID, Name: "Rolled back", IsActive: true}
+		if err := repositories.Product.AddProductCategory(ctx, category); err != nil {
+			return err
+		}
+		return failure
+	})
+	assert.Equal(t, failure, err)
+	assert.Len(t, outboxEvents(t), 2)
+
+	// a committed category write records a CategoryChanged event
+	category := &entity.ProductCategory{ClientID: client.ID, Name: "Committed", IsActive: true}
+	assert.NoError(t, repository.NewProductRepository(db).AddProductCategory(ctx, category))
+	events = outboxEvents(t)
+	assert.Len(t, events, 3)
+	assert.Equal(t, entity.EventCategoryChanged, events[2].Type)
+	assert.Equal(t, category.ID, events[2].AggregateID)
+}
+
+func TestOutboxRelay_PublishesEventsInOrder(t *testing.T) {
+	// events of the other tests are not cleaned up
+	clearDB([]string{"outbox_event"})
+
+	// create mock data
+	client := SampleClient()
+	db.Create(client)
+
+	// Clean up the testing environment
+	tables := []string{"outbox_event", "product_category", "client"}
+	defer clearDB(tables)
+
+	ctx := context.Background()
+	productRepository := repository.NewProductRepository(db)
+	for _, name := range []string{"Coffee", "Tea", "Juice"} {
+		assert.NoError(t, productRepository.AddProductCategory(ctx, &entity.ProductCategory{ClientID: client.ID, Name: name, IsActive: true}))
+	}
+
+	memoryBroker := broker.NewMemoryBroker()
+	relay := service.NewOutboxRelayService(repository.NewUnitOfWork(db), repository.NewOutboxRepository(db), memoryBroker)
+
+	// an unavailable broker publishes nothing, and the events are kept for the next relay
+	memoryBroker.SetError(errors.New("unavailable"))
+	published, err := relay.RelayEvents(ctx, 2)
+	assert.Error(t, err)
+	assert.Equal(t, 0, published)
+
+	memoryBroker.SetError(nil)
+	published, err = relay.RelayEvents(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, published)
+	published, err = relay.RelayEvents(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	published, err = relay.RelayEvents(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	messages := memoryBroker.Messages()
+	assert.Len(t, messages, 3)
+	events := outboxEvents(t)
+	for i, message := range messages {
+		assert.Equal(t, entity.EventCategoryChanged, message.Subject)
+		assert.Equal(t, fmt.Sprintf("category-%d", events[i].AggregateID), message.Key)
+
+		var event model.DomainEvent
+		assert.NoError(t, json.Unmarshal(message.Body, &event))
+		assert.Equal(t, fmt.Sprint(events[i].ID), event.ID)
+		assert.Equal(t, client.ID, event.ClientID)
+		assert.NotNil(t, events[i].PublishedAt)
+	}
+}
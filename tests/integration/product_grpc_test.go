@@ -0,0 +1,298 @@
+// product_grpc_test.go
+
+package handler_test
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"testing"
+
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+	pb "maqhaa/product_service/internal/interface/grpc/model"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	exModel "maqhaa/product_service/external/model"
+)
+
+func newProductClient(t *testing.T) (pb.ProductClient, func()) {
+	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Error creating gRPC client connection: %v", err)
+	}
+	return pb.NewProductClient(conn), func() { conn.Close() }
+}
+
+func TestCategoryGRPC_CreateUpdateDeactivate(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	// Clean up the testing environment
+	tables := []string{"product", "product_category", "client"}
+	defer clearDB(tables)
+
+	clientServer, closeConn := newProductClient(t)
+	defer closeConn()
+	ctx := context.Background()
+
+	created, err := clientServer.CreateCategory(ctx, &pb.CreateCategoryRequest{Token: token, Name: "Coffee"})
+	if err != nil {
+		t.Fatalf("Error calling CreateCategory gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), created.Code)
+	assert.NotZero(t, created.Data.Id)
+	assert.Equal(t, "Coffee", created.Data.Name)
+	assert.Equal(t, uint32(1), created.Data.Version)
+
+	// a name is required
+	invalid, err := clientServer.CreateCategory(ctx, &pb.CreateCategoryRequest{Token: token})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(service.InvalidRequestError), invalid.Code)
+	assert.Equal(t, "category", invalid.Errors[0].Field)
+
+	// updates need the current version
+	updated, err := clientServer.UpdateCategory(ctx, &pb.UpdateCategoryRequest{Token: token, Id: created.Data.Id, Name: "Coffees"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(service.VersionRequired), updated.Code)
+
+	updated, err = clientServer.UpdateCategory(ctx, &pb.UpdateCategoryRequest{Token: token, Id: created.Data.Id, Version: 1, Name: "Coffees"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(service.SuccessError), updated.Code)
+	assert.Equal(t, "Coffees", updated.Data.Name)
+	assert.Equal(t, uint32(2), updated.Data.Version)
+
+	deactivated, err := clientServer.DeactivateCategory(ctx, &pb.DeactivateRequest{Token: token, Id: created.Data.Id, Version: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(service.VersionConflict), deactivated.Code)
+
+	deactivated, err = clientServer.DeactivateCategory(ctx, &pb.DeactivateRequest{Token: token, Id: created.Data.Id, Version: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(service.SuccessError), deactivated.Code)
+
+	listed, err := clientServer.ListCategories(ctx, &pb.ListCategoriesRequest{Token: token})
+	assert.NoError(t, err)
+	assert.Len(t, listed.Data, 1)
+	assert.False(t, listed.Data[0].IsActive)
+	assert.Equal(t, uint32(3), listed.Data[0].Version)
+}
+
+func TestProductGRPC_CreateUpdateDeactivate(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	for _, category := range categories[:2] {
+		db.Create(category)
+	}
+
+	// Clean up the testing environment
+	tables := []string{"image_rendition", "image_blob", "product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	clientServer, closeConn := newProductClient(t)
+	defer closeConn()
+	ctx := context.Background()
+
+	image, err := base64.StdEncoding.DecodeString(SampleImagePNG())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := clientServer.CreateProduct(ctx, &pb.CreateProductRequest{
+		Token:       token,
+		CategoryId:  uint32(categories[0].ID),
+		Name:        "Cappuccino",
+		Description: "Foamy",
+		Price:       3.5,
+		ImageData:   image,
+	})
+	if err != nil {
+		t.Fatalf("Error calling CreateProduct gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), created.Code)
+	assert.Equal(t, "Cappuccino", created.Data.Name)
+	assert.NotEmpty(t, created.Data.Image)
+	assert.Len(t, created.Data.Images, 1)
+
+	updated, err := clientServer.UpdateProduct(ctx, &pb.UpdateProductRequest{
+		Token:       token,
+		Id:          created.Data.Id,
+		Version:     created.Data.Version,
+		CategoryId:  uint32(categories[1].ID),
+		Name:        "Cappuccino",
+		Description: "Foamy",
+		Price:       4,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(service.SuccessError), updated.Code)
+	assert.Equal(t, float32(4), updated.Data.Price)
+	assert.Equal(t, created.Data.Image, updated.Data.Image)
+	assert.Equal(t, created.Data.Version+1, updated.Data.Version)
+
+	listed, err := clientServer.ListProducts(ctx, &pb.ListProductsRequest{Token: token, CategoryId: uint32(categories[1].ID)})
+	assert.NoError(t, err)
+	assert.Len(t, listed.Data, 2)
+
+	listed, err = clientServer.ListProducts(ctx, &pb.ListProductsRequest{Token: token})
+	assert.NoError(t, err)
+	assert.Len(t, listed.Data, 4)
+
+	deactivated, err := clientServer.DeactivateProduct(ctx, &pb.DeactivateRequest{Token: token, Id: created.Data.Id})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(service.VersionRequired), deactivated.Code)
+
+	deactivated, err = clientServer.DeactivateProduct(ctx, &pb.DeactivateRequest{Token: token, Id: created.Data.Id, Version: updated.Data.Version})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(service.SuccessError), deactivated.Code)
+
+	// the categories are listed with their products on request
+	categoriesResponse, err := clientServer.ListCategories(ctx, &pb.ListCategoriesRequest{Token: token, IncludeProducts: true})
+	assert.NoError(t, err)
+	assert.Len(t, categoriesResponse.Data, 2)
+	assert.Len(t, categoriesResponse.Data[1].Products, 2)
+	assert.False(t, categoriesResponse.Data[1].Products[1].IsActive)
+}
+
+func TestProductGRPC_GetProducts(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	otherClient := SampleClient2()
+	otherClient.Token = "yyyyybbbbb"
+	db.Create(otherClient)
+
+	categories := SampleCategories(client.ID)
+	for _, category := range categories[:2] {
+		db.Create(category)
+	}
+	otherCategories := SampleCategories(otherClient.ID)
+	db.Create(otherCategories[1])
+
+	// Clean up the testing environment
+	tables := []string{"product", "product_category", "client"}
+	defer clearDB(tables)
+
+	espresso, latte, greenTea := categories[0].Products[0], categories[0].Products[1], categories[1].Products[0]
+	otherGreenTea := otherCategories[1].Products[0]
+	db.Model(&latte).Update("is_active", false)
+
+	clientServer, closeConn := newProductClient(t)
+	defer closeConn()
+	ctx := context.Background()
+
+	// inactive products, products of other clients and unknown IDs are missing
+	response, err := clientServer.GetProducts(ctx, &pb.GetProductsRequest{
+		Token:      token,
+		ProductIds: []uint32{uint32(greenTea.ID), uint32(latte.ID), uint32(espresso.ID), uint32(greenTea.ID), uint32(otherGreenTea.ID), 99999},
+	})
+	if err != nil {
+		t.Fatalf("Error calling GetProducts gRPC method: %v", err)
+	}
+	assert.Equal(t, int32(service.SuccessError), response.Code)
+	if assert.Len(t, response.Data, 2) {
+		assert.Equal(t, "Green Tea", response.Data[0].Name)
+		assert.Equal(t, "Espresso", response.Data[1].Name)
+	}
+	assert.Equal(t, []uint32{uint32(latte.ID), uint32(otherGreenTea.ID), 99999}, response.MissingIds)
+
+	// at least one ID is required
+	response, err = clientServer.GetProducts(ctx, &pb.GetProductsRequest{Token: token})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(service.InvalidRequestError), response.Code)
+	assert.Equal(t, "product_ids", response.Errors[0].Field)
+}
+
+func TestProductGRPC_WatchMenu(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	for _, category := range categories[:2] {
+		db.Create(category)
+	}
+
+	// Clean up the testing environment
+	tables := []string{"product_tag", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	espresso := categories[0].Products[0]
+
+	clientServer, closeConn := newProductClient(t)
+	defer closeConn()
+
+	// a watch starts with a snapshot of the menu
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := clientServer.WatchMenu(ctx, &pb.WatchMenuRequest{Token: token})
+	if err != nil {
+		t.Fatalf("Error calling WatchMenu gRPC method: %v", err)
+	}
+	snapshot, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(service.SuccessError), snapshot.Code)
+	assert.Equal(t, model.MenuSnapshot, snapshot.Event.Type)
+	assert.Len(t, snapshot.Event.Categories, 2)
+
+	// then sends the changes of the menu
+	bulk := &pb.BulkUpdateProductsRequest{
+		Token:      token,
+		ProductIds: []uint32{uint32(espresso.ID)},
+		Operations: []*pb.BulkProductOperation{{Type: model.BulkDeactivate}},
+	}
+	updated, err := clientServer.BulkUpdateProducts(context.Background(), bulk)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(service.SuccessError), updated.Code)
+
+	changed, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, model.MenuProductAvailability, changed.Event.Type)
+	assert.Equal(t, uint32(espresso.ID), changed.Event.ProductId)
+	assert.False(t, changed.Event.Product.IsActive)
+	assert.Greater(t, changed.Event.Sequence, snapshot.Event.Sequence)
+	cancel()
+
+	// a watch resuming after the last event received gets the changes missed in the meantime
+	bulk.Operations = []*pb.BulkProductOperation{{Type: model.BulkActivate}}
+	updated, err = clientServer.BulkUpdateProducts(context.Background(), bulk)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(service.SuccessError), updated.Code)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	stream, err = clientServer.WatchMenu(ctx, &pb.WatchMenuRequest{Token: token, AfterSequence: changed.Event.Sequence})
+	assert.NoError(t, err)
+	missed, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, model.MenuProductAvailability, missed.Event.Type)
+	assert.True(t, missed.Event.Product.IsActive)
+
+	// an unknown token ends the watch with an error
+	stream, err = clientServer.WatchMenu(ctx, &pb.WatchMenuRequest{Token: "unknown"})
+	assert.NoError(t, err)
+	invalid, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(service.InvalidToken), invalid.Code)
+	assert.Nil(t, invalid.Event)
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
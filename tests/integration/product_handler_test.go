@@ -460,6 +460,48 @@ func TestAddProductCategory_InvalidParam(t *testing.T) {
 	assert.Equal(t, service.InvalidRequestError, response.Code)
 }
 
+func TestAddProductCategory_InvalidParamLocalized(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	db.Create(client)
+	token := "xxxxxaaaaa"
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	// Clean up the testing environment
+	tables := []string{"product", "product_category", "client"}
+	defer clearDB(tables)
+
+	requestJSON, err := json.Marshal(model.ProductCategoryRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Mock HTTP request
+	req, err := http.NewRequest("POST", "/category", bytes.NewBuffer(requestJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+	req.Header.Set("Accept-Language", "id-ID,id;q=0.9")
+	requestID := uuid.New().String()
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(productHandler.AddCategoryHandler).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var response model.HTTPResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+	assert.Equal(t, "Permintaan Tidak Valid", response.Message)
+	assert.Equal(t, []model.FieldError{{Field: "category", Rule: "required", Message: "category wajib diisi"}}, response.Errors)
+}
+
 func TestEditProductCategory_Positive(t *testing.T) {
 	// create mock data
 	client := SampleClient()
@@ -496,6 +538,7 @@ func TestEditProductCategory_Positive(t *testing.T) {
 	}
 	requestID := uuid.New().String()
 	req.Header.Set("Token", token)
+	req.Header.Set("If-Match", `"1"`)
 	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
 	req = req.WithContext(ctx)
 
@@ -569,6 +612,7 @@ func TestEditProductCategory_InvalidToken(t *testing.T) {
 	}
 	requestID := uuid.New().String()
 	req.Header.Set("Token", "")
+	req.Header.Set("If-Match", `"1"`)
 	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
 	req = req.WithContext(ctx)
 
@@ -636,6 +680,7 @@ func TestEditProductCategory_CilentIDNotMatch(t *testing.T) {
 	}
 	requestID := uuid.New().String()
 	req.Header.Set("Token", token)
+	req.Header.Set("If-Match", `"1"`)
 	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
 	req = req.WithContext(ctx)
 
@@ -690,6 +735,7 @@ func TestDeactiveProductCategory_Positive(t *testing.T) {
 	}
 	requestID := uuid.New().String()
 	req.Header.Set("Token", token)
+	req.Header.Set("If-Match", `"1"`)
 	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
 	req = req.WithContext(ctx)
 
@@ -767,6 +813,7 @@ func TestDeactiveProductCategory_CilentIDNotMatch(t *testing.T) {
 	}
 	requestID := uuid.New().String()
 	req.Header.Set("Token", token)
+	req.Header.Set("If-Match", `"1"`)
 	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
 	req = req.WithContext(ctx)
 
@@ -834,6 +881,7 @@ func TestDeactiveProductCategory_InvalidToken(t *testing.T) {
 	}
 	requestID := uuid.New().String()
 	req.Header.Set("Token", "token")
+	req.Header.Set("If-Match", `"1"`)
 	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
 	req = req.WithContext(ctx)
 
@@ -875,7 +923,7 @@ func TestAddProduct_Success(t *testing.T) {
 	db.Create(categories[3])
 
 	// Clean up the testing environment
-	tables := []string{"product", "product_category", "client"}
+	tables := []string{"image_blob", "image_rendition", "product_image", "product", "product_category", "client"}
 	defer clearDB(tables)
 
 	// Create a login request
@@ -1005,7 +1053,7 @@ func TestAddProduct_InvalidImage(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	assert.Equal(t, service.InvalidRequestError, response.Code)
+	assert.Equal(t, service.UnsupportedImageFormat, response.Code)
 
 }
 
@@ -1091,7 +1139,7 @@ func TestEditProduct_Positive(t *testing.T) {
 	db.Create(categories[2])
 
 	// Clean up the testing environment
-	tables := []string{"product", "product_category", "client"}
+	tables := []string{"image_blob", "image_rendition", "product_image", "product", "product_category", "client"}
 	defer clearDB(tables)
 
 	// Create a login request
@@ -1121,6 +1169,7 @@ func TestEditProduct_Positive(t *testing.T) {
 	}
 	requestID := uuid.New().String()
 	req.Header.Set("Token", token)
+	req.Header.Set("If-Match", `"1"`)
 	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
 	req = req.WithContext(ctx)
 
@@ -1186,6 +1235,7 @@ func TestDeactiveProduct_Positive(t *testing.T) {
 	}
 	requestID := uuid.New().String()
 	req.Header.Set("Token", token)
+	req.Header.Set("If-Match", `"1"`)
 	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
 	req = req.WithContext(ctx)
 
@@ -1222,3 +1272,215 @@ func TestDeactiveProduct_Positive(t *testing.T) {
 
 	assert.Equal(t, false, product.IsActive)
 }
+
+func TestPatchProduct_Positive(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	categories[2].ID = 1
+	db.Create(categories[2])
+
+	// Clean up the testing environment
+	tables := []string{"product", "product_category", "client"}
+	defer clearDB(tables)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/product/{productID}", productHandler.PatchProductHandler).Methods("PATCH")
+
+	// Only the name is sent, every other field must be kept
+	original := categories[2].Products[0]
+	req, err := http.NewRequest("PATCH", "/product/"+strconv.Itoa(int(original.ID)), bytes.NewReader([]byte(`{"name": "Potato Chips"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestID := uuid.New().String()
+	req.Header.Set("Token", token)
+	req.Header.Set("If-Match", `"1"`)
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response model.HTTPResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	var product entity.Product
+	result := db.First(&product, original.ID)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+
+	assert.Equal(t, "Potato Chips", product.Name)
+	assert.Equal(t, original.Description, product.Description)
+	assert.Equal(t, original.Price, product.Price)
+	assert.Equal(t, original.Image, product.Image)
+	assert.Equal(t, true, product.IsActive)
+
+	// the response carries the new version
+	assert.Equal(t, uint(2), product.Version)
+	assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+}
+
+func TestPatchProduct_NullName(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	categories[2].ID = 1
+	db.Create(categories[2])
+
+	// Clean up the testing environment
+	tables := []string{"product", "product_category", "client"}
+	defer clearDB(tables)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/product/{productID}", productHandler.PatchProductHandler).Methods("PATCH")
+
+	// The name is required, it cannot be removed
+	req, err := http.NewRequest("PATCH", "/product/"+strconv.Itoa(int(categories[2].Products[0].ID)), bytes.NewReader([]byte(`{"name": null}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+	req.Header.Set("If-Match", `"1"`)
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, uuid.New().String())
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var response model.HTTPResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, service.InvalidFormatError, response.Code)
+
+	var product entity.Product
+	db.First(&product, categories[2].Products[0].ID)
+	assert.Equal(t, categories[2].Products[0].Name, product.Name)
+}
+
+func TestPatchProductCategory_Positive(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+
+	// Clean up the testing environment
+	tables := []string{"product", "product_category", "client"}
+	defer clearDB(tables)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/category/{categoryID}", productHandler.PatchCategoryHandler).Methods("PATCH")
+
+	req, err := http.NewRequest("PATCH", "/category/"+strconv.Itoa(int(categories[0].ID)), bytes.NewReader([]byte(`{"category": "Hot Coffee"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+	req.Header.Set("If-Match", `"1"`)
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, uuid.New().String())
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var category entity.ProductCategory
+	result := db.First(&category, categories[0].ID)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	assert.Equal(t, "Hot Coffee", category.Name)
+}
+
+func TestPatchProduct_VersionConflict(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	categories[2].ID = 1
+	db.Create(categories[2])
+
+	// Clean up the testing environment
+	tables := []string{"product", "product_category", "client"}
+	defer clearDB(tables)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/product/{productID}", productHandler.PatchProductHandler).Methods("PATCH")
+
+	patchProduct := func(body string, ifMatch string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("PATCH", "/product/"+strconv.Itoa(int(categories[2].Products[0].ID)), bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Token", token)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		ctx := context.WithValue(req.Context(), middleware.RequestIDKey, uuid.New().String())
+		req = req.WithContext(ctx)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// two managers edit the same version, the second edit is rejected
+	rr := patchProduct(`{"name": "Potato Chips"}`, `"1"`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = patchProduct(`{"price": 2.5}`, `"1"`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	var response model.HTTPResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, service.VersionConflict, response.Code)
+
+	// writes without If-Match are refused
+	rr = patchProduct(`{"price": 2.5}`, "")
+	assert.Equal(t, http.StatusPreconditionRequired, rr.Code)
+
+	var product entity.Product
+	result := db.First(&product, categories[2].Products[0].ID)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	assert.Equal(t, "Potato Chips", product.Name)
+	assert.Equal(t, categories[2].Products[0].Price, product.Price)
+	assert.Equal(t, uint(2), product.Version)
+}
@@ -0,0 +1,251 @@
+// product_image_handler_test.go
+
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	exModel "maqhaa/product_service/external/model"
+)
+
+func productImageRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/product/{productID}/image", productHandler.AddProductImageHandler).Methods("POST")
+	router.HandleFunc("/product/{productID}/image/order", productHandler.ReorderProductImagesHandler).Methods("PUT")
+	router.HandleFunc("/product/{productID}/image/{imageID:[0-9]+}", productHandler.EditProductImageHandler).Methods("PUT")
+	router.HandleFunc("/product/{productID}/image/{imageID:[0-9]+}", productHandler.DeleteProductImageHandler).Methods("DELETE")
+	return router
+}
+
+// serveProductImageRequest serves a gallery request made from the version in ifMatch, and returns the
+// response with the ETag of the new version.
+func serveProductImageRequest(t *testing.T, method string, url string, token string, ifMatch string, body interface{}) (model.HTTPResponse, string) {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", token)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, uuid.New().String())
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	productImageRouter().ServeHTTP(rr, req)
+
+	var response model.HTTPResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response, rr.Header().Get("ETag")
+}
+
+func TestAddProductImage_Gallery(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+	product := categories[0].Products[0]
+
+	// Clean up the testing environment
+	tables := []string{"image_blob", "image_rendition", "outbox_event", "product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	url := fmt.Sprintf("/product/%d/image", product.ID)
+	// each change of the gallery is made from the last version of the product
+	first, etag := serveProductImageRequest(t, "POST", url, token, `"1"`, model.ProductImageRequest{Image: SampleImagePNG(), AltText: "front"})
+	assert.Equal(t, service.SuccessError, first.Code)
+	assert.Equal(t, `"2"`, etag)
+	second, etag := serveProductImageRequest(t, "POST", url, token, etag, model.ProductImageRequest{Image: SampleImage(), AltText: "side", IsPrimary: true})
+	assert.Equal(t, service.SuccessError, second.Code)
+
+	var images []entity.ProductImage
+	db.Where("product_id = ?", product.ID).Order("position asc").Find(&images)
+	assert.Equal(t, 2, len(images))
+	assert.Equal(t, "front", images[0].AltText)
+	assert.False(t, images[0].IsPrimary)
+	assert.True(t, images[1].IsPrimary)
+
+	var stored entity.Product
+	db.First(&stored, product.ID)
+	assert.Equal(t, images[1].FileName, stored.Image)
+	assert.Equal(t, uint(3), stored.Version)
+
+	// reverse the order of the gallery
+	response, etag := serveProductImageRequest(t, "PUT", url+"/order", token, etag, model.ReorderProductImagesRequest{ImageIDs: []uint{images[1].ID, images[0].ID}})
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	db.Where("product_id = ?", product.ID).Order("position asc").Find(&images)
+	assert.Equal(t, "side", images[0].AltText)
+
+	// removing the primary image promotes the next one
+	response, _ = serveProductImageRequest(t, "DELETE", fmt.Sprintf("%s/%d", url, images[0].ID), token, etag, nil)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	db.First(&stored, product.ID)
+	assert.Equal(t, images[1].FileName, stored.Image)
+	assert.Equal(t, uint(5), stored.Version)
+}
+
+func TestEditProductImage_Version(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+	product := categories[0].Products[0]
+	images := []entity.ProductImage{
+		{ProductID: product.ID, FileName: "a.png", Position: 0, IsPrimary: true},
+		{ProductID: product.ID, FileName: "b.png", Position: 1},
+	}
+	db.Create(&images)
+
+	// Clean up the testing environment
+	tables := []string{"outbox_event", "product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	url := fmt.Sprintf("/product/%d/image/%d", product.ID, images[1].ID)
+
+	// a change of the gallery needs the version it was made from
+	response, _ := serveProductImageRequest(t, "PUT", url, token, "", model.EditProductImageRequest{AltText: "side"})
+	assert.Equal(t, service.VersionRequired, response.Code)
+
+	response, etag := serveProductImageRequest(t, "PUT", url, token, `"1"`, model.EditProductImageRequest{AltText: "side", IsPrimary: true})
+	assert.Equal(t, service.SuccessError, response.Code)
+	assert.Equal(t, `"2"`, etag)
+
+	var stored entity.Product
+	db.First(&stored, product.ID)
+	assert.Equal(t, "b.png", stored.Image)
+	assert.Equal(t, uint(2), stored.Version)
+
+	// and fails once the product changed since
+	response, _ = serveProductImageRequest(t, "PUT", url, token, `"1"`, model.EditProductImageRequest{AltText: "back"})
+	assert.Equal(t, service.VersionConflict, response.Code)
+	response, _ = serveProductImageRequest(t, "DELETE", url, token, `"1"`, nil)
+	assert.Equal(t, service.VersionConflict, response.Code)
+
+	var image entity.ProductImage
+	db.First(&image, images[1].ID)
+	assert.Equal(t, "side", image.AltText)
+}
+
+func TestReorderProductImages_MissingImage(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+	product := categories[0].Products[0]
+	images := []entity.ProductImage{
+		{ProductID: product.ID, FileName: "a.png", Position: 0, IsPrimary: true},
+		{ProductID: product.ID, FileName: "b.png", Position: 1},
+	}
+	db.Create(&images)
+
+	// Clean up the testing environment
+	tables := []string{"product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	response, _ := serveProductImageRequest(t, "PUT", fmt.Sprintf("/product/%d/image/order", product.ID), token, `*`, model.ReorderProductImagesRequest{ImageIDs: []uint{images[1].ID}})
+	assert.Equal(t, service.InvalidRequestError, response.Code)
+}
+
+func TestAddProductImage_SharedContent(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+	first, second := categories[0].Products[0], categories[0].Products[1]
+
+	// Clean up the testing environment
+	tables := []string{"image_blob", "image_rendition", "outbox_event", "product_image", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	// the same photo added to two products is stored once
+	for _, product := range []entity.Product{first, second} {
+		response, _ := serveProductImageRequest(t, "POST", fmt.Sprintf("/product/%d/image", product.ID), token, `*`, model.ProductImageRequest{Image: SampleImagePNG()})
+		assert.Equal(t, service.SuccessError, response.Code)
+	}
+
+	var images []entity.ProductImage
+	db.Where("product_id IN ?", []uint{first.ID, second.ID}).Order("product_id asc").Find(&images)
+	assert.Equal(t, 2, len(images))
+	assert.Equal(t, images[0].FileName, images[1].FileName)
+
+	var blob entity.ImageBlob
+	db.Where("name = ?", images[0].FileName).First(&blob)
+	assert.Equal(t, 2, blob.RefCount)
+
+	var renditions int64
+	db.Model(&entity.ImageRendition{}).Where("image_name = ?", images[0].FileName).Count(&renditions)
+	assert.NotZero(t, renditions)
+
+	// the file is kept while another product references it
+	response, _ := serveProductImageRequest(t, "DELETE", fmt.Sprintf("/product/%d/image/%d", first.ID, images[0].ID), token, `*`, nil)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	file, err := imagesRepository.OpenImage(images[0].FileName)
+	assert.NoError(t, err)
+	if file != nil {
+		file.Close()
+	}
+
+	// and removed with the last reference
+	response, _ = serveProductImageRequest(t, "DELETE", fmt.Sprintf("/product/%d/image/%d", second.ID, images[1].ID), token, `*`, nil)
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	_, err = imagesRepository.OpenImage(images[0].FileName)
+	assert.Error(t, err)
+
+	var blobs int64
+	db.Model(&entity.ImageBlob{}).Where("name = ?", images[0].FileName).Count(&blobs)
+	assert.Equal(t, int64(0), blobs)
+}
@@ -3,6 +3,7 @@
 package handler_test
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -32,6 +33,11 @@ var productHandler *httpHandler.ProductHandler
 var productGRPCHandler *gRPCHandler.ProductHandler
 var userRepo *mock.MockUserRepository
 var imagesRepository repository.ImagesRepository
+var idempotencyService service.IdempotencyService
+var idempotencyHandler *httpHandler.IdempotencyHandler
+var menuEventsHandler *httpHandler.MenuEventsHandler
+var webhookService service.WebhookService
+var webhookHandler *httpHandler.WebhookHandler
 
 func TestMain(m *testing.M) {
 	setup()
@@ -83,14 +89,27 @@ func setup() {
 
 	// Apply database migrations (if any)
 	// You can use db.AutoMigrate(&YourModel{}) to automatically apply migrations
+	if err := database.Migrate(db); err != nil {
+		panic(err)
+	}
 
 	// Create a product service and handler
 	productRepository := repository.NewProductRepository(db)
 	userRepo = mock.NewMockUserRepository()
-	imagesRepository = repository.NewImagesRepository(cfg.ImagePath)
-	productService := service.NewProductService(productRepository, userRepo, imagesRepository)
+	imagesRepository = repository.NewImagesRepository(cfg.ImagePath, cfg.ImageBaseURL, cfg.ImageRenditions)
+	translationRepository := repository.NewTranslationRepository(db)
+	productImageRepository := repository.NewProductImageRepository(db)
+	uploadedImageRepository := repository.NewUploadedImageRepository(db)
+	menuEvents := service.NewMenuEvents(repository.NewOutboxRepository(db), cfg.MenuEvents)
+	go menuEvents.RunMenuEvents(context.Background())
+	webhookService = service.NewWebhookService(repository.NewWebhookRepository(db), repository.NewUnitOfWork(db), userRepo, cfg.Webhook)
+	productService := service.NewProductService(productRepository, userRepo, imagesRepository, translationRepository, productImageRepository, uploadedImageRepository, repository.NewUnitOfWork(db), cfg.ImageValidation, menuEvents, webhookService)
 	productHandler = httpHandler.NewProductHandler(productService)
 	productGRPCHandler = gRPCHandler.NewProductGRPCHandler(productService)
+	idempotencyService = service.NewIdempotencyService(repository.NewIdempotencyRepository(db), userRepo, cfg.Idempotency.TTL, cfg.Idempotency.Lease)
+	idempotencyHandler = httpHandler.NewIdempotencyHandler(idempotencyService)
+	menuEventsHandler = httpHandler.NewMenuEventsHandler(productService, cfg.MenuEvents.Heartbeat)
+	webhookHandler = httpHandler.NewWebhookHandler(webhookService)
 
 	go func() {
 		// Create a gRPC server
@@ -0,0 +1,188 @@
+// translation_handler_test.go
+
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"maqhaa/library/logging"
+	"maqhaa/library/middleware"
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/service"
+
+	pb "maqhaa/product_service/internal/interface/grpc/model"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	exModel "maqhaa/product_service/external/model"
+)
+
+func TestSaveProductTranslation_Positive(t *testing.T) {
+	// events of the other tests are not cleaned up
+	clearDB([]string{"outbox_event"})
+
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+
+	// Clean up the testing environment
+	tables := []string{"outbox_event", "product_translation", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	request := model.ProductTranslationRequest{
+		Name:        "Kopi Espresso",
+		Description: "Kopi kental",
+	}
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/product/{productID}/translation/{locale}", productHandler.SaveProductTranslationHandler).Methods("PUT")
+
+	// Mock HTTP request
+	req, err := http.NewRequest("PUT", "/product/"+strconv.Itoa(int(categories[0].Products[0].ID))+"/translation/id-ID", bytes.NewReader(requestJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestID := uuid.New().String()
+	req.Header.Set("Token", token)
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
+	req = req.WithContext(ctx)
+
+	// Create a response recorder to capture the handler's response
+	rr := httptest.NewRecorder()
+
+	// Call the handler function
+	router.ServeHTTP(rr, req)
+	logging.Log.WithFields(logrus.Fields{
+		"RequestID": requestID,
+		"Status":    rr.Code,
+		"Body":      rr.Body.String(),
+	}).Info("Outgoing response")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response model.HTTPResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, service.SuccessError, response.Code)
+
+	var translation entity.ProductTranslation
+	result := db.First(&translation)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+
+	assert.Equal(t, "id", translation.Locale)
+	assert.Equal(t, request.Name, translation.Name)
+
+	// the translation is recorded as an update of the product
+	events := outboxEvents(t)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, entity.EventProductUpdated, events[0].Type)
+		assert.Equal(t, categories[0].Products[0].ID, events[0].AggregateID)
+	}
+}
+
+func TestGetProductGroupsByCategoryHandler_AcceptLanguage(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	client.DefaultLocale = "en"
+	db.Create(client)
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+	db.Create(&entity.ProductCategoryTranslation{CategoryID: categories[0].ID, Locale: "id", Name: "Kopi"})
+	db.Create(&entity.ProductTranslation{ProductID: categories[0].Products[0].ID, Locale: "id", Name: "Kopi Espresso", Description: "Kopi kental"})
+	db.Create(&entity.ProductTranslation{ProductID: categories[0].Products[1].ID, Locale: "en", Name: "Caffe Latte"})
+
+	// Clean up the testing environment
+	tables := []string{"product_translation", "product_category_translation", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	req, err := http.NewRequest("GET", "/product", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Token", client.Token)
+	req.Header.Set("Accept-Language", "id-ID,id;q=0.9,en;q=0.8")
+	requestID := uuid.New().String()
+	ctx := context.WithValue(req.Context(), middleware.RequestIDKey, requestID)
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(productHandler.GetProductGroupsByCategoryHandler).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response struct {
+		Code int                      `json:"code"`
+		Data []entity.ProductCategory `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, service.SuccessError, response.Code)
+	assert.Equal(t, 1, len(response.Data))
+	assert.Equal(t, "Kopi", response.Data[0].Name)
+	assert.Equal(t, "Kopi Espresso", response.Data[0].Products[0].Name)
+	assert.Equal(t, "Kopi kental", response.Data[0].Products[0].Description)
+	// no Indonesian translation, falls back to the client's default locale
+	assert.Equal(t, "Caffe Latte", response.Data[0].Products[1].Name)
+}
+
+func TestGetProductByIDGRPCHandler_Locale(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	db.Create(client)
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+	db.Create(&entity.ProductTranslation{ProductID: categories[0].Products[0].ID, Locale: "id", Name: "Kopi Espresso"})
+
+	// Clean up the testing environment
+	tables := []string{"product_translation", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Error creating gRPC client connection: %v", err)
+	}
+	defer conn.Close()
+
+	clientServer := pb.NewProductClient(conn)
+
+	resp, err := clientServer.GetProduct(context.Background(), &pb.GetProductRequest{
+		ProductId: uint32(categories[0].Products[0].ID),
+		Token:     client.Token,
+		Locale:    "id",
+	})
+	if err != nil {
+		t.Fatalf("Error calling GetProduct gRPC method: %v", err)
+	}
+
+	assert.Equal(t, int32(service.SuccessError), resp.Code)
+	assert.Equal(t, "Kopi Espresso", resp.Data.Name)
+	// the description is not translated and keeps the original value
+	assert.Equal(t, categories[0].Products[0].Description, resp.Data.Description)
+}
@@ -0,0 +1,237 @@
+// webhook_handler_test.go
+
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/repository"
+	"maqhaa/product_service/internal/app/service"
+	"maqhaa/product_service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+
+	exModel "maqhaa/product_service/external/model"
+)
+
+// webhookReceiver records the requests of webhooks, and responds with status.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.requests = append(rcv.requests, r)
+	rcv.bodies = append(rcv.bodies, body)
+	w.WriteHeader(rcv.status)
+}
+
+func (rcv *webhookReceiver) setStatus(status int) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.status = status
+}
+
+func webhookDeliveries(t *testing.T) []entity.WebhookDelivery {
+	var deliveries []entity.WebhookDelivery
+	if err := db.Order("id asc").Find(&deliveries).Error; err != nil {
+		t.Fatal(err)
+	}
+	return deliveries
+}
+
+func TestWebhook_Positive(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+
+	// Clean up the testing environment
+	tables := []string{"webhook_delivery", "webhook_subscription", "outbox_event", "product_tag", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	receiver := &webhookReceiver{status: http.StatusNoContent}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	secret := "0123456789abcdef0123"
+	rr := cloneRequest(t, "POST", "/webhook", "/webhook", webhookHandler.AddWebhookHandler, token,
+		fmt.Sprintf(`{"url": %q, "event_types": ["product_updated"], "secret": %q}`, server.URL, secret))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var added struct {
+		Code int                        `json:"code"`
+		Data entity.WebhookSubscription `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &added))
+	assert.Equal(t, service.SuccessError, added.Code)
+	assert.Equal(t, secret, added.Data.Secret)
+
+	// the secret is not listed
+	rr = cloneRequest(t, "GET", "/webhook", "/webhook", webhookHandler.GetWebhooksHandler, token, "")
+	var listed struct {
+		Data []entity.WebhookSubscription `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	assert.Len(t, listed.Data, 1)
+	assert.Empty(t, listed.Data[0].Secret)
+
+	espresso := categories[0].Products[0]
+	_, bulk := bulkUpdateProducts(t, token, fmt.Sprintf(`{"product_ids": [%d], "operations": [{"type": "set_price", "price": 30000}]}`, espresso.ID))
+	assert.Equal(t, service.SuccessError, bulk.Code)
+
+	delivered, err := webhookService.DeliverWebhooks(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	assert.Len(t, receiver.requests, 1)
+	request, body := receiver.requests[0], receiver.bodies[0]
+	assert.Equal(t, "product_updated", request.Header.Get(service.WebhookEventHeader))
+
+	parts := strings.Split(request.Header.Get(service.WebhookSignatureHeader), ",")
+	assert.Len(t, parts, 2)
+	timestamp, err := strconv.ParseInt(strings.TrimPrefix(parts[0], "t="), 10, 64)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1="+service.SignWebhook(secret, timestamp, body), parts[1])
+
+	var event map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, request.Header.Get(service.WebhookIDHeader), event["id"])
+	assert.Equal(t, float64(espresso.ID), event["productId"])
+
+	deliveries := webhookDeliveries(t)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, entity.WebhookDelivered, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.NotNil(t, deliveries[0].DeliveredAt)
+
+	// events the webhook is not subscribed to are not delivered
+	_, bulk = bulkUpdateProducts(t, token, fmt.Sprintf(`{"product_ids": [%d], "operations": [{"type": "deactivate"}]}`, espresso.ID))
+	assert.Equal(t, service.SuccessError, bulk.Code)
+	assert.Len(t, webhookDeliveries(t), 1)
+
+	// a removed webhook stops receiving events
+	rr = cloneRequest(t, "DELETE", fmt.Sprintf("/webhook/%d", added.Data.ID), "/webhook/{webhookID}", webhookHandler.DeleteWebhookHandler, token, "")
+	var deleted struct {
+		Code int `json:"code"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &deleted))
+	assert.Equal(t, service.SuccessError, deleted.Code)
+
+	rr = cloneRequest(t, "DELETE", fmt.Sprintf("/webhook/%d", added.Data.ID), "/webhook/{webhookID}", webhookHandler.DeleteWebhookHandler, token, "")
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &deleted))
+	assert.Equal(t, service.WebhookNotFound, deleted.Code)
+}
+
+func TestWebhook_RetriesAndDeadLetters(t *testing.T) {
+	// create mock data
+	client := SampleClient()
+	token := "xxxxxaaaaa"
+	client.Token = token
+	db.Create(client)
+
+	userRepo.SetUserResponse(token, &exModel.UserData{Id: 1, ClientId: uint32(client.ID), IsAdmin: true, IsLogin: true})
+
+	categories := SampleCategories(client.ID)
+	db.Create(categories[0])
+
+	// Clean up the testing environment
+	tables := []string{"webhook_delivery", "webhook_subscription", "outbox_event", "product_tag", "product", "product_category", "client"}
+	defer clearDB(tables)
+
+	receiver := &webhookReceiver{status: http.StatusInternalServerError}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	db.Create(&entity.WebhookSubscription{ClientID: client.ID, URL: server.URL, EventTypes: []string{"product_updated"}, Secret: "0123456789abcdef"})
+
+	espresso := categories[0].Products[0]
+	_, bulk := bulkUpdateProducts(t, token, fmt.Sprintf(`{"product_ids": [%d], "operations": [{"type": "set_price", "price": 30000}]}`, espresso.ID))
+	assert.Equal(t, service.SuccessError, bulk.Code)
+
+	// a failed attempt is retried after a backoff
+	deliveries := service.NewWebhookService(repository.NewWebhookRepository(db), repository.NewUnitOfWork(db), userRepo, config.WebhookConfig{
+		MaxAttempts:    2,
+		InitialBackoff: time.Minute,
+		MaxBackoff:     time.Hour,
+		AllowedHosts:   []string{"127.0.0.1"},
+	})
+	delivered, err := deliveries.DeliverWebhooks(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+
+	delivery := webhookDeliveries(t)[0]
+	assert.Equal(t, entity.WebhookPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, delivery.LastStatusCode)
+	assert.True(t, delivery.NextAttemptAt.After(time.Now().Add(50*time.Second)))
+
+	// the delivery is not due yet
+	delivered, err = deliveries.DeliverWebhooks(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	assert.Len(t, receiver.requests, 1)
+
+	// the last attempt failing makes a dead letter
+	db.Model(&entity.WebhookDelivery{}).Where("id = ?", delivery.ID).Update("next_attempt_at", time.Now().Add(-time.Second))
+	_, err = deliveries.DeliverWebhooks(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, receiver.requests, 2)
+
+	rr := cloneRequest(t, "GET", "/webhook/dead-letter", "/webhook/dead-letter", webhookHandler.GetDeadWebhookDeliveriesHandler, token, "")
+	var dead struct {
+		Code int                      `json:"code"`
+		Data []entity.WebhookDelivery `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &dead))
+	assert.Equal(t, service.SuccessError, dead.Code)
+	assert.Len(t, dead.Data, 1)
+	assert.Equal(t, delivery.ID, dead.Data[0].ID)
+	assert.Equal(t, 2, dead.Data[0].Attempts)
+	assert.NotEmpty(t, dead.Data[0].LastError)
+
+	// a redelivered dead letter is sent again, with the same event ID
+	receiver.setStatus(http.StatusOK)
+	rr = cloneRequest(t, "POST", fmt.Sprintf("/webhook/delivery/%d/redeliver", delivery.ID), "/webhook/delivery/{deliveryID}/redeliver", webhookHandler.RedeliverWebhookHandler, token, "")
+	var redelivered struct {
+		Code int                    `json:"code"`
+		Data entity.WebhookDelivery `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &redelivered))
+	assert.Equal(t, service.SuccessError, redelivered.Code)
+	assert.Equal(t, entity.WebhookPending, redelivered.Data.Status)
+
+	delivered, err = deliveries.DeliverWebhooks(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Len(t, receiver.requests, 3)
+	assert.Equal(t, receiver.requests[0].Header.Get(service.WebhookIDHeader), receiver.requests[2].Header.Get(service.WebhookIDHeader))
+	assert.Equal(t, entity.WebhookDelivered, webhookDeliveries(t)[0].Status)
+
+	// unknown deliveries are not redelivered
+	rr = cloneRequest(t, "POST", fmt.Sprintf("/webhook/delivery/%d/redeliver", delivery.ID+1000), "/webhook/delivery/{deliveryID}/redeliver", webhookHandler.RedeliverWebhookHandler, token, "")
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &redelivered))
+	assert.Equal(t, service.WebhookDeliveryNotFound, redelivered.Code)
+}
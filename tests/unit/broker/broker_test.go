@@ -0,0 +1,215 @@
+package broker_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"maqhaa/product_service/internal/broker"
+	"maqhaa/product_service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// natsFrame is a message received by the fake NATS server.
+type natsFrame struct {
+	Subject string
+	Header  string
+	Body    string
+}
+
+// startNATSServer starts a fake NATS server with a JetStream stream capturing the subjects starting with
+// stream, sending received messages to frames and the CONNECT options to connects. The messages of other
+// subjects have no responders. A server of a failing token answers the CONNECT with an authorization error.
+func startNATSServer(t *testing.T, token string, stream string) (string, <-chan natsFrame, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	frames := make(chan natsFrame, 10)
+	connects := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveNATS(conn, token, stream, frames, connects)
+		}
+	}()
+	return "nats://" + listener.Addr().String(), frames, connects
+}
+
+func serveNATS(conn net.Conn, token string, stream string, frames chan<- natsFrame, connects chan<- string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\",\"version\":\"2.10.0\",\"proto\":1,\"headers\":true,\"max_payload\":1024}\r\n")
+
+	// subscriptions are the sids of the subscriptions of the client, by subject
+	subscriptions := map[string]string{}
+	sequence := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "CONNECT":
+			options := strings.TrimSpace(strings.TrimPrefix(line, "CONNECT"))
+			connects <- options
+			if token != "" && !strings.Contains(options, `"auth_token":"`+token+`"`) {
+				fmt.Fprintf(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PING":
+			fmt.Fprintf(conn, "PONG\r\n")
+		case "SUB":
+			subscriptions[fields[1]] = fields[len(fields)-1]
+		case "HPUB":
+			// HPUB <subject> <reply> <header size> <total size>
+			headerSize, _ := strconv.Atoi(fields[3])
+			totalSize, _ := strconv.Atoi(fields[4])
+			data := make([]byte, totalSize+2)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return
+			}
+
+			reply := fields[2]
+			sid := subscriptions[reply[:strings.LastIndex(reply, ".")]+".*"]
+			if !strings.HasPrefix(fields[1], stream+".") {
+				status := "NATS/1.0 503\r\n\r\n"
+				fmt.Fprintf(conn, "HMSG %s %s %d %d\r\n%s\r\n", reply, sid, len(status), len(status), status)
+				continue
+			}
+
+			frames <- natsFrame{Subject: fields[1], Header: string(data[:headerSize]), Body: string(data[headerSize:totalSize])}
+			sequence++
+			ack := fmt.Sprintf(`{"stream":"CATALOGUE","seq":%d}`, sequence)
+			fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", reply, sid, len(ack), ack)
+		}
+	}
+}
+
+func TestNATSBroker_Publish(t *testing.T) {
+	address, frames, connects := startNATSServer(t, "secret", "catalogue")
+	serverURL := strings.Replace(address, "nats://", "nats://secret@", 1)
+
+	natsBroker, err := broker.NewBroker(config.BrokerConfig{Driver: "nats", URL: serverURL, Topic: "catalogue", Timeout: time.Second})
+	assert.NoError(t, err)
+	defer natsBroker.Close()
+
+	message := broker.Message{ID: "42", Subject: "ProductCreated", Key: "product-7", Body: []byte(`{"id":"42"}`)}
+	assert.NoError(t, natsBroker.Publish(context.Background(), message))
+	assert.Contains(t, <-connects, `"auth_token":"secret"`)
+
+	frame := <-frames
+	assert.Equal(t, "catalogue.ProductCreated", frame.Subject)
+	assert.Contains(t, frame.Header, "Nats-Msg-Id: 42\r\n")
+	assert.Equal(t, `{"id":"42"}`, frame.Body)
+
+	// the connection is kept for the following messages
+	assert.NoError(t, natsBroker.Publish(context.Background(), broker.Message{ID: "43", Subject: "CategoryChanged", Body: []byte(`{}`)}))
+	assert.Equal(t, "catalogue.CategoryChanged", (<-frames).Subject)
+	assert.Empty(t, connects)
+
+	// messages over the maximum payload of the server are refused
+	err = natsBroker.Publish(context.Background(), broker.Message{ID: "44", Subject: "ProductUpdated", Body: make([]byte, 2048)})
+	assert.ErrorContains(t, err, "maximum payload")
+}
+
+func TestNATSBroker_NoStream(t *testing.T) {
+	address, frames, _ := startNATSServer(t, "", "orders")
+	natsBroker, err := broker.NewNATSBroker(address, "catalogue", time.Second)
+	assert.NoError(t, err)
+	defer natsBroker.Close()
+
+	// a message no stream stored is not acknowledged
+	err = natsBroker.Publish(context.Background(), broker.Message{ID: "1", Subject: "ProductCreated", Body: []byte(`{}`)})
+	assert.Error(t, err)
+	assert.Empty(t, frames)
+}
+
+func TestNATSBroker_Errors(t *testing.T) {
+	address, _, _ := startNATSServer(t, "secret", "catalogue")
+	natsBroker, err := broker.NewNATSBroker(strings.Replace(address, "nats://", "nats://wrong@", 1), "catalogue", time.Second)
+	assert.NoError(t, err)
+	defer natsBroker.Close()
+
+	err = natsBroker.Publish(context.Background(), broker.Message{ID: "1", Subject: "ProductCreated", Body: []byte(`{}`)})
+	assert.ErrorContains(t, err, "Authorization Violation")
+
+	_, err = broker.NewNATSBroker("http://localhost:4222", "catalogue", time.Second)
+	assert.Error(t, err)
+}
+
+func TestKafkaBroker_Publish(t *testing.T) {
+	var path, contentType string
+	var records struct {
+		Records []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"records"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&records)
+		if records.Records[0].Key == base64.StdEncoding.EncodeToString([]byte("rejected")) {
+			w.Write([]byte(`{"offsets":[{"partition":null,"offset":null,"error_code":50002,"error":"Kafka error"}]}`))
+			return
+		}
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":12}]}`))
+	}))
+	defer server.Close()
+
+	kafkaBroker, err := broker.NewBroker(config.BrokerConfig{Driver: "kafka", URL: server.URL + "/", Topic: "catalogue"})
+	assert.NoError(t, err)
+	defer kafkaBroker.Close()
+
+	message := broker.Message{ID: "42", Subject: "ProductCreated", Key: "product-7", Body: []byte(`{"id":"42"}`)}
+	assert.NoError(t, kafkaBroker.Publish(context.Background(), message))
+	assert.Equal(t, "/topics/catalogue", path)
+	assert.Equal(t, "application/vnd.kafka.binary.v2+json", contentType)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("product-7")), records.Records[0].Key)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(`{"id":"42"}`)), records.Records[0].Value)
+
+	message.Key = "rejected"
+	assert.ErrorContains(t, kafkaBroker.Publish(context.Background(), message), "Kafka error")
+
+	server.Close()
+	assert.Error(t, kafkaBroker.Publish(context.Background(), message))
+}
+
+func TestMemoryBroker(t *testing.T) {
+	memoryBroker := broker.NewMemoryBroker()
+	assert.NoError(t, memoryBroker.Publish(context.Background(), broker.Message{ID: "1"}))
+
+	failure := errors.New("unavailable")
+	memoryBroker.SetError(failure)
+	assert.Equal(t, failure, memoryBroker.Publish(context.Background(), broker.Message{ID: "2"}))
+	memoryBroker.SetError(nil)
+	assert.NoError(t, memoryBroker.Publish(context.Background(), broker.Message{ID: "3"}))
+
+	messages := memoryBroker.Messages()
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "3", messages[1].ID)
+
+	_, err := broker.NewBroker(config.BrokerConfig{Driver: "rabbitmq"})
+	assert.Error(t, err)
+}
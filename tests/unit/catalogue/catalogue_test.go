@@ -0,0 +1,139 @@
+package catalogue_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/catalogue"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestReadCSV(t *testing.T) {
+	file := "\xef\xbb\xbfName,Category,Price,Active,Notes\n" +
+		"Espresso,Coffee,25000,TRUE,strong\n" +
+		"\n" +
+		"\"Latte\",Coffee, 30000 ,,\"two\nlines\"\n" +
+		"Green Tea,Tea,20000\n"
+
+	rows, err := catalogue.ReadCSV(strings.NewReader(file))
+	assert.NoError(t, err)
+	assert.Equal(t, []model.CatalogueRow{
+		{Line: 2, Category: "Coffee", Name: "Espresso", Price: "25000", Active: "true"},
+		{Line: 4, Category: "Coffee", Name: "Latte", Price: "30000"},
+		{Line: 6, Category: "Tea", Name: "Green Tea", Price: "20000"},
+	}, rows)
+}
+
+func TestReadCSV_Semicolons(t *testing.T) {
+	rows, err := catalogue.ReadCSV(strings.NewReader("category;name;price;description\nCoffee;Espresso;25000;Strong, short\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []model.CatalogueRow{
+		{Line: 2, Category: "Coffee", Name: "Espresso", Description: "Strong, short", Price: "25000"},
+	}, rows)
+}
+
+func TestReadCSV_MissingColumn(t *testing.T) {
+	_, err := catalogue.ReadCSV(strings.NewReader("category,name\nCoffee,Espresso\n"))
+	assert.EqualError(t, err, "missing column price")
+
+	_, err = catalogue.ReadCSV(strings.NewReader(""))
+	assert.EqualError(t, err, "missing header")
+}
+
+func TestReadXLSX(t *testing.T) {
+	workbook := excelize.NewFile()
+	sheet := workbook.GetSheetName(0)
+	workbook.SetSheetRow(sheet, "A1", &[]interface{}{"category", "name", "description", "price", "image", "active"})
+	workbook.SetSheetRow(sheet, "A2", &[]interface{}{"Coffee", "Espresso", "Strong coffee", 25000.5, "https://example.com/espresso.jpg", false})
+	workbook.SetSheetRow(sheet, "A4", &[]interface{}{"Tea", "Green Tea", "", 20000})
+
+	// the number format of the sheet does not change the prices read
+	style, err := workbook.NewStyle(&excelize.Style{NumFmt: 3})
+	assert.NoError(t, err)
+	workbook.SetCellStyle(sheet, "D2", "D4", style)
+
+	var file bytes.Buffer
+	assert.NoError(t, workbook.Write(&file))
+
+	// boolean cells are read as 1 or 0
+	rows, err := catalogue.ReadRows(&file, catalogue.FormatOf("menu.XLSX"))
+	assert.NoError(t, err)
+	assert.Equal(t, []model.CatalogueRow{
+		{Line: 2, Category: "Coffee", Name: "Espresso", Description: "Strong coffee", Price: "25000.5", Image: "https://example.com/espresso.jpg", Active: "0"},
+		{Line: 4, Category: "Tea", Name: "Green Tea", Price: "20000"},
+	}, rows)
+}
+
+func TestReadRows_UnsupportedFormat(t *testing.T) {
+	assert.Equal(t, "", catalogue.FormatOf("menu.ods"))
+
+	_, err := catalogue.ReadRows(strings.NewReader(""), catalogue.FormatOf("menu.ods"))
+	assert.Error(t, err)
+}
+
+func TestReadJSON(t *testing.T) {
+	file := `[
+		{"category": "Coffee", "name": " Espresso ", "price": 25000.5, "active": true},
+		{},
+		{"category": "Coffee", "name": "Latte", "description": "Milky", "price": "30000", "image": "latte.webp", "active": "No"},
+		{"category": "Tea", "name": "Green Tea", "price": "free", "active": null}
+	]`
+
+	rows, err := catalogue.ReadRows(strings.NewReader(file), catalogue.FormatOf("menu.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, []model.CatalogueRow{
+		{Line: 1, Category: "Coffee", Name: "Espresso", Price: "25000.5", Active: "true"},
+		{Line: 3, Category: "Coffee", Name: "Latte", Description: "Milky", Price: "30000", Image: "latte.webp", Active: "no"},
+		{Line: 4, Category: "Tea", Name: "Green Tea", Price: "free"},
+	}, rows)
+
+	_, err = catalogue.ReadJSON(strings.NewReader(`[{"name": "Espresso", "price": [1]}]`))
+	assert.Error(t, err)
+}
+
+func TestWriteJSON(t *testing.T) {
+	rows := []model.CatalogueRow{
+		{Category: "Coffee", Name: "Espresso & Milk", Price: "25000.5", Image: "https://example.com/espresso.jpg", Active: "true"},
+		{Category: "Tea", Name: "Green Tea", Price: "NaN", Active: "false"},
+	}
+
+	var file bytes.Buffer
+	assert.NoError(t, catalogue.WriteJSON(&file, rows))
+	assert.JSONEq(t, `[
+		{"category": "Coffee", "name": "Espresso & Milk", "description": "", "price": 25000.5, "image": "https://example.com/espresso.jpg", "active": true},
+		{"category": "Tea", "name": "Green Tea", "description": "", "price": "NaN", "active": false}
+	]`, file.String())
+}
+
+func TestWriteRows_RoundTrip(t *testing.T) {
+	rows := []model.CatalogueRow{
+		{Line: 2, Category: "Coffee", Name: "Espresso", Description: "Strong, \"short\"", Price: "25000.5", Image: "https://example.com/espresso.jpg", Active: "true"},
+		{Line: 3, Category: "Tea", Name: "Green Tea", Description: "two\nlines", Price: "20000", Active: "false"},
+	}
+
+	for _, format := range []string{catalogue.FormatCSV, catalogue.FormatXLSX, catalogue.FormatJSON} {
+		var file bytes.Buffer
+		assert.NoError(t, catalogue.WriteRows(&file, rows, format), format)
+
+		read, err := catalogue.ReadRows(&file, format)
+		assert.NoError(t, err, format)
+		if !assert.Len(t, read, len(rows), format) {
+			continue
+		}
+		for i := range rows {
+			expected := rows[i]
+			if format == catalogue.FormatJSON {
+				expected.Line = i + 1
+			}
+			assert.Equal(t, expected, read[i], format)
+		}
+	}
+
+	var file bytes.Buffer
+	assert.Error(t, catalogue.WriteRows(&file, rows, "ods"))
+	assert.Equal(t, "text/csv; charset=utf-8", catalogue.ContentType(catalogue.FormatCSV))
+}
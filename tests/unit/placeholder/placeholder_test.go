@@ -0,0 +1,59 @@
+package placeholder_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+
+	"maqhaa/product_service/internal/placeholder"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func solidImage(width int, height int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+const base83Characters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+func TestBlurHash_Solid(t *testing.T) {
+	hash := placeholder.BlurHash(solidImage(300, 200, color.NRGBA{R: 0xff, A: 0xff}))
+	assert.Len(t, hash, 4+2*placeholder.BlurHashXComponents*placeholder.BlurHashYComponents)
+	// 4x3 components
+	assert.Equal(t, "L", hash[:1])
+	// the DC component is the colour of the image, 0xff0000
+	assert.Equal(t, "TI:j", hash[2:6])
+}
+
+func TestBlurHash_Gradient(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 120, 80))
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 120; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 2), G: 0x40, B: uint8(255 - x*2), A: 0xff})
+		}
+	}
+
+	hash := placeholder.BlurHash(img)
+	assert.Len(t, hash, 4+2*placeholder.BlurHashXComponents*placeholder.BlurHashYComponents)
+	assert.Equal(t, hash, placeholder.BlurHash(img))
+
+	// a gradient has a larger AC amplitude than a solid colour
+	solid := placeholder.BlurHash(solidImage(120, 80, color.NRGBA{R: 0x80, G: 0x40, B: 0x80, A: 0xff}))
+	assert.Greater(t, strings.Index(base83Characters, hash[1:2]), strings.Index(base83Characters, solid[1:2]))
+}
+
+func TestDominantColor(t *testing.T) {
+	assert.Equal(t, "#a0522d", placeholder.DominantColor(solidImage(50, 50, color.NRGBA{R: 0xa0, G: 0x52, B: 0x2d, A: 0xff})))
+
+	// the largest area wins over a smaller one
+	img := solidImage(100, 100, color.NRGBA{R: 0x10, G: 0x80, B: 0x10, A: 0xff})
+	draw.Draw(img, image.Rect(0, 0, 30, 100), image.NewUniform(color.NRGBA{R: 0xf0, A: 0xff}), image.Point{}, draw.Src)
+	assert.Equal(t, "#108010", placeholder.DominantColor(img))
+
+	// transparent pixels are ignored
+	assert.Equal(t, "#ffffff", placeholder.DominantColor(image.NewNRGBA(image.Rect(0, 0, 10, 10))))
+}
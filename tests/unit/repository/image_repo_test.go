@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"image"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -38,3 +40,34 @@ func TestSaveImage_Success(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestSaveRenditions_Success(t *testing.T) {
+	imageData, err := base64.StdEncoding.DecodeString(SampleImagePNG())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imageName := fmt.Sprintf("PR-%d.png", time.Now().UnixNano()/int64(time.Millisecond))
+	renditions, err := imageRepository.SaveRenditions(img, imageName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer imageRepository.RemoveImage(imageName)
+
+	if len(renditions) == 0 {
+		t.Fatal("expected renditions to be generated")
+	}
+	for _, rendition := range renditions {
+		if rendition.ImageName != imageName || rendition.Width > img.Bounds().Dx() {
+			t.Fatalf("unexpected rendition %+v", rendition)
+		}
+		if _, err := os.Stat(filepath.Join(imageRepository.GetPath(), rendition.FileName)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
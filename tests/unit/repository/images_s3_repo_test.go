@@ -0,0 +1,141 @@
+package repository_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"maqhaa/product_service/internal/app/repository"
+	"maqhaa/product_service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newS3ImagesRepository(t *testing.T, storage config.ImageStorageConfig) (repository.ImagesRepository, *fakeS3) {
+	s3 := newFakeS3()
+	t.Cleanup(s3.server.Close)
+
+	storage.Driver = "s3"
+	storage.Endpoint = s3.endpoint()
+	storage.Region = "us-east-1"
+	storage.Bucket = "product-images"
+	storage.AccessKeyID = "minio"
+	storage.SecretAccessKey = "minio123"
+
+	renditions := []config.ImageRendition{{Name: "thumbnail", Width: 10, Format: "webp"}}
+	imagesRepository, err := repository.NewImagesRepositoryFromConfig(&config.Config{ImageStorage: storage, ImageRenditions: renditions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return imagesRepository, s3
+}
+
+func TestS3ImagesRepository_SaveOpenRemove(t *testing.T) {
+	imagesRepository, s3 := newS3ImagesRepository(t, config.ImageStorageConfig{})
+
+	imageData, err := base64.StdEncoding.DecodeString(SampleImagePNG())
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, imagesRepository.SaveImage(imageData, "PR-1.png"))
+	renditions, err := imagesRepository.SaveRenditions(img, "PR-1.png")
+	assert.NoError(t, err)
+	assert.Equal(t, "PR-1-thumbnail.webp", renditions[0].FileName)
+
+	file, err := imagesRepository.OpenImage("PR-1.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := io.ReadAll(file)
+	file.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, imageData, stored)
+
+	resized, err := imagesRepository.OpenResizedImage("PR-1.png", 5, 0, "png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := resized.Stat()
+	assert.NoError(t, err)
+	assert.Equal(t, "PR-1-10x0.png", info.Name())
+	resizedImage, _, err := image.Decode(resized)
+	resized.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, resizedImage.Bounds().Dx())
+
+	// sizes are rounded up to the renditions and the allowed sizes, and an image that fits is not copied
+	resized, err = imagesRepository.OpenResizedImage("PR-1.png", 4000, 0, "png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err = resized.Stat()
+	resized.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, "PR-1.png", info.Name())
+
+	assert.Equal(t, []string{"PR-1-thumbnail.webp", "PR-1.png", "cache/PR-1-10x0.png"}, s3.keys())
+
+	// resized copies are not listed
+	listed, err := imagesRepository.ListImages()
+	assert.NoError(t, err)
+	if assert.Len(t, listed, 2) {
+		assert.Equal(t, "PR-1-thumbnail.webp", listed[0].Name())
+		assert.Equal(t, "PR-1.png", listed[1].Name())
+	}
+
+	// the image, its renditions and its resized copies are removed together
+	assert.NoError(t, imagesRepository.RemoveImage("PR-1.png"))
+	assert.Empty(t, s3.keys())
+
+	_, err = imagesRepository.OpenImage("PR-1.png")
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+	_, err = imagesRepository.OpenImage("../PR-1.png")
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestS3ImagesRepository_URLs(t *testing.T) {
+	imagesRepository, s3 := newS3ImagesRepository(t, config.ImageStorageConfig{})
+	assert.Equal(t, s3.server.URL+"/product-images/PR-1.png", imagesRepository.GetURL("PR-1.png"))
+	assert.Equal(t, "", imagesRepository.GetURL(""))
+
+	signedURL, err := imagesRepository.GetSignedURL("PR-1.png", time.Minute)
+	assert.NoError(t, err)
+	assert.Contains(t, signedURL, "/product-images/PR-1.png?")
+	assert.Contains(t, signedURL, "X-Amz-Expires=60")
+	assert.Contains(t, signedURL, "X-Amz-Signature=")
+
+	// with an expiry every URL is signed
+	imagesRepository, _ = newS3ImagesRepository(t, config.ImageStorageConfig{SignedURLExpiry: time.Hour})
+	assert.Contains(t, imagesRepository.GetURL("PR-1.png"), "X-Amz-Expires=3600")
+}
+
+func TestMigrateLocalImages(t *testing.T) {
+	imagesRepository, s3 := newS3ImagesRepository(t, config.ImageStorageConfig{})
+
+	imagePath := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(imagePath, "PR-1.png"), []byte("one"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(imagePath, "PR-2-thumbnail.webp"), []byte("two"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(imagePath, "cache"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(imagePath, "cache", "PR-1-5x0.png"), []byte("cached"), 0644))
+
+	copied, err := repository.MigrateLocalImages(imagePath, imagesRepository)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, copied)
+	assert.Equal(t, []string{"PR-1.png", "PR-2-thumbnail.webp"}, s3.keys())
+
+	// running the migration again is harmless
+	copied, err = repository.MigrateLocalImages(imagePath, imagesRepository)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, copied)
+}
@@ -74,7 +74,7 @@ func setup() {
 	// Set the logrus output to the log file
 	logging.Log.SetOutput(logFile)
 
-	imageRepository = repository.NewImagesRepository(cfg.ImagePath)
+	imageRepository = repository.NewImagesRepository(cfg.ImagePath, cfg.ImageBaseURL, cfg.ImageRenditions)
 }
 
 func clearDB(tables []string) {
@@ -0,0 +1,58 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitOfWork_Rollback(t *testing.T) {
+	defer clearDB([]string{"image_blob", "product_image"})
+
+	ctx := context.Background()
+	unitOfWork := repository.NewUnitOfWork(db)
+
+	failure := errors.New("failure")
+	err := unitOfWork.Do(ctx, func(repositories *repository.Repositories) error {
+		if _, err := repositories.ProductImage.AcquireImageBlob(ctx, "rolled-back.png", 10); err != nil {
+			return err
+		}
+		if err := repositories.ProductImage.AddProductImage(ctx, &entity.ProductImage{ProductID: 999, FileName: "rolled-back.png"}); err != nil {
+			return err
+		}
+		return failure
+	})
+	assert.Equal(t, failure, err)
+
+	var count int64
+	db.Model(&entity.ImageBlob{}).Where("name = ?", "rolled-back.png").Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Model(&entity.ProductImage{}).Where("product_id = ?", 999).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestUnitOfWork_Commit(t *testing.T) {
+	defer clearDB([]string{"image_blob", "product_image"})
+
+	ctx := context.Background()
+	err := repository.NewUnitOfWork(db).Do(ctx, func(repositories *repository.Repositories) error {
+		if _, err := repositories.ProductImage.AcquireImageBlob(ctx, "committed.png", 10); err != nil {
+			return err
+		}
+		return repositories.ProductImage.AddProductImage(ctx, &entity.ProductImage{ProductID: 999, FileName: "committed.png"})
+	})
+	assert.NoError(t, err)
+
+	var blob entity.ImageBlob
+	assert.NoError(t, db.Where("name = ?", "committed.png").First(&blob).Error)
+	assert.Equal(t, 1, blob.RefCount)
+
+	var count int64
+	db.Model(&entity.ProductImage{}).Where("product_id = ?", 999).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
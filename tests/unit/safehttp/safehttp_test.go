@@ -0,0 +1,57 @@
+package safehttp_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"maqhaa/product_service/internal/safehttp"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	for _, address := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		assert.True(t, safehttp.IsPublicIP(net.ParseIP(address)), address)
+	}
+	for _, address := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1",
+		"0.0.0.0", "255.255.255.255", "::1", "fe80::1", "fd00::1", "::ffff:10.0.0.1", "::ffff:127.0.0.1",
+	} {
+		assert.False(t, safehttp.IsPublicIP(net.ParseIP(address)), address)
+	}
+	assert.False(t, safehttp.IsPublicIP(nil))
+}
+
+func TestNewClient_RefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := safehttp.NewClient(time.Second).Get(server.URL)
+	assert.True(t, errors.Is(err, safehttp.ErrPrivateAddress), err)
+
+	// a public name of a private address is refused too
+	serverURL, _ := url.Parse(server.URL)
+	_, err = safehttp.NewClient(time.Second).Get("http://localhost:" + serverURL.Port())
+	assert.Error(t, err)
+
+	response, err := safehttp.NewClient(time.Second, "127.0.0.1").Get(server.URL)
+	assert.NoError(t, err)
+	response.Body.Close()
+}
+
+func TestNewClient_DoesNotFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	}))
+	defer server.Close()
+
+	response, err := safehttp.NewClient(time.Second, "127.0.0.1").Get(server.URL)
+	assert.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusFound, response.StatusCode)
+}
@@ -0,0 +1,64 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/repository/mock"
+	"maqhaa/product_service/internal/app/service"
+	"maqhaa/product_service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkUpdateProductsService_Validation(t *testing.T) {
+	productService := service.NewProductService(nil, mock.NewMockUserRepository(), nil, nil, nil, nil, nil, config.ImageValidationConfig{}, nil, nil)
+
+	// valid operations only fail on the unknown token
+	request := &model.ProductBulkRequest{
+		CategoryIDs: []uint{1},
+		Operations: []model.ProductBulkOperation{
+			{Type: model.BulkAdjustPrice, Percent: -10},
+			{Type: model.BulkAddTag, Tag: " Vegan "},
+		},
+	}
+	report, appError := productService.BulkUpdateProductsService(context.Background(), request, "token")
+	assert.Nil(t, report)
+	assert.Equal(t, service.InvalidToken, appError.Code)
+	assert.Equal(t, "vegan", request.Operations[1].Tag)
+
+	request = &model.ProductBulkRequest{
+		ProductIDs: []uint{1, 2},
+		Operations: []model.ProductBulkOperation{
+			{Type: model.BulkSetPrice},
+			{Type: model.BulkAdjustPrice, Percent: -100},
+			{Type: model.BulkMoveCategory},
+			{Type: model.BulkRemoveTag, Tag: strings.Repeat("x", 51)},
+			{Type: "rename"},
+			{Type: model.BulkDeactivate},
+		},
+	}
+	_, appError = productService.BulkUpdateProductsService(context.Background(), request, "token")
+	assert.Equal(t, service.InvalidRequestError, appError.Code)
+
+	rules := map[string]string{}
+	for _, fieldError := range appError.Errors {
+		rules[fieldError.Field] = fieldError.Rule + " " + fieldError.Param
+	}
+	assert.Equal(t, map[string]string{
+		"operations[0].price":       "gt 0",
+		"operations[1].percent":     "gt -100",
+		"operations[2].category_id": "required ",
+		"operations[3].tag":         "max 50",
+		"operations[4].type":        "oneof set_price adjust_price move_category activate deactivate add_tag remove_tag",
+	}, rules)
+
+	// products are selected by ID or category
+	_, appError = productService.BulkUpdateProductsService(context.Background(), &model.ProductBulkRequest{
+		Operations: []model.ProductBulkOperation{{Type: model.BulkActivate}},
+	}, "token")
+	assert.Equal(t, service.InvalidRequestError, appError.Code)
+	assert.Equal(t, "product_ids", appError.Errors[0].Field)
+}
@@ -0,0 +1,72 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/repository"
+	"maqhaa/product_service/internal/app/repository/mock"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// leaseRepository records the renewals of the lease of a key, held until lost is set.
+type leaseRepository struct {
+	repository.IdempotencyRepository
+
+	mutex    sync.Mutex
+	renewals int
+	lost     bool
+}
+
+func (r *leaseRepository) RenewIdempotencyLease(ctx context.Context, key *entity.IdempotencyKey) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.lost {
+		return false, nil
+	}
+	r.renewals++
+	return true, nil
+}
+
+func (r *leaseRepository) count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.renewals
+}
+
+func TestHoldRequest_RenewsTheLease(t *testing.T) {
+	leases := &leaseRepository{}
+	idempotencyService := service.NewIdempotencyService(leases, mock.NewMockUserRepository(), time.Hour, 30*time.Millisecond)
+
+	stop := idempotencyService.HoldRequest(context.Background(), &entity.IdempotencyKey{ClientID: 1, Key: "key", LeaseID: "lease"})
+	time.Sleep(100 * time.Millisecond)
+	stop()
+
+	renewals := leases.count()
+	assert.GreaterOrEqual(t, renewals, 2)
+
+	// nothing is renewed once stopped
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, renewals, leases.count())
+}
+
+func TestHoldRequest_StopsWhenTheLeaseIsLost(t *testing.T) {
+	leases := &leaseRepository{lost: true}
+	idempotencyService := service.NewIdempotencyService(leases, mock.NewMockUserRepository(), time.Hour, 30*time.Millisecond)
+
+	stop := idempotencyService.HoldRequest(context.Background(), &entity.IdempotencyKey{ClientID: 1, Key: "key", LeaseID: "lease"})
+	time.Sleep(50 * time.Millisecond)
+	leases.mutex.Lock()
+	leases.lost = false
+	leases.mutex.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	stop()
+
+	assert.Equal(t, 0, leases.count())
+}
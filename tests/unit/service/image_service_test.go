@@ -0,0 +1,99 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/repository"
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/webp"
+)
+
+func newImageService(t *testing.T) (service.ImageService, string) {
+	imagePath := t.TempDir()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+	for x := 0; x < 200; x++ {
+		img.SetNRGBA(x, x/2, color.NRGBA{R: 200, A: 0xff})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(imagePath, "PR-1.png"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return service.NewImageService(repository.NewImagesRepository(imagePath, "", nil)), imagePath
+}
+
+func TestGetImageService_Original(t *testing.T) {
+	imageService, _ := newImageService(t)
+
+	file, appError := imageService.GetImageService(context.Background(), &model.ImageRequest{Name: "PR-1.png"})
+	assert.Equal(t, service.SuccessError, appError.Code)
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, img.Bounds().Dx())
+}
+
+func TestGetImageService_ResizedAndCached(t *testing.T) {
+	imageService, imagePath := newImageService(t)
+
+	file, appError := imageService.GetImageService(context.Background(), &model.ImageRequest{Name: "PR-1.png", Width: 50, Format: "webp"})
+	assert.Equal(t, service.SuccessError, appError.Code)
+	defer file.Close()
+
+	// the width is rounded up to the smallest allowed size
+	img, err := webp.Decode(file)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, img.Bounds().Dx())
+	assert.Equal(t, 50, img.Bounds().Dy())
+
+	_, err = os.Stat(filepath.Join(imagePath, "cache", "PR-1-100x0.webp"))
+	assert.NoError(t, err)
+}
+
+func TestGetImageService_NotEnlarged(t *testing.T) {
+	imageService, imagePath := newImageService(t)
+
+	file, appError := imageService.GetImageService(context.Background(), &model.ImageRequest{Name: "PR-1.png", Width: 400, Height: 400})
+	assert.Equal(t, service.SuccessError, appError.Code)
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, img.Bounds().Dx())
+
+	// the image already fits, so it is served as is and not copied to the cache
+	_, err = os.Stat(filepath.Join(imagePath, "cache"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGetImageService_NotFound(t *testing.T) {
+	imageService, _ := newImageService(t)
+
+	for _, name := range []string{"missing.png", "../PR-1.png", ".."} {
+		_, appError := imageService.GetImageService(context.Background(), &model.ImageRequest{Name: name})
+		assert.Equal(t, service.ImageNotFound, appError.Code, name)
+	}
+}
+
+func TestGetImageService_InvalidFormat(t *testing.T) {
+	imageService, _ := newImageService(t)
+
+	_, appError := imageService.GetImageService(context.Background(), &model.ImageRequest{Name: "PR-1.png", Format: "bmp"})
+	assert.Equal(t, service.InvalidRequestError, appError.Code)
+	assert.Equal(t, "format", appError.Errors[0].Field)
+}
@@ -0,0 +1,124 @@
+package service_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"maqhaa/product_service/internal/app/service"
+	"maqhaa/product_service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var validationRules = config.ImageValidationConfig{
+	MaxBytes:       1 << 20,
+	MinWidth:       16,
+	MinHeight:      16,
+	MaxWidth:       400,
+	MaxHeight:      400,
+	AllowedFormats: []string{"jpeg", "png"},
+}
+
+func encodeTestImage(t *testing.T, width int, height int, format string) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	// mark the top left corner to follow it through rotations
+	for y := 0; y < 8 && y < height; y++ {
+		for x := 0; x < 8 && x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 0xff, A: 0xff})
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100})
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation inserts an APP1 Exif segment holding the orientation tag after the SOI marker of a JPEG image.
+func withOrientation(data []byte, orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2a, 0x00, 0x00, 0x00, 0x08, // header, first IFD at 8
+		0x00, 0x01, // one entry
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, orientation, 0x00, 0x00, // orientation, SHORT
+		0x00, 0x00, 0x00, 0x00, // no next IFD
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	length := len(payload) + 2
+	segment := append([]byte{0xff, 0xe1, byte(length >> 8), byte(length)}, payload...)
+
+	result := append([]byte{}, data[:2]...)
+	result = append(result, segment...)
+	return append(result, data[2:]...)
+}
+
+func TestValidateImage_Accepted(t *testing.T) {
+	img, format, appError := service.ValidateImage(encodeTestImage(t, 40, 20, "png"), validationRules)
+	assert.Equal(t, service.SuccessError, appError.Code)
+	assert.Equal(t, "png", format)
+	assert.Equal(t, image.Rect(0, 0, 40, 20), img.Bounds())
+
+	// without rules every decodable image is accepted
+	_, format, appError = service.ValidateImage(encodeTestImage(t, 1, 1, "gif"), config.ImageValidationConfig{})
+	assert.Equal(t, service.SuccessError, appError.Code)
+	assert.Equal(t, "gif", format)
+}
+
+func TestValidateImage_Rejected(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		code int
+	}{
+		{"empty", nil, service.InvalidImage},
+		{"not an image", []byte("hello world"), service.InvalidImage},
+		{"truncated", encodeTestImage(t, 40, 20, "png")[:60], service.InvalidImage},
+		{"too many bytes", append(encodeTestImage(t, 40, 20, "png"), make([]byte, 1<<20)...), service.ImageTooLarge},
+		{"format not allowed", encodeTestImage(t, 40, 20, "gif"), service.UnsupportedImageFormat},
+		{"too small", encodeTestImage(t, 40, 8, "png"), service.InvalidImageDimensions},
+		{"too wide", encodeTestImage(t, 401, 20, "png"), service.InvalidImageDimensions},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, appError := service.ValidateImage(test.data, validationRules)
+			assert.Equal(t, test.code, appError.Code)
+		})
+	}
+}
+
+func TestValidateImage_Orientation(t *testing.T) {
+	data := encodeTestImage(t, 40, 20, "jpeg")
+
+	// rotated 90 clockwise: the top left corner moves to the top right
+	img, _, appError := service.ValidateImage(withOrientation(data, 6), validationRules)
+	assert.Equal(t, service.SuccessError, appError.Code)
+	assert.Equal(t, image.Rect(0, 0, 20, 40), img.Bounds())
+	r, _, _, _ := img.At(19, 0).RGBA()
+	assert.Greater(t, r, uint32(0x8000))
+
+	// rotated 180: the top left corner moves to the bottom right
+	img, _, _ = service.ValidateImage(withOrientation(data, 3), validationRules)
+	assert.Equal(t, image.Rect(0, 0, 40, 20), img.Bounds())
+	r, _, _, _ = img.At(39, 19).RGBA()
+	assert.Greater(t, r, uint32(0x8000))
+
+	// upright images are left alone
+	img, _, _ = service.ValidateImage(data, validationRules)
+	r, _, _, _ = img.At(0, 0).RGBA()
+	assert.Greater(t, r, uint32(0x8000))
+}
@@ -0,0 +1,23 @@
+package service_test
+
+import (
+	"testing"
+
+	"maqhaa/product_service/internal/app/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLocale(t *testing.T) {
+	assert.Equal(t, "en", service.NormalizeLocale("en-US"))
+	assert.Equal(t, "id", service.NormalizeLocale("ID"))
+	assert.Equal(t, "", service.NormalizeLocale("not a locale"))
+	assert.Equal(t, "", service.NormalizeLocale(""))
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, "id", service.ParseAcceptLanguage("id-ID,id;q=0.9,en;q=0.8"))
+	assert.Equal(t, "en", service.ParseAcceptLanguage("id;q=0.5, en-GB"))
+	assert.Equal(t, "", service.ParseAcceptLanguage("*"))
+	assert.Equal(t, "", service.ParseAcceptLanguage(""))
+}
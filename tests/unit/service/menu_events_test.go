@@ -0,0 +1,196 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"maqhaa/product_service/internal/app/entity"
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/repository/mock"
+	"maqhaa/product_service/internal/app/service"
+	"maqhaa/product_service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func productOutboxEvent(clientID uint, productID uint, menuEventType string) entity.OutboxEvent {
+	return entity.OutboxEvent{
+		Type:          entity.EventProductUpdated,
+		MenuEventType: menuEventType,
+		AggregateType: entity.AggregateProduct,
+		AggregateID:   productID,
+		ClientID:      clientID,
+		Payload:       `{"id": 1, "categoryId": 7}`,
+	}
+}
+
+func deliverMenuEvents(t *testing.T, menuEvents service.MenuEvents) int {
+	delivered, err := menuEvents.DeliverMenuEvents(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return delivered
+}
+
+func TestMenuEvents_DeliversEventsOfTheClient(t *testing.T) {
+	outbox := mock.NewMockOutboxRepository()
+	// events recorded before the first use are not delivered
+	outbox.AddOutboxEvent(productOutboxEvent(1, 9, model.MenuProductCreated))
+	menuEvents := service.NewMenuEvents(outbox, config.MenuEventsConfig{History: 10, Buffer: 10})
+
+	watch, err := menuEvents.Watch(context.Background(), 1, 0)
+	assert.NoError(t, err)
+	defer watch.Stop()
+	assert.False(t, watch.Resumed)
+	assert.Equal(t, uint64(1), watch.Sequence)
+
+	created := outbox.AddOutboxEvent(productOutboxEvent(1, 10, model.MenuProductCreated))
+	outbox.AddOutboxEvent(productOutboxEvent(2, 20, model.MenuProductCreated))
+	updated := outbox.AddOutboxEvent(productOutboxEvent(1, 10, model.MenuProductAvailability))
+	assert.Equal(t, 3, deliverMenuEvents(t, menuEvents))
+
+	first := <-watch.Events
+	second := <-watch.Events
+	assert.Equal(t, model.MenuProductCreated, first.Type)
+	assert.Equal(t, model.MenuProductAvailability, second.Type)
+	// the sequence is the ID of the event in the outbox
+	assert.Equal(t, uint64(created.ID), first.Sequence)
+	assert.Equal(t, uint64(updated.ID), second.Sequence)
+	assert.Equal(t, uint(10), first.ProductID)
+	assert.Equal(t, uint(7), first.CategoryID)
+	assert.False(t, first.CreatedAt.IsZero())
+	assert.Empty(t, watch.Events)
+
+	// events recorded without a menu event type get the one of their domain event
+	outbox.AddOutboxEvent(entity.OutboxEvent{Type: entity.EventCategoryChanged, AggregateType: entity.AggregateCategory, AggregateID: 7, ClientID: 1})
+	assert.Equal(t, 1, deliverMenuEvents(t, menuEvents))
+	changed := <-watch.Events
+	assert.Equal(t, model.MenuCategoryUpdated, changed.Type)
+	assert.Equal(t, uint(7), changed.CategoryID)
+}
+
+func TestMenuEvents_Resume(t *testing.T) {
+	outbox := mock.NewMockOutboxRepository()
+	menuEvents := service.NewMenuEvents(outbox, config.MenuEventsConfig{History: 2, Buffer: 10})
+
+	watch, err := menuEvents.Watch(context.Background(), 1, 0)
+	assert.NoError(t, err)
+	first := outbox.AddOutboxEvent(productOutboxEvent(1, 10, model.MenuProductCreated))
+	second := outbox.AddOutboxEvent(productOutboxEvent(1, 10, model.MenuProductUpdated))
+	outbox.AddOutboxEvent(productOutboxEvent(2, 20, model.MenuProductUpdated))
+	outbox.AddOutboxEvent(productOutboxEvent(1, 11, model.MenuProductUpdated))
+	outbox.AddOutboxEvent(productOutboxEvent(1, 12, model.MenuProductUpdated))
+	deliverMenuEvents(t, menuEvents)
+	watch.Stop()
+
+	// another instance reading the same outbox resumes after the events delivered by this one
+	other := service.NewMenuEvents(outbox, config.MenuEventsConfig{History: 2, Buffer: 10})
+	resumed, err := other.Watch(context.Background(), 1, uint64(second.ID))
+	assert.NoError(t, err)
+	defer resumed.Stop()
+	assert.True(t, resumed.Resumed)
+	if assert.Len(t, resumed.Missed, 2) {
+		assert.Equal(t, uint(11), resumed.Missed[0].ProductID)
+		assert.Equal(t, uint(12), resumed.Missed[1].ProductID)
+	}
+
+	// a watcher missing more events than the history gets a snapshot
+	tooFar, err := menuEvents.Watch(context.Background(), 1, uint64(first.ID))
+	assert.NoError(t, err)
+	defer tooFar.Stop()
+	assert.False(t, tooFar.Resumed)
+	assert.Empty(t, tooFar.Missed)
+
+	// so does a watcher resuming from an event no longer kept
+	outbox.RemoveOutboxEvents(second.ID)
+	expired, err := menuEvents.Watch(context.Background(), 1, uint64(first.ID))
+	assert.NoError(t, err)
+	defer expired.Stop()
+	assert.False(t, expired.Resumed)
+
+	// or from an unknown sequence
+	unknown, err := menuEvents.Watch(context.Background(), 1, 42)
+	assert.NoError(t, err)
+	defer unknown.Stop()
+	assert.False(t, unknown.Resumed)
+
+	// a watcher which missed nothing resumes with no events
+	upToDate, err := menuEvents.Watch(context.Background(), 1, lastMenuSequence(t, menuEvents))
+	assert.NoError(t, err)
+	defer upToDate.Stop()
+	assert.True(t, upToDate.Resumed)
+	assert.Empty(t, upToDate.Missed)
+}
+
+func TestMenuEvents_HoldsBackEventsAfterAnUncommittedOne(t *testing.T) {
+	outbox := mock.NewMockOutboxRepository()
+	menuEvents := service.NewMenuEvents(outbox, config.MenuEventsConfig{History: 10, Buffer: 10, GapTimeout: 50 * time.Millisecond})
+
+	watch, err := menuEvents.Watch(context.Background(), 1, 0)
+	assert.NoError(t, err)
+	defer watch.Stop()
+
+	uncommitted := outbox.ReserveOutboxEventID()
+	outbox.AddOutboxEvent(productOutboxEvent(1, 11, model.MenuProductUpdated))
+	assert.Equal(t, 0, deliverMenuEvents(t, menuEvents))
+
+	// the event is delivered once the earlier one commits, in order
+	committed := productOutboxEvent(1, 10, model.MenuProductUpdated)
+	committed.ID = uncommitted
+	outbox.AddOutboxEvent(committed)
+	assert.Equal(t, 2, deliverMenuEvents(t, menuEvents))
+	assert.Equal(t, uint(10), (<-watch.Events).ProductID)
+	assert.Equal(t, uint(11), (<-watch.Events).ProductID)
+
+	// or once the gap timeout passes, the earlier one being delivered late
+	late := outbox.ReserveOutboxEventID()
+	outbox.AddOutboxEvent(productOutboxEvent(1, 13, model.MenuProductUpdated))
+	assert.Equal(t, 0, deliverMenuEvents(t, menuEvents))
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, 1, deliverMenuEvents(t, menuEvents))
+	assert.Equal(t, uint(13), (<-watch.Events).ProductID)
+
+	committed = productOutboxEvent(1, 12, model.MenuProductUpdated)
+	committed.ID = late
+	outbox.AddOutboxEvent(committed)
+	assert.Equal(t, 1, deliverMenuEvents(t, menuEvents))
+	assert.Equal(t, uint(12), (<-watch.Events).ProductID)
+}
+
+func TestMenuEvents_StopsLaggingWatch(t *testing.T) {
+	outbox := mock.NewMockOutboxRepository()
+	menuEvents := service.NewMenuEvents(outbox, config.MenuEventsConfig{History: 10, Buffer: 1})
+
+	watch, err := menuEvents.Watch(context.Background(), 1, 0)
+	assert.NoError(t, err)
+	outbox.AddOutboxEvent(productOutboxEvent(1, 10, model.MenuProductUpdated))
+	outbox.AddOutboxEvent(productOutboxEvent(1, 11, model.MenuProductUpdated))
+	deliverMenuEvents(t, menuEvents)
+
+	_, ok := <-watch.Events
+	assert.True(t, ok)
+	_, ok = <-watch.Events
+	assert.False(t, ok)
+	assert.True(t, watch.Lagging())
+
+	// stopping a stopped watch does nothing
+	watch.Stop()
+
+	stopped, err := menuEvents.Watch(context.Background(), 1, 0)
+	assert.NoError(t, err)
+	stopped.Stop()
+	_, ok = <-stopped.Events
+	assert.False(t, ok)
+	assert.False(t, stopped.Lagging())
+}
+
+// lastMenuSequence returns the sequence of the last event delivered.
+func lastMenuSequence(t *testing.T, menuEvents service.MenuEvents) uint64 {
+	watch, err := menuEvents.Watch(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watch.Stop()
+	return watch.Sequence
+}
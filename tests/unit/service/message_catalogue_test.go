@@ -0,0 +1,61 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/repository/mock"
+	"maqhaa/product_service/internal/app/service"
+	"maqhaa/product_service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalizeMessage(t *testing.T) {
+	assert.Equal(t, "Token Tidak Valid", service.LocalizeMessage(service.InvalidToken, service.InvalidTokendMessage, "id"))
+	assert.Equal(t, service.InvalidTokendMessage, service.LocalizeMessage(service.InvalidToken, service.InvalidTokendMessage, "en-US"))
+	// unknown locales and codes keep the original message
+	assert.Equal(t, service.InvalidTokendMessage, service.LocalizeMessage(service.InvalidToken, service.InvalidTokendMessage, "fr"))
+	assert.Equal(t, "Something", service.LocalizeMessage(999, "Something", "id"))
+}
+
+func TestLocalizeMessage_KeepsDetail(t *testing.T) {
+	appError := service.NewInvalidRequestError("locale")
+	assert.Equal(t, "Permintaan Tidak Valid locale", service.LocalizeMessage(appError.Code, appError.Message, "id"))
+	assert.Equal(t, "Invalid Request locale", service.LocalizeMessage(appError.Code, appError.Message, "en"))
+}
+
+func TestNewValidationError(t *testing.T) {
+	productService := service.NewProductService(nil, mock.NewMockUserRepository(), nil, nil, nil, nil, nil, config.ImageValidationConfig{}, nil, nil)
+
+	appError := productService.AddProductService(context.Background(), &model.ProductRequest{Name: "Latte"}, "token")
+	assert.Equal(t, service.InvalidRequestError, appError.Code)
+	assert.Equal(t, "Invalid Request", appError.Message)
+
+	rules := map[string]string{}
+	for _, fieldError := range appError.Errors {
+		rules[fieldError.Field] = fieldError.Rule
+	}
+	assert.Equal(t, map[string]string{
+		"category_id": "required",
+		"description": "required",
+		"image":       "required_without",
+		"price":       "required",
+	}, rules)
+}
+
+func TestNewValidationError_NotValidationErrors(t *testing.T) {
+	appError := service.NewValidationError(errors.New("broken"))
+	assert.Equal(t, "Invalid Request broken", appError.Message)
+	assert.Empty(t, appError.Errors)
+}
+
+func TestLocalizeFieldErrors(t *testing.T) {
+	fieldErrors := []model.FieldError{{Field: "price", Rule: "gt", Param: "0", Message: "price must be greater than 0"}}
+
+	localized := service.LocalizeFieldErrors(fieldErrors, "id")
+	assert.Equal(t, "price harus lebih besar dari 0", localized[0].Message)
+	assert.Equal(t, "price must be greater than 0", fieldErrors[0].Message)
+}
@@ -0,0 +1,45 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/repository/mock"
+	"maqhaa/product_service/internal/app/service"
+	"maqhaa/product_service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditProductService_ImageOptional(t *testing.T) {
+	productService := service.NewProductService(nil, mock.NewMockUserRepository(), nil, nil, nil, nil, nil, config.ImageValidationConfig{}, nil, nil)
+
+	// without an image the request passes validation and only fails on the unknown token
+	request := &model.ProductRequest{ID: 1, CategoryID: 1, Name: "Latte", Description: "Latte", Price: 25000}
+	appError := productService.EditProductService(context.Background(), request, "token")
+	assert.Equal(t, service.InvalidToken, appError.Code)
+
+	request.Name = ""
+	appError = productService.EditProductService(context.Background(), request, "token")
+	assert.Equal(t, service.InvalidRequestError, appError.Code)
+	assert.Equal(t, "name", appError.Errors[0].Field)
+}
+
+func TestPatchProductService_Validation(t *testing.T) {
+	productService := service.NewProductService(nil, mock.NewMockUserRepository(), nil, nil, nil, nil, nil, config.ImageValidationConfig{}, nil, nil)
+
+	// an empty patch is valid
+	appError := productService.PatchProductService(context.Background(), &model.ProductPatchRequest{ID: 1}, "token")
+	assert.Equal(t, service.InvalidToken, appError.Code)
+
+	name, price := "", -1.0
+	appError = productService.PatchProductService(context.Background(), &model.ProductPatchRequest{ID: 1, Name: &name, Price: &price}, "token")
+	assert.Equal(t, service.InvalidRequestError, appError.Code)
+
+	rules := map[string]string{}
+	for _, fieldError := range appError.Errors {
+		rules[fieldError.Field] = fieldError.Rule
+	}
+	assert.Equal(t, map[string]string{"name": "min", "price": "gt"}, rules)
+}
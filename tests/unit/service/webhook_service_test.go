@@ -0,0 +1,72 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"maqhaa/product_service/internal/app/model"
+	"maqhaa/product_service/internal/app/repository/mock"
+	"maqhaa/product_service/internal/app/service"
+	"maqhaa/product_service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+
+	signature := service.SignWebhook("secret", 1700000000, body)
+	assert.Len(t, signature, 64)
+	assert.Equal(t, signature, service.SignWebhook("secret", 1700000000, body))
+	// the secret, the timestamp and the body are all signed
+	assert.NotEqual(t, signature, service.SignWebhook("other", 1700000000, body))
+	assert.NotEqual(t, signature, service.SignWebhook("secret", 1700000001, body))
+	assert.NotEqual(t, signature, service.SignWebhook("secret", 1700000000, []byte(`{"id":"2"}`)))
+}
+
+func TestAddWebhookService_Validation(t *testing.T) {
+	webhookService := service.NewWebhookService(nil, nil, mock.NewMockUserRepository(), config.WebhookConfig{})
+
+	_, appError := webhookService.AddWebhookService(context.Background(), &model.WebhookRequest{
+		URL:        "not a url",
+		EventTypes: []string{"product_updated", "product_updated", "order_created"},
+		Secret:     "short",
+	}, "token")
+	assert.Equal(t, service.InvalidRequestError, appError.Code)
+
+	rules := map[string]string{}
+	for _, fieldError := range appError.Errors {
+		rules[fieldError.Field] = fieldError.Rule
+	}
+	assert.Equal(t, "http_url", rules["url"])
+	assert.Equal(t, "unique", rules["event_types"])
+	assert.Equal(t, "min", rules["secret"])
+}
+
+func TestAddWebhookService_InvalidToken(t *testing.T) {
+	webhookService := service.NewWebhookService(nil, nil, mock.NewMockUserRepository(), config.WebhookConfig{})
+
+	_, appError := webhookService.AddWebhookService(context.Background(), &model.WebhookRequest{
+		URL:        "https://example.com/hooks",
+		EventTypes: []string{"product_updated"},
+	}, "token")
+	assert.Equal(t, service.InvalidToken, appError.Code)
+}
+
+func TestAddWebhookService_RequiresHTTPS(t *testing.T) {
+	webhookService := service.NewWebhookService(nil, nil, mock.NewMockUserRepository(), config.WebhookConfig{AllowedHosts: []string{"hooks.internal"}})
+
+	_, appError := webhookService.AddWebhookService(context.Background(), &model.WebhookRequest{
+		URL:        "http://example.com/hooks",
+		EventTypes: []string{"product_updated"},
+	}, "token")
+	assert.Equal(t, service.InvalidRequestError, appError.Code)
+	assert.Equal(t, "https_url", appError.Errors[0].Rule)
+
+	// allowed hosts are trusted with plain http
+	_, appError = webhookService.AddWebhookService(context.Background(), &model.WebhookRequest{
+		URL:        "http://hooks.internal/menu",
+		EventTypes: []string{"product_updated"},
+	}, "token")
+	assert.Equal(t, service.InvalidToken, appError.Code)
+}
@@ -0,0 +1,111 @@
+package webp_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"math/rand"
+	"testing"
+
+	"maqhaa/product_service/internal/webp"
+
+	"github.com/stretchr/testify/assert"
+	xwebp "golang.org/x/image/webp"
+)
+
+// photoImage returns an image resembling a photo: smooth shading with some sensor noise.
+func photoImage(width int, height int) *image.NRGBA {
+	random := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			shade := 60 + 80*math.Sin(float64(x)/40)*math.Cos(float64(y)/30)
+			noise := random.Intn(9) - 4
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: clamp(shade + 90 + float64(noise)),
+				G: clamp(shade + 50 + float64(noise)),
+				B: clamp(shade + float64(x)/8 + float64(noise)),
+				A: 0xff,
+			})
+		}
+	}
+	return img
+}
+
+func clamp(value float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, value)))
+}
+
+func encode(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// assertDecodes checks the encoded image decodes to the size of img, with colors close to its own.
+func assertDecodes(t *testing.T, img image.Image, data []byte) {
+	decoded, err := xwebp.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := img.Bounds()
+	assert.Equal(t, b.Dx(), decoded.Bounds().Dx())
+	assert.Equal(t, b.Dy(), decoded.Bounds().Dy())
+
+	diff := 0.0
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			er, eg, eb, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			ar, ag, ab, _ := decoded.At(decoded.Bounds().Min.X+x, decoded.Bounds().Min.Y+y).RGBA()
+			diff += math.Abs(float64(er>>8)-float64(ar>>8)) + math.Abs(float64(eg>>8)-float64(ag>>8)) + math.Abs(float64(eb>>8)-float64(ab>>8))
+		}
+	}
+	assert.Less(t, diff/float64(3*b.Dx()*b.Dy()), 16.0)
+}
+
+func TestEncode_Photo(t *testing.T) {
+	img := photoImage(600, 400)
+	assertDecodes(t, img, encode(t, img))
+}
+
+func TestEncode_SmallerThanJPEG(t *testing.T) {
+	img := photoImage(600, 400)
+
+	var jpegData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, img, &jpeg.Options{Quality: 85}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Less(t, len(encode(t, img)), jpegData.Len())
+}
+
+func TestEncode_SolidColor(t *testing.T) {
+	img := image.NewUniform(color.NRGBA{R: 10, G: 200, B: 30, A: 0xff})
+	for _, bounds := range []image.Rectangle{image.Rect(0, 0, 1, 1), image.Rect(0, 0, 20, 3)} {
+		bounded := &boundedImage{img, bounds}
+		assertDecodes(t, bounded, encode(t, bounded))
+	}
+}
+
+func TestEncode_SubImage(t *testing.T) {
+	img := photoImage(40, 40).SubImage(image.Rect(5, 7, 31, 29))
+	assertDecodes(t, img, encode(t, img))
+}
+
+func TestEncode_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Error(t, webp.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 0, 0))))
+}
+
+type boundedImage struct {
+	*image.Uniform
+	bounds image.Rectangle
+}
+
+func (b *boundedImage) Bounds() image.Rectangle {
+	return b.bounds
+}